@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/report"
+	"github.com/buildkite/test-engine-client/internal/runner"
+)
+
+// commands are the subcommands of bktec, along with their arguments and description.
+// bktec runs the tests when no command is given.
+var commands = []struct {
+	name        string
+	args        string
+	description string
+}{
+	{name: "run", args: "[flags]", description: "run the tests assigned to this node (default)"},
+	{name: "files", args: "[flags]", description: "print the test files discovered by the test runner"},
+	{name: "examples", args: "[flags] [test files]", description: "print the test examples of the test files as JSON"},
+	{name: "plan", args: "[flags]", description: "fetch or create the test plan for all the nodes and print it as JSON"},
+	{name: "report", args: "[summary file]", description: "print the report of the JSON summary of a previous run"},
+	{name: "doctor", args: "[flags]", description: "check the configuration, the test runner and the connection to Test Engine"},
+}
+
+// localCommandFields are the environment variables of the API and the build,
+// which the commands discovering the tests locally don't use.
+var localCommandFields = []string{
+	"BUILDKITE_BUILD_ID",
+	"BUILDKITE_STEP_ID",
+	"BUILDKITE_PARALLEL_JOB",
+	"BUILDKITE_PARALLEL_JOB_COUNT",
+	"BUILDKITE_ORGANIZATION_SLUG",
+	"BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN",
+	"BUILDKITE_TEST_ENGINE_SUITE_SLUG",
+	"BUILDKITE_TEST_ENGINE_RESULT_PATH",
+}
+
+// parseCommand returns the name of the command and its arguments.
+// The command is "run" when the first argument is a flag or there are no arguments.
+func parseCommand(args []string) (string, []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "run", args
+	}
+	return args[0], args[1:]
+}
+
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "Usage: bktec [command] [flags]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s%s\n", c.name, c.description)
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Run 'bktec <command> --help' for the flags of a command.")
+}
+
+// newFlagSet returns the flag set of the command, printing the usage of the command on --help.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("bktec "+name, flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		for _, c := range commands {
+			if c.name == name {
+				fmt.Fprintf(out, "Usage: bktec %s %s\n", c.name, c.args)
+				fmt.Fprintln(out, "")
+				fmt.Fprintln(out, strings.ToUpper(c.description[:1])+c.description[1:]+".")
+			}
+		}
+		if name == "run" {
+			fmt.Fprintln(out, "")
+			printCommands(out)
+		}
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(out, "")
+			fmt.Fprintln(out, "Each flag takes precedence over the environment variable in parentheses.")
+			fmt.Fprintln(out, "")
+			fmt.Fprintln(out, "Flags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// filesCommand prints the test files discovered by the test runner, one per line.
+func filesCommand(args []string) {
+	fs := newFlagSet("files")
+	configFlags := config.RegisterFlags(fs)
+	_ = fs.Parse(args)
+
+	cfg := newCommandConfig(configFlags, localCommandFields...)
+	testRunner := detectRunner(cfg)
+
+	files, err := testRunner.GetFiles()
+	if err != nil {
+		logErrorAndExit(16, "Couldn't get files: %v", err)
+	}
+
+	for _, file := range files {
+		fmt.Println(file)
+	}
+}
+
+// examplesCommand prints the test examples of the given test files as JSON,
+// or of all the test files discovered by the test runner when none is given.
+func examplesCommand(args []string) {
+	fs := newFlagSet("examples")
+	configFlags := config.RegisterFlags(fs)
+	_ = fs.Parse(args)
+
+	cfg := newCommandConfig(configFlags, localCommandFields...)
+	testRunner := detectRunner(cfg)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		var err error
+		files, err = testRunner.GetFiles()
+		if err != nil {
+			logErrorAndExit(16, "Couldn't get files: %v", err)
+		}
+	}
+
+	examples, err := testRunner.GetExamples(files)
+	if err != nil {
+		logErrorAndExit(16, "Couldn't get examples: %v", err)
+	}
+
+	printJSON(examples)
+}
+
+// planCommand fetches or creates the test plan for all the nodes, and prints it as JSON.
+// The node index and the result path aren't needed, because no tests are run.
+func planCommand(args []string) {
+	fs := newFlagSet("plan")
+	configFlags := config.RegisterFlags(fs)
+	_ = fs.Parse(args)
+
+	cfg := newCommandConfig(configFlags, "BUILDKITE_PARALLEL_JOB", "BUILDKITE_TEST_ENGINE_RESULT_PATH")
+	testRunner := detectRunner(cfg)
+	files := getFiles(cfg, testRunner)
+
+	testPlan := getTestPlan(context.Background(), newAPIClient(cfg), cfg, files, testRunner)
+
+	printJSON(testPlan)
+}
+
+// reportCommand prints the report of the JSON summary written by a previous run,
+// read from the given file or BUILDKITE_TEST_ENGINE_SUMMARY_PATH.
+func reportCommand(args []string) {
+	fs := newFlagSet("report")
+	_ = fs.Parse(args)
+
+	path := os.Getenv("BUILDKITE_TEST_ENGINE_SUMMARY_PATH")
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if path == "" {
+		logErrorAndExit(16, "Missing summary file, pass it as an argument or set BUILDKITE_TEST_ENGINE_SUMMARY_PATH")
+	}
+
+	summary, err := report.ReadSummaryFile(path)
+	if err != nil {
+		logErrorAndExit(16, "Couldn't read summary: %v", err)
+	}
+
+	printSummary(summary)
+}
+
+// printSummary prints the report of the summary of a run, like printReport.
+// The summary doesn't have the failure messages and durations of the tests, therefore they are not printed.
+func printSummary(summary report.Summary) {
+	fmt.Println("+++ ========== Buildkite Test Engine Report  ==========")
+	fmt.Printf("Node %d, test plan %s", summary.NodeIndex, summary.Identifier)
+	if summary.Fallback {
+		fmt.Print(" (fallback)")
+	}
+	fmt.Println("")
+
+	printStatistics(runner.RunStatistics{
+		Total:            summary.Statistics.Total,
+		PassedOnFirstRun: summary.Statistics.PassedOnFirstRun,
+		PassedOnRetry:    summary.Statistics.PassedOnRetry,
+		MutedPassed:      summary.Statistics.MutedPassed,
+		MutedFailed:      summary.Statistics.MutedFailed,
+		Failed:           summary.Statistics.Failed,
+	})
+
+	var mutedTests, flakyTests, failedTests []report.SummaryTest
+	for _, test := range summary.Tests {
+		switch {
+		case test.Muted:
+			mutedTests = append(mutedTests, test)
+		case test.Status == runner.TestStatusFailed:
+			failedTests = append(failedTests, test)
+		case test.Flaky:
+			flakyTests = append(flakyTests, test)
+		}
+	}
+
+	if len(mutedTests) > 0 {
+		fmt.Println("")
+		fmt.Println("+++ Muted Tests:")
+		for _, test := range mutedTests {
+			fmt.Printf("- %s %s (%s)\n", test.Scope, test.Name, test.Status)
+		}
+	}
+
+	if len(flakyTests) > 0 {
+		fmt.Println("")
+		fmt.Println("+++ Flaky Tests:")
+		for _, test := range flakyTests {
+			fmt.Printf("- %s %s (passed after %d attempts)\n", test.Scope, test.Name, test.ExecutionCount)
+		}
+	}
+
+	if len(failedTests) > 0 {
+		fmt.Println("")
+		fmt.Println("+++ Failed Tests:")
+		for _, test := range failedTests {
+			fmt.Printf("- %s %s", test.Scope, test.Name)
+			if test.Path != "" {
+				fmt.Printf(" (%s)", test.Path)
+			}
+			fmt.Println("")
+		}
+	}
+
+	fmt.Println("===================================================")
+}
+
+// printJSON prints the value as indented JSON.
+func printJSON(v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		logErrorAndExit(16, "Couldn't encode JSON: %v", err)
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		args     []string
+		wantName string
+		wantArgs []string
+	}{
+		{args: []string{}, wantName: "run", wantArgs: []string{}},
+		{args: []string{"--version"}, wantName: "run", wantArgs: []string{"--version"}},
+		{args: []string{"run", "--runner", "rspec"}, wantName: "run", wantArgs: []string{"--runner", "rspec"}},
+		{args: []string{"examples", "--runner", "jest", "src/a.test.js"}, wantName: "examples", wantArgs: []string{"--runner", "jest", "src/a.test.js"}},
+		{args: []string{"report", "tmp/summary.json"}, wantName: "report", wantArgs: []string{"tmp/summary.json"}},
+	}
+
+	for _, tc := range cases {
+		name, args := parseCommand(tc.args)
+		if name != tc.wantName {
+			t.Errorf("parseCommand(%q) name = %q, want %q", tc.args, name, tc.wantName)
+		}
+		if diff := cmp.Diff(args, tc.wantArgs); diff != "" {
+			t.Errorf("parseCommand(%q) args diff (-got +want):\n%s", tc.args, diff)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/kballard/go-shellquote"
+)
+
+// doctorTimeout is the maximum time spent on the request to Test Engine by bktec doctor.
+var doctorTimeout = 15 * time.Second
+
+// doctorCheck is the outcome of a check of bktec doctor.
+type doctorCheck struct {
+	name string
+	// err is the reason the check failed, along with what to do about it. It's nil when the check passed.
+	err error
+	// details are printed under the check, whether it passed or not.
+	details []string
+}
+
+func (c doctorCheck) print(w io.Writer) {
+	if c.err != nil {
+		fmt.Fprintf(w, "✗ %s: %v\n", c.name, c.err)
+	} else {
+		fmt.Fprintf(w, "✓ %s\n", c.name)
+	}
+	for _, detail := range c.details {
+		fmt.Fprintf(w, "    %s\n", detail)
+	}
+}
+
+// doctorCommand checks the configuration, the test runner and the connection to Test Engine,
+// and exits with status 16 if any check fails.
+func doctorCommand(args []string) {
+	fs := newFlagSet("doctor")
+	configFlags := config.RegisterFlags(fs)
+	_ = fs.Parse(args)
+
+	checks := runDoctorChecks(context.Background(), configFlags)
+
+	failed := 0
+	for _, check := range checks {
+		check.print(os.Stdout)
+		if check.err != nil {
+			failed++
+		}
+	}
+
+	fmt.Println("")
+	if failed > 0 {
+		fmt.Printf("%d of %d checks failed\n", failed, len(checks))
+		os.Exit(16)
+	}
+	fmt.Printf("All %d checks passed\n", len(checks))
+}
+
+// runDoctorChecks runs the checks of bktec doctor.
+// The checks of the test runner are skipped when the test runner is not supported.
+func runDoctorChecks(ctx context.Context, configFlags config.Flags) []doctorCheck {
+	cfg, configCheck := checkConfig(configFlags)
+	checks := []doctorCheck{configCheck}
+
+	runnerConfig, err := runner.EffectiveConfig(cfg)
+	if err != nil {
+		checks = append(checks, doctorCheck{
+			name: "Test runner",
+			err:  fmt.Errorf("%v, set BUILDKITE_TEST_ENGINE_TEST_RUNNER or --runner", err),
+		})
+	} else {
+		checks = append(checks,
+			checkCommands(runnerConfig),
+			checkPlaceholders(runnerConfig),
+			checkFiles(cfg, runnerConfig),
+		)
+	}
+
+	return append(checks, checkAPI(ctx, cfg))
+}
+
+// checkConfig validates the configuration and lists where its values are set.
+// When the configuration is invalid, the invalid values are ignored so the other checks can still run.
+func checkConfig(configFlags config.Flags) (config.Config, doctorCheck) {
+	check := doctorCheck{name: "Configuration"}
+
+	cfg, err := config.NewWithFlags(configFlags)
+	var invConfigError config.InvalidConfigError
+	if errors.As(err, &invConfigError) {
+		var invalid []string
+		for key := range invConfigError {
+			invalid = append(invalid, key)
+		}
+		sort.Strings(invalid)
+
+		check.err = fmt.Errorf("%d invalid values, set them with the environment variables or the flags in `bktec doctor --help`", len(invalid))
+		for _, line := range strings.Split(err.Error(), "\n") {
+			check.details = append(check.details, "✗ "+line)
+		}
+
+		cfg, _ = config.NewWithFlagsExcept(configFlags, invalid...)
+	}
+
+	if cfg.ConfigFile != "" {
+		check.details = append(check.details, fmt.Sprintf("Config file: %s, profile %q", cfg.ConfigFile, cfg.Profile))
+	}
+	check.details = append(check.details, fmt.Sprintf("CI provider: %s", cfg.CIProvider))
+	for _, source := range cfg.Sources() {
+		check.details = append(check.details, fmt.Sprintf("%s=%s (%s)", source.Env, source.Value, source.From))
+	}
+
+	return cfg, check
+}
+
+// checkCommands checks that the executables of the test and retry commands are found on PATH.
+func checkCommands(runnerConfig runner.RunnerConfig) doctorCheck {
+	check := doctorCheck{name: "Test command"}
+
+	commands := []struct{ env, command string }{
+		{env: "BUILDKITE_TEST_ENGINE_TEST_CMD", command: runnerConfig.TestCommand},
+		{env: "BUILDKITE_TEST_ENGINE_RETRY_CMD", command: runnerConfig.RetryTestCommand},
+	}
+
+	for _, c := range commands {
+		if c.command == "" {
+			continue
+		}
+
+		words, err := shellquote.Split(c.command)
+		if err != nil || len(words) == 0 {
+			check.err = fmt.Errorf("couldn't parse %q, check %s", c.command, c.env)
+			return check
+		}
+
+		path, err := exec.LookPath(words[0])
+		if err != nil {
+			check.err = fmt.Errorf("%q of %q isn't found on PATH, install it or change %s", words[0], c.command, c.env)
+			return check
+		}
+
+		check.details = append(check.details, fmt.Sprintf("%s (%s)", c.command, path))
+	}
+
+	return check
+}
+
+// checkPlaceholders checks that the placeholders of the test and retry commands are well-formed.
+func checkPlaceholders(runnerConfig runner.RunnerConfig) doctorCheck {
+	check := doctorCheck{name: "Command placeholders"}
+
+	if err := runner.CheckPlaceholders(runnerConfig.TestRunner, runnerConfig.TestCommand); err != nil {
+		check.err = fmt.Errorf("%v, fix BUILDKITE_TEST_ENGINE_TEST_CMD", err)
+		return check
+	}
+
+	if err := runner.CheckPlaceholders(runnerConfig.TestRunner, runnerConfig.RetryTestCommand); err != nil {
+		check.err = fmt.Errorf("%v, fix BUILDKITE_TEST_ENGINE_RETRY_CMD", err)
+	}
+
+	return check
+}
+
+// checkFiles checks that the test runner discovers test files.
+func checkFiles(cfg config.Config, runnerConfig runner.RunnerConfig) doctorCheck {
+	check := doctorCheck{name: "Test files"}
+
+	testRunner, err := runner.DetectRunner(cfg)
+	if err != nil {
+		check.err = err
+		return check
+	}
+
+	// The runners return an error when no test files are found.
+	files, err := testRunner.GetFiles()
+	if err != nil {
+		check.err = fmt.Errorf("%v, check BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN and BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN", err)
+		return check
+	}
+
+	pattern := fmt.Sprintf("pattern %q", runnerConfig.TestFilePattern)
+	if runnerConfig.TestFileExcludePattern != "" {
+		pattern += fmt.Sprintf(", excluding %q", runnerConfig.TestFileExcludePattern)
+	}
+
+	check.details = append(check.details, fmt.Sprintf("%d test files match %s", len(files), pattern))
+	return check
+}
+
+// checkAPI sends an authenticated request that doesn't change anything to Test Engine,
+// and reports how long it took.
+func checkAPI(ctx context.Context, cfg config.Config) doctorCheck {
+	check := doctorCheck{name: "Test Engine API"}
+
+	if cfg.AccessToken == "" {
+		check.err = errors.New("no access token, set BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN or --access-token")
+		if cfg.HasPlanFile() {
+			check.err = nil
+			check.details = append(check.details, fmt.Sprintf("skipped, the test plan is read from %s", cfg.PlanFile))
+		}
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := newAPIClient(cfg).GetAccessToken(ctx)
+	latency := time.Since(start).Round(time.Millisecond)
+
+	switch {
+	case errors.Is(err, api.ErrUnauthorized):
+		check.err = fmt.Errorf("%s rejected the access token (%v), check BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", cfg.ServerBaseUrl, err)
+	case errors.Is(err, api.ErrRetryTimeout):
+		check.err = fmt.Errorf("couldn't reach %s within %s, check BUILDKITE_TEST_ENGINE_BASE_URL and the network", cfg.ServerBaseUrl, doctorTimeout)
+	case err != nil:
+		check.err = fmt.Errorf("request to %s failed: %v", cfg.ServerBaseUrl, err)
+	default:
+		check.details = append(check.details, fmt.Sprintf("%s responded in %s", cfg.ServerBaseUrl, latency))
+	}
+
+	return check
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/runner"
+)
+
+func TestCheckAPI(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"uuid": "b63254c0-3271-4a98-8270-7cfbd6c2f14e", "scopes": ["read_suites"]}`))
+	}))
+	defer svr.Close()
+
+	check := checkAPI(context.Background(), config.Config{AccessToken: "asdf1234", ServerBaseUrl: svr.URL})
+
+	if check.err != nil {
+		t.Errorf("checkAPI() error = %v", check.err)
+	}
+
+	if len(check.details) != 1 || !strings.HasPrefix(check.details[0], svr.URL+" responded in ") {
+		t.Errorf("checkAPI() details = %q, want the latency", check.details)
+	}
+}
+
+func TestCheckAPI_Unauthorized(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Authentication required. Please supply a valid API Access Token"}`, http.StatusUnauthorized)
+	}))
+	defer svr.Close()
+
+	check := checkAPI(context.Background(), config.Config{AccessToken: "invalid", ServerBaseUrl: svr.URL})
+
+	if check.err == nil || !strings.Contains(check.err.Error(), "rejected the access token") {
+		t.Errorf("checkAPI() error = %v, want the access token to be rejected", check.err)
+	}
+}
+
+func TestCheckAPI_NoAccessToken(t *testing.T) {
+	check := checkAPI(context.Background(), config.Config{ServerBaseUrl: "https://api.buildkite.com"})
+
+	want := "no access token, set BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN or --access-token"
+	if check.err == nil || check.err.Error() != want {
+		t.Errorf("checkAPI() error = %v, want %q", check.err, want)
+	}
+}
+
+func TestCheckCommands(t *testing.T) {
+	check := checkCommands(runner.RunnerConfig{
+		TestCommand:      "go test {{testExamples}}",
+		RetryTestCommand: "bktec-missing-binary --only-failures",
+	})
+
+	want := `"bktec-missing-binary" of "bktec-missing-binary --only-failures" isn't found on PATH, install it or change BUILDKITE_TEST_ENGINE_RETRY_CMD`
+	if check.err == nil || check.err.Error() != want {
+		t.Errorf("checkCommands() error = %v, want %q", check.err, want)
+	}
+}
+
+func TestCheckPlaceholders(t *testing.T) {
+	check := checkPlaceholders(runner.RunnerConfig{
+		TestRunner:       "rspec",
+		TestCommand:      "bin/rspec --out {{resultPath}} {{testExamples}}",
+		RetryTestCommand: "bin/rspec --out={{resultPath}}",
+	})
+
+	if check.err == nil || !strings.HasSuffix(check.err.Error(), "fix BUILDKITE_TEST_ENGINE_RETRY_CMD") {
+		t.Errorf("checkPlaceholders() error = %v, want an error of the retry command", check.err)
+	}
+}
+
+func TestCheckFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "apple_spec.rb"), []byte(""), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Config{TestRunner: "rspec", TestFilePattern: filepath.Join(dir, "*_spec.rb")}
+	runnerConfig, err := runner.EffectiveConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := checkFiles(cfg, runnerConfig)
+	if check.err != nil {
+		t.Errorf("checkFiles() error = %v", check.err)
+	}
+
+	cfg.TestFileExcludePattern = filepath.Join(dir, "apple_*")
+	runnerConfig.TestFileExcludePattern = cfg.TestFileExcludePattern
+
+	check = checkFiles(cfg, runnerConfig)
+	if check.err == nil || !strings.HasPrefix(check.err.Error(), "no files found with pattern") {
+		t.Errorf("checkFiles() error = %v, want no test files", check.err)
+	}
+}
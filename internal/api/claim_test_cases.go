@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+)
+
+// ClaimTestCasesParams represents the params sent when claiming test cases from the queue of a dynamic test plan.
+type ClaimTestCasesParams struct {
+	Identifier string `json:"identifier"`
+	NodeIndex  int    `json:"node_index"`
+	BatchSize  int    `json:"batch_size"`
+	// Sequence is the number of the claim made by the node, starting from 1.
+	// Along with NodeIndex, it identifies the claim, so that a retried claim returns the same batch.
+	Sequence int `json:"sequence"`
+}
+
+type claimTestCasesResponse struct {
+	Tests []plan.TestCase `json:"tests"`
+}
+
+// ClaimTestCases claims the next batch of test cases from the queue of the test plan identified by params.Identifier.
+// Each test case is claimed by a single node. An empty batch is returned when the queue is drained.
+// The request is retried with the same node index and sequence, for which the server returns the batch
+// it already claimed, so that a batch isn't lost when the response of the first request is.
+// ErrRetryTimeout is returned if the client failed to communicate with the server after exceeding the retry limit.
+func (c Client) ClaimTestCases(ctx context.Context, suiteSlug string, params ClaimTestCasesParams) ([]plan.TestCase, error) {
+	url := fmt.Sprintf("%s/v2/analytics/organizations/%s/suites/%s/test_plan/claim", c.ServerBaseUrl, c.OrganizationSlug, suiteSlug)
+
+	var response claimTestCasesResponse
+	_, err := c.DoWithRetry(ctx, httpRequest{
+		Method: http.MethodPost,
+		URL:    url,
+		Body:   params,
+	}, &response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Tests, nil
+}
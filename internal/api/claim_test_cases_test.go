@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pact-foundation/pact-go/v2/consumer"
+	"github.com/pact-foundation/pact-go/v2/matchers"
+)
+
+func TestClaimTestCases(t *testing.T) {
+	mockProvider, err := consumer.NewV2Pact(consumer.MockHTTPProviderConfig{
+		Consumer: "TestEngineClient",
+		Provider: "TestEngineServer",
+	})
+
+	if err != nil {
+		t.Error("Error mocking provider", err)
+	}
+
+	params := ClaimTestCasesParams{
+		Identifier: "abc123",
+		NodeIndex:  1,
+		BatchSize:  2,
+		Sequence:   1,
+	}
+
+	err = mockProvider.
+		AddInteraction().
+		Given("A dynamic test plan identified as abc123 exists").
+		UponReceiving("A request to claim test cases").
+		WithRequest("POST", "/v2/analytics/organizations/buildkite/suites/rspec/test_plan/claim", func(b *consumer.V2RequestBuilder) {
+			b.Header("Authorization", matchers.Like("Bearer asdf1234"))
+			b.JSONBody(params)
+		}).
+		WillRespondWith(200, func(b *consumer.V2ResponseBuilder) {
+			b.Header("Content-Type", matchers.Like("application/json; charset=utf-8"))
+			b.JSONBody(matchers.MapMatcher{
+				"tests": matchers.EachLike(matchers.MapMatcher{
+					"path":               matchers.Like("apple_spec.rb"),
+					"format":             matchers.Like("file"),
+					"estimated_duration": matchers.Like(1000),
+				}, 1),
+			})
+		}).
+		ExecuteTest(t, func(config consumer.MockServerConfig) error {
+			url := fmt.Sprintf("http://%s:%d", config.Host, config.Port)
+			c := NewClient(ClientConfig{
+				AccessToken:      "asdf1234",
+				OrganizationSlug: "buildkite",
+				ServerBaseUrl:    url,
+			})
+			got, err := c.ClaimTestCases(context.Background(), "rspec", params)
+			if err != nil {
+				t.Errorf("ClaimTestCases() error = %v", err)
+			}
+			want := []plan.TestCase{
+				{Path: "apple_spec.rb", Format: plan.TestCaseFormatFile, EstimatedDuration: 1000},
+			}
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("ClaimTestCases() diff (-got +want):\n%s", diff)
+			}
+			return nil
+		})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClaimTestCases_QueueDrained(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tests": []}`)
+	}))
+	defer svr.Close()
+
+	c := NewClient(ClientConfig{
+		OrganizationSlug: "my-org",
+		ServerBaseUrl:    svr.URL,
+	})
+
+	got, err := c.ClaimTestCases(context.Background(), "my-suite", ClaimTestCasesParams{Identifier: "abc123", BatchSize: 2})
+	if err != nil {
+		t.Errorf("ClaimTestCases() error = %v", err)
+	}
+
+	if len(got) != 0 {
+		t.Errorf("ClaimTestCases() = %v, want empty", got)
+	}
+}
+
+func TestClaimTestCases_BadRequest(t *testing.T) {
+	requestCount := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		http.Error(w, `{"message": "test plan is not dynamic"}`, http.StatusBadRequest)
+	}))
+	defer svr.Close()
+
+	c := NewClient(ClientConfig{
+		OrganizationSlug: "my-org",
+		ServerBaseUrl:    svr.URL,
+	})
+
+	_, err := c.ClaimTestCases(context.Background(), "my-suite", ClaimTestCasesParams{Identifier: "abc123", BatchSize: 2})
+
+	if requestCount > 1 {
+		t.Errorf("http request count = %v, want  %d", requestCount, 1)
+	}
+
+	if err == nil || err.Error() != "test plan is not dynamic" {
+		t.Errorf("ClaimTestCases() error = %v, want %v", err, "test plan is not dynamic")
+	}
+}
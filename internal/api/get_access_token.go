@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrUnauthorized is returned when the server rejects the access token.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// AccessToken is the access token the client authenticates with.
+type AccessToken struct {
+	UUID   string   `json:"uuid"`
+	Scopes []string `json:"scopes"`
+}
+
+// GetAccessToken gets the access token the client authenticates with.
+// It doesn't change anything on the server, therefore it's used to check the connection and the authentication.
+// ErrUnauthorized is returned if the access token is invalid.
+func (c Client) GetAccessToken(ctx context.Context) (AccessToken, error) {
+	url := fmt.Sprintf("%s/v2/access-token", c.ServerBaseUrl)
+
+	var accessToken AccessToken
+
+	resp, err := c.DoWithRetry(ctx, httpRequest{
+		Method: http.MethodGet,
+		URL:    url,
+	}, &accessToken)
+
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return AccessToken{}, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		}
+		return AccessToken{}, err
+	}
+
+	return accessToken, nil
+}
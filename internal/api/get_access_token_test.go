@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetAccessToken(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/access-token" || r.Header.Get("Authorization") != "Bearer asdf1234" {
+			http.Error(w, `{"message": "Not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"uuid": "b63254c0-3271-4a98-8270-7cfbd6c2f14e", "scopes": ["read_suites", "write_suites"]}`))
+	}))
+	defer svr.Close()
+
+	c := NewClient(ClientConfig{
+		AccessToken:   "asdf1234",
+		ServerBaseUrl: svr.URL,
+	})
+
+	got, err := c.GetAccessToken(context.Background())
+	if err != nil {
+		t.Fatalf("GetAccessToken() error = %v", err)
+	}
+
+	want := AccessToken{
+		UUID:   "b63254c0-3271-4a98-8270-7cfbd6c2f14e",
+		Scopes: []string{"read_suites", "write_suites"},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("GetAccessToken() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestGetAccessToken_Unauthorized(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Authentication required. Please supply a valid API Access Token"}`, http.StatusUnauthorized)
+	}))
+	defer svr.Close()
+
+	c := NewClient(ClientConfig{
+		AccessToken:   "invalid",
+		ServerBaseUrl: svr.URL,
+	})
+
+	_, err := c.GetAccessToken(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("GetAccessToken() error = %v, want %v", err, ErrUnauthorized)
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// FlakyTest is a test that failed and then passed on retry in the same run.
+type FlakyTest struct {
+	Scope          string `json:"scope"`
+	Name           string `json:"name"`
+	Path           string `json:"path"`
+	Identifier     string `json:"identifier,omitempty"`
+	ExecutionCount int    `json:"execution_count"`
+}
+
+type FlakyTestsParams struct {
+	Identifier string      `json:"identifier"`
+	FlakyTests []FlakyTest `json:"flaky_tests"`
+}
+
+// PostFlakyTests reports the flaky tests detected on a node to Test Engine,
+// so they can be considered for muting in the next test plans.
+func (c Client) PostFlakyTests(ctx context.Context, suiteSlug string, params FlakyTestsParams) error {
+	url := fmt.Sprintf("%s/v2/analytics/organizations/%s/suites/%s/test_plan/flaky_tests", c.ServerBaseUrl, c.OrganizationSlug, suiteSlug)
+
+	_, err := c.DoWithRetry(ctx, httpRequest{
+		Method: http.MethodPost,
+		URL:    url,
+		Body:   params,
+	}, nil)
+
+	return err
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pact-foundation/pact-go/v2/consumer"
+	"github.com/pact-foundation/pact-go/v2/matchers"
+)
+
+func TestPostFlakyTests(t *testing.T) {
+	mockProvider, err := consumer.NewV2Pact(consumer.MockHTTPProviderConfig{
+		Consumer: "TestEngineClient",
+		Provider: "TestEngineServer",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := FlakyTestsParams{
+		Identifier: "abc123",
+		FlakyTests: []FlakyTest{
+			{Scope: "Apple", Name: "is red", Path: "./spec/apple_spec.rb[1:1]", ExecutionCount: 2},
+		},
+	}
+
+	err = mockProvider.
+		AddInteraction().
+		Given("A test plan identified as abc123 exists").
+		UponReceiving("A request to post flaky tests of the test plan abc123").
+		WithRequest("POST", "/v2/analytics/organizations/buildkite/suites/rspec/test_plan/flaky_tests", func(b *consumer.V2RequestBuilder) {
+			b.Header("Authorization", matchers.String("Bearer asdf1234"))
+			b.Header("Content-Type", matchers.String("application/json"))
+			b.JSONBody(params)
+		}).
+		WillRespondWith(200, func(b *consumer.V2ResponseBuilder) {
+			b.Header("Content-Type", matchers.Like("application/json; charset=utf-8"))
+			b.JSONBody(matchers.MapMatcher{
+				"head": matchers.String("no_content"),
+			})
+		}).
+		ExecuteTest(t, func(config consumer.MockServerConfig) error {
+			url := fmt.Sprintf("http://%s:%d", config.Host, config.Port)
+			c := NewClient(ClientConfig{
+				AccessToken:      "asdf1234",
+				OrganizationSlug: "buildkite",
+				ServerBaseUrl:    url,
+			})
+
+			if err := c.PostFlakyTests(context.Background(), "rspec", params); err != nil {
+				t.Errorf("PostFlakyTests() error = %v", err)
+			}
+
+			return nil
+		})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostFlakyTests_RequestBody(t *testing.T) {
+	params := FlakyTestsParams{
+		Identifier: "abc123",
+		FlakyTests: []FlakyTest{
+			{Scope: "Apple", Name: "is red", Path: "./spec/apple_spec.rb[1:1]", Identifier: "./spec/apple_spec.rb[1:1]", ExecutionCount: 3},
+		},
+	}
+
+	var got FlakyTestsParams
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/analytics/organizations/buildkite/suites/rspec/test_plan/flaky_tests" {
+			http.Error(w, `{"message": "Not found"}`, http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("json.Decode() error = %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer svr.Close()
+
+	c := NewClient(ClientConfig{
+		AccessToken:      "asdf1234",
+		OrganizationSlug: "buildkite",
+		ServerBaseUrl:    svr.URL,
+	})
+
+	if err := c.PostFlakyTests(context.Background(), "rspec", params); err != nil {
+		t.Errorf("PostFlakyTests() error = %v", err)
+	}
+
+	if diff := cmp.Diff(got, params); diff != "" {
+		t.Errorf("PostFlakyTests() request body diff (-got +want):\n%s", diff)
+	}
+}
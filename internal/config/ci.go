@@ -0,0 +1,205 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The CI providers bktec derives the identifier, node index, parallelism and branch of the build from.
+const (
+	CIProviderBuildkite     = "buildkite"
+	CIProviderGitHubActions = "github_actions"
+	CIProviderGitLab        = "gitlab"
+	CIProviderCircleCI      = "circleci"
+	CIProviderJenkins       = "jenkins"
+)
+
+// ciProvider describes where a CI provider exposes the build and job that bktec runs in.
+type ciProvider struct {
+	// name is the name of the provider, one of the CIProvider constants.
+	name string
+	// detectEnv is the environment variable the provider sets in every job.
+	detectEnv string
+	// envs maps the Buildkite environment variables to the environment variables of the provider
+	// they fall back to, in order of preference.
+	envs map[string][]string
+	// convert converts the value of an environment variable of the provider
+	// to the value of the Buildkite environment variable, when they differ.
+	convert func(key, value string) string
+	// envKeys are the environment variables of the provider sent to Test Engine, see DumpEnv.
+	envKeys []string
+}
+
+// gitlabParallelJobName matches the suffix GitLab appends to the name of each parallel job, e.g. "rspec 2/4".
+var gitlabParallelJobName = regexp.MustCompile(`\s+\d+/\d+$`)
+
+// ciProviders are the supported CI providers, in order of detection.
+// Buildkite comes first and is the default when no provider is detected.
+var ciProviders = []ciProvider{
+	{
+		name:      CIProviderBuildkite,
+		detectEnv: "BUILDKITE",
+		envKeys: []string{
+			"BUILDKITE_BUILD_ID",
+			"BUILDKITE_JOB_ID",
+			"BUILDKITE_PARALLEL_JOB_COUNT",
+			"BUILDKITE_PARALLEL_JOB",
+			"BUILDKITE_STEP_ID",
+			"BUILDKITE_BRANCH",
+		},
+	},
+	{
+		// GitHub Actions doesn't expose the index and total of matrix jobs as environment variables,
+		// they need to be passed from ${{ strategy.job-index }} and ${{ strategy.job-total }}.
+		name:      CIProviderGitHubActions,
+		detectEnv: "GITHUB_ACTIONS",
+		envs: map[string][]string{
+			"BUILDKITE_BUILD_ID":                 {"GITHUB_RUN_ID"},
+			"BUILDKITE_STEP_ID":                  {"GITHUB_JOB"},
+			"BUILDKITE_BRANCH":                   {"GITHUB_HEAD_REF", "GITHUB_REF_NAME"},
+			"BUILDKITE_PULL_REQUEST_BASE_BRANCH": {"GITHUB_BASE_REF"},
+		},
+		envKeys: []string{
+			"GITHUB_REPOSITORY",
+			"GITHUB_WORKFLOW",
+			"GITHUB_RUN_ID",
+			"GITHUB_RUN_NUMBER",
+			"GITHUB_RUN_ATTEMPT",
+			"GITHUB_JOB",
+			"GITHUB_REF_NAME",
+			"GITHUB_HEAD_REF",
+			"GITHUB_SHA",
+		},
+	},
+	{
+		name:      CIProviderGitLab,
+		detectEnv: "GITLAB_CI",
+		envs: map[string][]string{
+			"BUILDKITE_BUILD_ID":                 {"CI_PIPELINE_ID"},
+			"BUILDKITE_STEP_ID":                  {"CI_JOB_NAME"},
+			"BUILDKITE_PARALLEL_JOB":             {"CI_NODE_INDEX"},
+			"BUILDKITE_PARALLEL_JOB_COUNT":       {"CI_NODE_TOTAL"},
+			"BUILDKITE_BRANCH":                   {"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_REF_NAME"},
+			"BUILDKITE_PULL_REQUEST_BASE_BRANCH": {"CI_MERGE_REQUEST_TARGET_BRANCH_NAME"},
+		},
+		convert: func(key, value string) string {
+			switch key {
+			case "BUILDKITE_STEP_ID":
+				// The parallel jobs of a step share the test plan, so the "2/4" suffix is removed.
+				return gitlabParallelJobName.ReplaceAllString(value, "")
+			case "BUILDKITE_PARALLEL_JOB":
+				// GitLab counts the parallel jobs from 1.
+				if index, err := strconv.Atoi(value); err == nil {
+					return strconv.Itoa(index - 1)
+				}
+			}
+			return value
+		},
+		envKeys: []string{
+			"CI_PROJECT_PATH",
+			"CI_PIPELINE_ID",
+			"CI_JOB_ID",
+			"CI_JOB_NAME",
+			"CI_NODE_INDEX",
+			"CI_NODE_TOTAL",
+			"CI_COMMIT_REF_NAME",
+			"CI_COMMIT_SHA",
+		},
+	},
+	{
+		name:      CIProviderCircleCI,
+		detectEnv: "CIRCLECI",
+		envs: map[string][]string{
+			"BUILDKITE_BUILD_ID":           {"CIRCLE_WORKFLOW_ID"},
+			"BUILDKITE_STEP_ID":            {"CIRCLE_JOB"},
+			"BUILDKITE_PARALLEL_JOB":       {"CIRCLE_NODE_INDEX"},
+			"BUILDKITE_PARALLEL_JOB_COUNT": {"CIRCLE_NODE_TOTAL"},
+			"BUILDKITE_BRANCH":             {"CIRCLE_BRANCH"},
+		},
+		envKeys: []string{
+			"CIRCLE_PROJECT_REPONAME",
+			"CIRCLE_WORKFLOW_ID",
+			"CIRCLE_BUILD_NUM",
+			"CIRCLE_JOB",
+			"CIRCLE_NODE_INDEX",
+			"CIRCLE_NODE_TOTAL",
+			"CIRCLE_BRANCH",
+			"CIRCLE_SHA1",
+		},
+	},
+	{
+		// Jenkins doesn't have parallel jobs, the node index and parallelism need to be set explicitly.
+		name:      CIProviderJenkins,
+		detectEnv: "JENKINS_URL",
+		envs: map[string][]string{
+			"BUILDKITE_BUILD_ID":                 {"BUILD_TAG"},
+			"BUILDKITE_STEP_ID":                  {"STAGE_NAME"},
+			"BUILDKITE_BRANCH":                   {"CHANGE_BRANCH", "BRANCH_NAME", "GIT_BRANCH"},
+			"BUILDKITE_PULL_REQUEST_BASE_BRANCH": {"CHANGE_TARGET"},
+		},
+		envKeys: []string{
+			"JOB_NAME",
+			"BUILD_TAG",
+			"BUILD_NUMBER",
+			"STAGE_NAME",
+			"BRANCH_NAME",
+			"GIT_BRANCH",
+			"GIT_COMMIT",
+		},
+	},
+}
+
+// findCIProvider returns the CI provider of the name, or Buildkite if there is no such provider.
+func findCIProvider(name string) (ciProvider, bool) {
+	for _, p := range ciProviders {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return ciProviders[0], false
+}
+
+// readCIProvider sets the CI provider named by BUILDKITE_TEST_ENGINE_CI_PROVIDER,
+// or the first one detected in the environment, defaulting to Buildkite.
+func (c *Config) readCIProvider() {
+	if name := c.getenv("BUILDKITE_TEST_ENGINE_CI_PROVIDER"); name != "" {
+		provider, ok := findCIProvider(name)
+		if !ok {
+			names := make([]string, len(ciProviders))
+			for i, p := range ciProviders {
+				names[i] = p.name
+			}
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_CI_PROVIDER", "was %q, must be one of %s", name, strings.Join(names, ", "))
+		}
+		c.ci = provider
+		c.CIProvider = provider.name
+		return
+	}
+
+	c.ci = ciProviders[0]
+	for _, p := range ciProviders {
+		if os.Getenv(p.detectEnv) != "" {
+			c.ci = p
+			break
+		}
+	}
+	c.CIProvider = c.ci.name
+}
+
+// lookup returns the value of the first environment variable of the provider the key falls back to,
+// along with the name of that environment variable.
+func (p ciProvider) lookup(key string) (value string, source string) {
+	for _, env := range p.envs[key] {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		if p.convert != nil {
+			value = p.convert(key, value)
+		}
+		return value, env
+	}
+	return "", ""
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfigReadFromEnv_CIProvider(t *testing.T) {
+	type ciConfig struct {
+		CIProvider     string
+		Identifier     string
+		NodeIndex      int
+		Parallelism    int
+		Branch         string
+		ChangedBaseRef string
+	}
+
+	cases := []struct {
+		name string
+		env  map[string]string
+		want ciConfig
+	}{
+		{
+			name: "buildkite",
+			env: map[string]string{
+				"BUILDKITE":                    "true",
+				"BUILDKITE_BUILD_ID":           "123",
+				"BUILDKITE_STEP_ID":            "456",
+				"BUILDKITE_PARALLEL_JOB":       "2",
+				"BUILDKITE_PARALLEL_JOB_COUNT": "4",
+				"BUILDKITE_BRANCH":             "feature",
+			},
+			want: ciConfig{CIProvider: "buildkite", Identifier: "123/456", NodeIndex: 2, Parallelism: 4, Branch: "feature", ChangedBaseRef: "origin/main"},
+		},
+		{
+			name: "github_actions",
+			env: map[string]string{
+				"GITHUB_ACTIONS":  "true",
+				"GITHUB_RUN_ID":   "9876",
+				"GITHUB_JOB":      "rspec",
+				"GITHUB_REF_NAME": "42/merge",
+				"GITHUB_HEAD_REF": "feature",
+				"GITHUB_BASE_REF": "develop",
+			},
+			want: ciConfig{CIProvider: "github_actions", Identifier: "9876/rspec", NodeIndex: 0, Parallelism: 1, Branch: "feature", ChangedBaseRef: "origin/develop"},
+		},
+		{
+			name: "github_actions matrix job",
+			env: map[string]string{
+				"GITHUB_ACTIONS":               "true",
+				"GITHUB_RUN_ID":                "9876",
+				"GITHUB_JOB":                   "rspec",
+				"GITHUB_REF_NAME":              "main",
+				"BUILDKITE_PARALLEL_JOB":       "1",
+				"BUILDKITE_PARALLEL_JOB_COUNT": "3",
+			},
+			want: ciConfig{CIProvider: "github_actions", Identifier: "9876/rspec", NodeIndex: 1, Parallelism: 3, Branch: "main", ChangedBaseRef: "origin/main"},
+		},
+		{
+			name: "gitlab",
+			env: map[string]string{
+				"GITLAB_CI":          "true",
+				"CI_PIPELINE_ID":     "555",
+				"CI_JOB_NAME":        "rspec 3/4",
+				"CI_NODE_INDEX":      "3",
+				"CI_NODE_TOTAL":      "4",
+				"CI_COMMIT_REF_NAME": "feature",
+			},
+			want: ciConfig{CIProvider: "gitlab", Identifier: "555/rspec", NodeIndex: 2, Parallelism: 4, Branch: "feature", ChangedBaseRef: "origin/main"},
+		},
+		{
+			name: "gitlab without parallel",
+			env: map[string]string{
+				"GITLAB_CI":                           "true",
+				"CI_PIPELINE_ID":                      "555",
+				"CI_JOB_NAME":                         "rspec",
+				"CI_NODE_TOTAL":                       "1",
+				"CI_COMMIT_REF_NAME":                  "feature",
+				"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "feature",
+				"CI_MERGE_REQUEST_TARGET_BRANCH_NAME": "develop",
+			},
+			want: ciConfig{CIProvider: "gitlab", Identifier: "555/rspec", NodeIndex: 0, Parallelism: 1, Branch: "feature", ChangedBaseRef: "origin/develop"},
+		},
+		{
+			name: "circleci",
+			env: map[string]string{
+				"CIRCLECI":           "true",
+				"CIRCLE_WORKFLOW_ID": "abc-def",
+				"CIRCLE_JOB":         "test",
+				"CIRCLE_NODE_INDEX":  "0",
+				"CIRCLE_NODE_TOTAL":  "5",
+				"CIRCLE_BRANCH":      "feature",
+			},
+			want: ciConfig{CIProvider: "circleci", Identifier: "abc-def/test", NodeIndex: 0, Parallelism: 5, Branch: "feature", ChangedBaseRef: "origin/main"},
+		},
+		{
+			name: "jenkins",
+			env: map[string]string{
+				"JENKINS_URL": "https://jenkins.localhost/",
+				"BUILD_TAG":   "jenkins-my-app-12",
+				"STAGE_NAME":  "Test",
+				"BRANCH_NAME": "feature",
+			},
+			want: ciConfig{CIProvider: "jenkins", Identifier: "jenkins-my-app-12/Test", NodeIndex: 0, Parallelism: 1, Branch: "feature", ChangedBaseRef: "origin/main"},
+		},
+		{
+			name: "buildkite environment variables take precedence",
+			env: map[string]string{
+				"CIRCLECI":           "true",
+				"CIRCLE_WORKFLOW_ID": "abc-def",
+				"CIRCLE_JOB":         "test",
+				"CIRCLE_NODE_INDEX":  "0",
+				"CIRCLE_NODE_TOTAL":  "5",
+				"BUILDKITE_STEP_ID":  "unit",
+			},
+			want: ciConfig{CIProvider: "circleci", Identifier: "abc-def/unit", NodeIndex: 0, Parallelism: 5, ChangedBaseRef: "origin/main"},
+		},
+		{
+			name: "provider set explicitly",
+			env: map[string]string{
+				"BUILDKITE_TEST_ENGINE_CI_PROVIDER": "circleci",
+				"GITHUB_ACTIONS":                    "true",
+				"CIRCLE_WORKFLOW_ID":                "abc-def",
+				"CIRCLE_JOB":                        "test",
+			},
+			want: ciConfig{CIProvider: "circleci", Identifier: "abc-def/test", NodeIndex: 0, Parallelism: 1, ChangedBaseRef: "origin/main"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tc.env {
+				os.Setenv(k, v)
+			}
+			defer os.Clearenv()
+
+			c := Config{errs: InvalidConfigError{}}
+			if err := c.readFromEnv(); err != nil {
+				t.Errorf("config.readFromEnv() error = %v", err)
+			}
+
+			got := ciConfig{
+				CIProvider:     c.CIProvider,
+				Identifier:     c.Identifier,
+				NodeIndex:      c.NodeIndex,
+				Parallelism:    c.Parallelism,
+				Branch:         c.Branch,
+				ChangedBaseRef: c.ChangedBaseRef,
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("config.readFromEnv() diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigReadFromEnv_CIProviderInvalidValue(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("GITLAB_CI", "true")
+	os.Setenv("CI_PIPELINE_ID", "555")
+	os.Setenv("CI_JOB_NAME", "rspec 1/4")
+	os.Setenv("CI_NODE_INDEX", "1")
+	os.Setenv("CI_NODE_TOTAL", "four")
+	defer os.Clearenv()
+
+	c := Config{errs: InvalidConfigError{}}
+	err := c.readFromEnv()
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.readFromEnv() error = %v, want InvalidConfigError", err)
+	}
+
+	want := `BUILDKITE_PARALLEL_JOB_COUNT was "four", must be a number (set by CI_NODE_TOTAL)`
+	if got := invConfigError.Error(); got != want {
+		t.Errorf("config.readFromEnv() error = %q, want %q", got, want)
+	}
+}
+
+func TestConfigReadFromEnv_CIProviderMissingBuildId(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("JENKINS_URL", "https://jenkins.localhost/")
+	os.Setenv("BUILD_TAG", "jenkins-my-app-12")
+	defer os.Clearenv()
+
+	c := Config{errs: InvalidConfigError{}}
+	err := c.readFromEnv()
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.readFromEnv() error = %v, want InvalidConfigError", err)
+	}
+
+	want := "BUILDKITE_STEP_ID must not be blank"
+	if got := invConfigError.Error(); got != want {
+		t.Errorf("config.readFromEnv() error = %q, want %q", got, want)
+	}
+}
+
+func TestConfigReadFromEnv_UnknownCIProvider(t *testing.T) {
+	os.Clearenv()
+	setEnv(t)
+	os.Setenv("BUILDKITE_TEST_ENGINE_CI_PROVIDER", "travis")
+	defer os.Clearenv()
+
+	c := Config{errs: InvalidConfigError{}}
+	err := c.readFromEnv()
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.readFromEnv() error = %v, want InvalidConfigError", err)
+	}
+
+	want := `BUILDKITE_TEST_ENGINE_CI_PROVIDER was "travis", must be one of buildkite, github_actions, gitlab, circleci, jenkins`
+	if got := invConfigError.Error(); got != want {
+		t.Errorf("config.readFromEnv() error = %q, want %q", got, want)
+	}
+}
+
+func TestDumpEnv_CIProvider(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("CI_PIPELINE_ID", "555")
+	os.Setenv("CI_NODE_INDEX", "2")
+	os.Setenv("BUILDKITE_BUILD_ID", "123")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	defer os.Clearenv()
+
+	c := Config{CIProvider: CIProviderGitLab, Identifier: "555/rspec"}
+	got := c.DumpEnv()
+
+	if got["CI_PIPELINE_ID"] != "555" || got["CI_NODE_INDEX"] != "2" {
+		t.Errorf("DumpEnv() = %v, want the GitLab environment variables", got)
+	}
+
+	if got["BUILDKITE_TEST_ENGINE_SUITE_SLUG"] != "my_suite" || got["BUILDKITE_TEST_ENGINE_IDENTIFIER"] != "555/rspec" {
+		t.Errorf("DumpEnv() = %v, want the Test Engine environment variables", got)
+	}
+
+	if _, ok := got["BUILDKITE_BUILD_ID"]; ok {
+		t.Errorf("DumpEnv() = %v, want no Buildkite build environment variables", got)
+	}
+}
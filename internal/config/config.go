@@ -1,5 +1,7 @@
 package config
 
+import "os"
+
 // Config is the internal representation of the complete test engine client configuration.
 type Config struct {
 	// AccessToken is the access token for the API.
@@ -34,19 +36,101 @@ type Config struct {
 	TestRunner string
 	// Branch is the string value of the git branch name, used by Buildkite only.
 	Branch string
+	// PlanFile is the path to a test plan file. If the file exists, the test plan is read from it instead of the API.
+	PlanFile string
+	// PlanOutputFile is the path to write the test plan to.
+	PlanOutputFile string
+	// TimingCachePath is the path to the local timing cache file.
+	TimingCachePath string
+	// JUnitReportPath is the path to write the JUnit XML report of the combined run result to.
+	JUnitReportPath string
+	// SummaryPath is the path to write the JSON summary of the run to.
+	SummaryPath string
+	// AnnotationPath is the path to write the Markdown annotation of the run to.
+	AnnotationPath string
+	// Annotate is the flag to pipe the annotation of the run to `buildkite-agent annotate`.
+	Annotate bool
+	// AgentPath is the path to the buildkite-agent binary used to annotate the build.
+	AgentPath string
+	// Mode is the mode of running the test plan, either "static" or "dynamic".
+	// In static mode, each node runs the task assigned to it in the test plan.
+	// In dynamic mode, the nodes claim batches of test cases from a shared queue until it's drained.
+	Mode string
+	// BatchSize is the number of test cases claimed at a time in dynamic mode.
+	BatchSize int
+	// LocalConcurrency is the number of test processes run concurrently on this node.
+	LocalConcurrency int
+	// SelectChangedTests is the flag to run only the test files affected by the changes against ChangedBaseRef.
+	SelectChangedTests bool
+	// ChangedBaseRef is the git ref to diff against to find the changed files.
+	ChangedBaseRef string
+	// ChangedMappingFile is the path to the file mapping source files to test files.
+	ChangedMappingFile string
+	// DefaultBranch is the default branch of the pipeline, on which all the tests are run.
+	DefaultBranch string
+	// ConfigFile is the path to the config file the configuration is read from, along with the environment variables.
+	ConfigFile string
+	// Profile is the name of the profile of the config file selected for this step.
+	Profile string
+	// CIProvider is the name of the CI provider the identifier, node index, parallelism and branch are derived from.
+	CIProvider string
+	// ci is the CI provider named by CIProvider.
+	ci ciProvider
+	// flags is the values of the command-line flags.
+	flags Flags
+	// file is the content of the config file.
+	file *configFile
 	// errs is a map of environment variables name and the validation errors associated with them.
 	errs InvalidConfigError
 }
 
-// New wraps the readFromEnv and validate functions to create a new Config struct.
-// It returns Config struct and an InvalidConfigError if there is an invalid configuration.
+const (
+	ModeStatic  = "static"
+	ModeDynamic = "dynamic"
+)
+
+// HasPlanFile returns true if the test plan file is set and exists.
+// In this case, the test plan is read from the file, and the API is not used.
+func (c Config) HasPlanFile() bool {
+	if c.PlanFile == "" {
+		return false
+	}
+
+	_, err := os.Stat(c.PlanFile)
+	return err == nil
+}
+
+// New creates a new Config struct from the environment variables and the config file,
+// without command-line flags. See NewWithFlags.
 func New() (Config, error) {
-	c := Config{errs: InvalidConfigError{}}
+	return NewWithFlags(nil)
+}
+
+// NewWithFlags wraps the readFromFile, readFromEnv and validate functions to create a new Config struct.
+// The command-line flags take precedence over the environment variables,
+// which take precedence over the environment variables of the CI provider,
+// which take precedence over the selected profile of the config file,
+// which takes precedence over the top level values of the config file.
+// It returns Config struct and an InvalidConfigError if there is an invalid configuration.
+func NewWithFlags(flags Flags) (Config, error) {
+	return NewWithFlagsExcept(flags)
+}
 
+// NewWithFlagsExcept creates a new Config struct like NewWithFlags, except that the validation errors
+// of the given environment variables are ignored, for the commands that don't use them.
+// For example, listing the test files doesn't need the API access token nor the build ID.
+func NewWithFlagsExcept(flags Flags, except ...string) (Config, error) {
+	c := Config{errs: InvalidConfigError{}, flags: flags}
+
+	c.readFromFile()
 	// TODO: remove error from readFromEnv and validate functions
 	_ = c.readFromEnv()
 	_ = c.validate()
 
+	for _, key := range except {
+		delete(c.errs, key)
+	}
+
 	if len(c.errs) > 0 {
 		return Config{}, c.errs
 	}
@@ -44,6 +44,12 @@ func TestNewConfig(t *testing.T) {
 		ResultPath:       "tmp/rspec.json",
 		SuiteSlug:        "my_suite",
 		TestRunner:       "rspec",
+		Mode:             "static",
+		BatchSize:        5,
+		LocalConcurrency: 1,
+		ChangedBaseRef:   "origin/main",
+		AgentPath:        "buildkite-agent",
+		CIProvider:       "buildkite",
 		errs:             InvalidConfigError{},
 	}
 
@@ -83,6 +89,12 @@ func TestNewConfig_MissingConfigWithDefault(t *testing.T) {
 		OrganizationSlug: "my_org",
 		SuiteSlug:        "my_suite",
 		TestRunner:       "rspec",
+		Mode:             "static",
+		BatchSize:        5,
+		LocalConcurrency: 1,
+		ChangedBaseRef:   "origin/main",
+		AgentPath:        "buildkite-agent",
+		CIProvider:       "buildkite",
 		ResultPath:       "tmp/rspec.json",
 	}
 
@@ -108,3 +120,34 @@ func TestNewConfig_InvalidConfig(t *testing.T) {
 		t.Errorf("config.readFromEnv() error length = %d, want 2", len(invConfigError))
 	}
 }
+
+func TestNewWithFlagsExcept(t *testing.T) {
+	setEnv(t)
+	os.Unsetenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN")
+	os.Unsetenv("BUILDKITE_BUILD_ID")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "-1")
+	defer os.Clearenv()
+
+	c, err := NewWithFlagsExcept(nil, "BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "BUILDKITE_BUILD_ID")
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.NewWithFlagsExcept() error = %v, want InvalidConfigError", err)
+	}
+
+	want := "BUILDKITE_TEST_ENGINE_RETRY_COUNT was -1, must be greater than or equal to 0"
+	if diff := cmp.Diff(err.Error(), want); diff != "" {
+		t.Errorf("config.NewWithFlagsExcept() error diff (-got +want):\n%s", diff)
+	}
+
+	os.Unsetenv("BUILDKITE_TEST_ENGINE_RETRY_COUNT")
+
+	c, err = NewWithFlagsExcept(nil, "BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "BUILDKITE_BUILD_ID")
+	if err != nil {
+		t.Errorf("config.NewWithFlagsExcept() error = %v", err)
+	}
+
+	if c.TestRunner != "rspec" {
+		t.Errorf("TestRunner = %q, want %q", c.TestRunner, "rspec")
+	}
+}
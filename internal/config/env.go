@@ -5,22 +5,20 @@ import (
 	"strconv"
 )
 
-// getEnvWithDefault retrieves the value of the environment variable named by the key.
+// getEnvWithDefault retrieves the value of the environment variable named by the key,
+// or of its command-line flag or config file key, see lookup.
 // If the variable is present and not empty, the value is returned.
 // Otherwise the returned value will be the default value.
-func getEnvWithDefault(key string, defaultValue string) string {
-	value, ok := os.LookupEnv(key)
-	if !ok {
-		return defaultValue
-	}
+func (c *Config) getEnvWithDefault(key string, defaultValue string) string {
+	value := c.getenv(key)
 	if value == "" {
 		return defaultValue
 	}
 	return value
 }
 
-func getIntEnvWithDefault(key string, defaultValue int) (int, error) {
-	value := os.Getenv(key)
+func (c *Config) getIntEnvWithDefault(key string, defaultValue int) (int, error) {
+	value := c.getenv(key)
 	// If the environment variable is not set, return the default value.
 	if value == "" {
 		return defaultValue, nil
@@ -34,13 +32,11 @@ func getIntEnvWithDefault(key string, defaultValue int) (int, error) {
 	return valueInt, nil
 }
 
+// DumpEnv returns the environment variables sent to Test Engine along with the test plan,
+// including the ones of the CI provider.
 func (c Config) DumpEnv() map[string]string {
 	keys := []string{
-		"BUILDKITE_BUILD_ID",
-		"BUILDKITE_JOB_ID",
 		"BUILDKITE_ORGANIZATION_SLUG",
-		"BUILDKITE_PARALLEL_JOB_COUNT",
-		"BUILDKITE_PARALLEL_JOB",
 		"BUILDKITE_TEST_ENGINE_DEBUG_ENABLED",
 		"BUILDKITE_TEST_ENGINE_RETRY_COUNT",
 		"BUILDKITE_TEST_ENGINE_RETRY_CMD",
@@ -50,9 +46,9 @@ func (c Config) DumpEnv() map[string]string {
 		"BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN",
 		"BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN",
 		"BUILDKITE_TEST_ENGINE_TEST_RUNNER",
-		"BUILDKITE_STEP_ID",
-		"BUILDKITE_BRANCH",
 	}
+	provider, _ := findCIProvider(c.CIProvider)
+	keys = append(keys, provider.envKeys...)
 
 	envs := make(map[string]string)
 	for _, key := range keys {
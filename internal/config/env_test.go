@@ -51,7 +51,8 @@ func TestGetIntEnvWithDefault(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.key, func(t *testing.T) {
-			got, err := getIntEnvWithDefault(tt.key, tt.defaultValue)
+			c := Config{}
+			got, err := c.getIntEnvWithDefault(tt.key, tt.defaultValue)
 			if err != nil && !errors.Is(err, tt.err) {
 				t.Errorf("getIntEnvWithDefault(%q, %d) error = %v, want %v", tt.key, tt.defaultValue, err, tt.err)
 			}
@@ -101,7 +102,8 @@ func TestGetEnvWithDefault(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.key, func(t *testing.T) {
-			if got := getEnvWithDefault(tt.key, tt.defaultValue); got != tt.want {
+			c := Config{}
+			if got := c.getEnvWithDefault(tt.key, tt.defaultValue); got != tt.want {
 				t.Errorf("getEnvWithDefault(%q, %q) = %q, want %q", tt.key, tt.defaultValue, got, tt.want)
 			}
 		})
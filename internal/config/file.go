@@ -0,0 +1,466 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// configFileNames are the names of the config file looked up in the working directory, in order.
+var configFileNames = []string{".bktec.yml", ".bktec.yaml", ".bktec.toml"}
+
+// fileKeys maps the keys of the config file to the environment variables they stand in for.
+var fileKeys = map[string]string{
+	"test_runner":               "BUILDKITE_TEST_ENGINE_TEST_RUNNER",
+	"test_cmd":                  "BUILDKITE_TEST_ENGINE_TEST_CMD",
+	"retry_cmd":                 "BUILDKITE_TEST_ENGINE_RETRY_CMD",
+	"test_file_pattern":         "BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN",
+	"test_file_exclude_pattern": "BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN",
+	"retry_count":               "BUILDKITE_TEST_ENGINE_RETRY_COUNT",
+	"result_path":               "BUILDKITE_TEST_ENGINE_RESULT_PATH",
+}
+
+// configFile is the content of a config file.
+// The values at the top level of the file are the defaults of all steps,
+// and each profile overrides some of them for the steps that select it.
+type configFile struct {
+	path     string
+	values   map[string]string
+	profiles map[string]map[string]string
+}
+
+// findConfigFile returns the path of the config file in the working directory, or an empty string if there is none.
+func findConfigFile() string {
+	for _, name := range configFileNames {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// readConfigFile reads the config file at the given path.
+// Files with the .toml extension are parsed as TOML, any other file as YAML.
+// Only a subset of both formats is supported: string, integer and boolean values,
+// and the profiles as nested mappings in YAML or as [profiles.<name>] tables in TOML.
+func readConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &configFile{
+		path:     path,
+		values:   map[string]string{},
+		profiles: map[string]map[string]string{},
+	}
+
+	if filepath.Ext(path) == ".toml" {
+		err = f.parseTOML(string(data))
+	} else {
+		err = f.parseYAML(string(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// set sets the value of the key in the given profile, or at the top level if the profile is empty.
+func (f *configFile) set(profile, key, value string) error {
+	if _, ok := fileKeys[key]; !ok {
+		return fmt.Errorf("unknown key %q", key)
+	}
+
+	values := f.values
+	if profile != "" {
+		values = f.profiles[profile]
+	}
+
+	if _, ok := values[key]; ok {
+		return fmt.Errorf("duplicate key %q", key)
+	}
+	values[key] = value
+
+	return nil
+}
+
+func (f *configFile) addProfile(profile string) error {
+	if profile == "" {
+		return errors.New("profile name must not be blank")
+	}
+	if _, ok := f.profiles[profile]; ok {
+		return fmt.Errorf("duplicate profile %q", profile)
+	}
+	f.profiles[profile] = map[string]string{}
+	return nil
+}
+
+// profileNames returns the sorted names of the profiles in the file.
+func (f *configFile) profileNames() []string {
+	var names []string
+	for name := range f.profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// parseYAML parses a YAML config file such as:
+//
+//	test_runner: rspec
+//	retry_count: 2
+//	profiles:
+//	  ci:
+//	    retry_count: 3
+func (f *configFile) parseYAML(data string) error {
+	inProfiles := false
+	profile := ""
+	profileIndent, keyIndent := 0, 0
+
+	for i, raw := range strings.Split(data, "\n") {
+		lineNumber := i + 1
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+
+		indentation := raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+		if strings.Contains(indentation, "\t") {
+			return fmt.Errorf("line %d: tabs are not allowed for indentation", lineNumber)
+		}
+		indent := len(indentation)
+
+		key, rawValue, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return fmt.Errorf("line %d: expected \"key: value\"", lineNumber)
+		}
+		key = strings.TrimSpace(key)
+		value, err := parseYAMLValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		switch {
+		case indent == 0:
+			inProfiles, profile, profileIndent = false, "", 0
+			if key == "profiles" {
+				if value != "" {
+					return fmt.Errorf("line %d: profiles must be a mapping of profile names", lineNumber)
+				}
+				inProfiles = true
+				continue
+			}
+			err = f.set("", key, value)
+
+		case !inProfiles:
+			return fmt.Errorf("line %d: unexpected indentation", lineNumber)
+
+		case profileIndent == 0 || indent == profileIndent:
+			if value != "" {
+				return fmt.Errorf("line %d: profile %q must be a mapping", lineNumber, key)
+			}
+			profile, profileIndent, keyIndent = key, indent, 0
+			err = f.addProfile(profile)
+
+		case indent > profileIndent:
+			if keyIndent == 0 {
+				keyIndent = indent
+			} else if indent != keyIndent {
+				return fmt.Errorf("line %d: unexpected indentation", lineNumber)
+			}
+			err = f.set(profile, key, value)
+
+		default:
+			return fmt.Errorf("line %d: unexpected indentation", lineNumber)
+		}
+
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	return nil
+}
+
+// parseYAMLValue parses a plain, single-quoted or double-quoted YAML scalar, followed by an optional comment.
+func parseYAMLValue(value string) (string, error) {
+	switch {
+	case value == "":
+		return "", nil
+
+	case value[0] == '"':
+		quoted, rest, err := cutDoubleQuoted(value)
+		if err != nil {
+			return "", err
+		}
+		return quoted, checkTrailingComment(rest)
+
+	case value[0] == '\'':
+		// In single-quoted scalars, a single quote is escaped by doubling it.
+		end := 1
+		for {
+			idx := strings.IndexByte(value[end:], '\'')
+			if idx < 0 {
+				return "", errors.New("unterminated string")
+			}
+			end += idx
+			if end+1 < len(value) && value[end+1] == '\'' {
+				end += 2
+				continue
+			}
+			break
+		}
+		return strings.ReplaceAll(value[1:end], "''", "'"), checkTrailingComment(value[end+1:])
+
+	case strings.ContainsRune("[{|>&*!", rune(value[0])):
+		return "", errors.New("only string, integer and boolean values are supported")
+
+	default:
+		if idx := strings.Index(value, " #"); idx >= 0 {
+			value = value[:idx]
+		}
+		return strings.TrimSpace(value), nil
+	}
+}
+
+// parseTOML parses a TOML config file such as:
+//
+//	test_runner = "rspec"
+//	retry_count = 2
+//
+//	[profiles.ci]
+//	retry_count = 3
+func (f *configFile) parseTOML(data string) error {
+	profile := ""
+
+	for i, raw := range strings.Split(data, "\n") {
+		lineNumber := i + 1
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "[") {
+			header, _, _ := strings.Cut(trimmed, "#")
+			header = strings.TrimSpace(header)
+			if !strings.HasSuffix(header, "]") || strings.HasPrefix(header, "[[") {
+				return fmt.Errorf("line %d: invalid table header %q", lineNumber, header)
+			}
+
+			name, ok := strings.CutPrefix(strings.TrimSpace(header[1:len(header)-1]), "profiles.")
+			if !ok {
+				return fmt.Errorf("line %d: unknown table %q, only [profiles.<name>] tables are supported", lineNumber, header)
+			}
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+
+			profile = name
+			if err := f.addProfile(profile); err != nil {
+				return fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			continue
+		}
+
+		key, rawValue, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			return fmt.Errorf("line %d: expected \"key = value\"", lineNumber)
+		}
+		value, err := parseTOMLValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		if err := f.set(profile, strings.TrimSpace(key), value); err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	return nil
+}
+
+// parseTOMLValue parses a basic string, literal string, integer or boolean TOML value, followed by an optional comment.
+func parseTOMLValue(value string) (string, error) {
+	switch {
+	case value == "":
+		return "", errors.New("missing value")
+
+	case value[0] == '"':
+		quoted, rest, err := cutDoubleQuoted(value)
+		if err != nil {
+			return "", err
+		}
+		return quoted, checkTrailingComment(rest)
+
+	case value[0] == '\'':
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		return value[1 : end+1], checkTrailingComment(value[end+2:])
+
+	default:
+		if idx := strings.IndexByte(value, '#'); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+		if value == "true" || value == "false" {
+			return value, nil
+		}
+		if _, err := strconv.ParseInt(strings.ReplaceAll(value, "_", ""), 10, 64); err == nil {
+			return strings.ReplaceAll(value, "_", ""), nil
+		}
+		return "", fmt.Errorf("unsupported value %q, only string, integer and boolean values are supported", value)
+	}
+}
+
+// cutDoubleQuoted unquotes the double-quoted string at the start of s, and returns it with the rest of s.
+func cutDoubleQuoted(s string) (quoted, rest string, err error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return quoted, s[i+1:], nil
+		}
+	}
+	return "", "", errors.New("unterminated string")
+}
+
+// checkTrailingComment returns an error if rest contains anything other than a comment.
+func checkTrailingComment(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected %q after string", rest)
+	}
+	return nil
+}
+
+// readFromFile reads the config file, and the profile selected for the step.
+// The config file is set by BUILDKITE_TEST_ENGINE_CONFIG_FILE (--config-file), or found in the working directory,
+// and the profile is selected by BUILDKITE_TEST_ENGINE_PROFILE (--profile).
+// The values read from the file are used for the environment variables that are not set.
+func (c *Config) readFromFile() {
+	c.ConfigFile = c.getenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE")
+	if c.ConfigFile == "" {
+		c.ConfigFile = findConfigFile()
+	}
+	c.Profile = c.getenv("BUILDKITE_TEST_ENGINE_PROFILE")
+
+	if c.ConfigFile == "" {
+		if c.Profile != "" {
+			c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_PROFILE", "was %q, but no config file was found", c.Profile)
+		}
+		return
+	}
+
+	file, err := readConfigFile(c.ConfigFile)
+	if err != nil {
+		c.errs.appendFieldError(c.ConfigFile, "is invalid: %v", err)
+		return
+	}
+	c.file = file
+
+	if _, ok := file.profiles[c.Profile]; c.Profile != "" && !ok {
+		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_PROFILE", "was %q, must be one of the profiles in %s: %s", c.Profile, c.ConfigFile, strings.Join(file.profileNames(), ", "))
+	}
+}
+
+// lookup returns the value of the command-line flag of the environment variable if it's set,
+// then the value of the environment variable if it's set,
+// then the value of the environment variable of the CI provider it falls back to,
+// otherwise the value of the matching key in the selected profile or at the top level of the config file.
+// The source of the value is returned when it comes from a flag, the CI provider or the config file,
+// and is empty when it comes from the environment.
+func (c *Config) lookup(key string) (value string, source string) {
+	if value, ok := c.flags[key]; ok {
+		return value, "--" + flagName(key)
+	}
+
+	if value := os.Getenv(key); value != "" {
+		return value, ""
+	}
+
+	if value, source := c.ci.lookup(key); source != "" || c.file == nil {
+		return value, source
+	}
+
+	for fileKey, envKey := range fileKeys {
+		if envKey != key {
+			continue
+		}
+
+		if value, ok := c.file.profiles[c.Profile][fileKey]; ok {
+			return value, fmt.Sprintf("%s in profile %q of %s", fileKey, c.Profile, c.file.path)
+		}
+		if value, ok := c.file.values[fileKey]; ok {
+			return value, fmt.Sprintf("%s in %s", fileKey, c.file.path)
+		}
+	}
+
+	return "", ""
+}
+
+// getenv returns the value of the environment variable, falling back to the config file. See lookup.
+func (c *Config) getenv(key string) string {
+	value, _ := c.lookup(key)
+	return value
+}
+
+// appendFieldError appends a validation error of the field,
+// mentioning where the value was set when it comes from a flag or the config file.
+func (c *Config) appendFieldError(field, format string, v ...any) {
+	if _, source := c.lookup(field); source != "" {
+		format += " (set by %s)"
+		v = append(v, source)
+	}
+	c.errs.appendFieldError(field, format, v...)
+}
+
+// Source is where the value of a configuration environment variable is set.
+type Source struct {
+	// Env is the environment variable.
+	Env string
+	// Value is the value of the environment variable, masked for secrets such as the access token.
+	Value string
+	// From is the command-line flag, the environment variable of the CI provider or the key of the config file
+	// the value is set by, or "environment" when it's set by the environment variable itself.
+	From string
+}
+
+// Sources returns where the configuration environment variables that are set get their values from,
+// in the order of the command-line flags.
+func (c *Config) Sources() []Source {
+	var sources []Source
+	for _, d := range flagDefinitions {
+		value, from := c.lookup(d.env)
+		if value == "" {
+			continue
+		}
+		if from == "" {
+			from = "environment"
+		}
+		if d.secret {
+			value = maskSecret(value)
+		}
+		sources = append(sources, Source{Env: d.env, Value: value, From: from})
+	}
+	return sources
+}
+
+// maskSecret masks all but the last 4 characters of the secret.
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+	return strings.Repeat("*", len(secret)-4) + secret[len(secret)-4:]
+}
@@ -0,0 +1,316 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadConfigFile_YAML(t *testing.T) {
+	path := writeConfigFile(t, ".bktec.yml", `# bktec configuration
+test_runner: rspec
+test_cmd: "bundle exec rspec --format json --out {{resultPath}} {{testExamples}}"
+test_file_pattern: 'spec/**/*_spec.rb' # all specs
+retry_count: 1
+profiles:
+  unit:
+    test_file_exclude_pattern: spec/features/**
+    retry_count: 2
+  features:
+    test_file_pattern: "spec/features/**/*_spec.rb"
+    result_path: 'tmp/it''s.json'
+`)
+
+	got, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("readConfigFile(%q) error = %v", path, err)
+	}
+
+	want := &configFile{
+		path: path,
+		values: map[string]string{
+			"test_runner":       "rspec",
+			"test_cmd":          "bundle exec rspec --format json --out {{resultPath}} {{testExamples}}",
+			"test_file_pattern": "spec/**/*_spec.rb",
+			"retry_count":       "1",
+		},
+		profiles: map[string]map[string]string{
+			"unit": {
+				"test_file_exclude_pattern": "spec/features/**",
+				"retry_count":               "2",
+			},
+			"features": {
+				"test_file_pattern": "spec/features/**/*_spec.rb",
+				"result_path":       "tmp/it's.json",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(configFile{})); diff != "" {
+		t.Errorf("readConfigFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestReadConfigFile_TOML(t *testing.T) {
+	path := writeConfigFile(t, ".bktec.toml", `# bktec configuration
+test_runner = "rspec"
+test_cmd = "bundle exec rspec --format json --out {{resultPath}} {{testExamples}}"
+test_file_pattern = 'spec/**/*_spec.rb' # all specs
+retry_count = 1
+
+[profiles.unit]
+test_file_exclude_pattern = "spec/features/**"
+retry_count = 2
+
+[profiles."features"]
+test_file_pattern = "spec/features/**/*_spec.rb"
+`)
+
+	got, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("readConfigFile(%q) error = %v", path, err)
+	}
+
+	want := &configFile{
+		path: path,
+		values: map[string]string{
+			"test_runner":       "rspec",
+			"test_cmd":          "bundle exec rspec --format json --out {{resultPath}} {{testExamples}}",
+			"test_file_pattern": "spec/**/*_spec.rb",
+			"retry_count":       "1",
+		},
+		profiles: map[string]map[string]string{
+			"unit": {
+				"test_file_exclude_pattern": "spec/features/**",
+				"retry_count":               "2",
+			},
+			"features": {
+				"test_file_pattern": "spec/features/**/*_spec.rb",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(configFile{})); diff != "" {
+		t.Errorf("readConfigFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestReadConfigFile_Invalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    ".bktec.yml",
+			content: "test_runner: rspec\nsuite: my_suite\n",
+			want:    `line 2: unknown key "suite"`,
+		},
+		{
+			name:    ".bktec.yml",
+			content: "test_runner: rspec\n  retry_count: 1\n",
+			want:    "line 2: unexpected indentation",
+		},
+		{
+			name:    ".bktec.yml",
+			content: "test_file_pattern: [spec, test]\n",
+			want:    "line 1: only string, integer and boolean values are supported",
+		},
+		{
+			name:    ".bktec.yml",
+			content: "test_cmd: \"bin/rspec\n",
+			want:    "line 1: unterminated string",
+		},
+		{
+			name:    ".bktec.yml",
+			content: "profiles:\n  ci:\n    retry_count: 1\n  ci:\n",
+			want:    `line 4: duplicate profile "ci"`,
+		},
+		{
+			name:    ".bktec.toml",
+			content: "[runner]\ntest_runner = \"rspec\"\n",
+			want:    `line 1: unknown table "[runner]", only [profiles.<name>] tables are supported`,
+		},
+		{
+			name:    ".bktec.toml",
+			content: "retry_count = 1.5\n",
+			want:    `line 1: unsupported value "1.5", only string, integer and boolean values are supported`,
+		},
+		{
+			name:    ".bktec.toml",
+			content: "test_runner = \"rspec\"\ntest_runner = \"jest\"\n",
+			want:    `line 2: duplicate key "test_runner"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			path := writeConfigFile(t, tc.name, tc.content)
+
+			_, err := readConfigFile(path)
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("readConfigFile(%q) error = %v, want %q", path, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewConfig_ConfigFile(t *testing.T) {
+	setEnv(t)
+	os.Unsetenv("BUILDKITE_TEST_ENGINE_TEST_CMD")
+	os.Unsetenv("BUILDKITE_TEST_ENGINE_RESULT_PATH")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN", "spec/models/**/*_spec.rb")
+	os.Setenv("BUILDKITE_TEST_ENGINE_PROFILE", "ci")
+	os.Setenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE", writeConfigFile(t, ".bktec.yml", `
+test_runner: jest
+test_cmd: bin/rspec {{testExamples}}
+test_file_pattern: spec/**/*_spec.rb
+retry_cmd: bin/rspec --only-failures
+result_path: tmp/rspec.json
+retry_count: 1
+profiles:
+  ci:
+    retry_count: 3
+    result_path: tmp/ci.json
+`))
+	defer os.Clearenv()
+
+	c, err := New()
+	if err != nil {
+		t.Errorf("config.New() error = %v", err)
+	}
+
+	want := Config{
+		Parallelism:      60,
+		NodeIndex:        7,
+		ServerBaseUrl:    "https://build.kite",
+		Identifier:       "123/456",
+		AccessToken:      "my_token",
+		OrganizationSlug: "my_org",
+		SuiteSlug:        "my_suite",
+		Mode:             "static",
+		BatchSize:        5,
+		LocalConcurrency: 1,
+		ChangedBaseRef:   "origin/main",
+		AgentPath:        "buildkite-agent",
+		CIProvider:       "buildkite",
+		ConfigFile:       os.Getenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE"),
+		Profile:          "ci",
+		// the environment takes precedence over the config file
+		TestRunner:      "rspec",
+		TestFilePattern: "spec/models/**/*_spec.rb",
+		// the profile takes precedence over the top level values
+		MaxRetries: 3,
+		ResultPath: "tmp/ci.json",
+		// the top level values are used when neither the environment nor the profile set them
+		TestCommand:  "bin/rspec {{testExamples}}",
+		RetryCommand: "bin/rspec --only-failures",
+	}
+
+	if diff := cmp.Diff(c, want, cmpopts.IgnoreUnexported(Config{})); diff != "" {
+		t.Errorf("config.New() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewConfig_ConfigFileInWorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".bktec.toml"), []byte("retry_count = 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	setEnv(t)
+	defer os.Clearenv()
+
+	c, err := New()
+	if err != nil {
+		t.Errorf("config.New() error = %v", err)
+	}
+
+	if c.ConfigFile != ".bktec.toml" {
+		t.Errorf("ConfigFile = %q, want %q", c.ConfigFile, ".bktec.toml")
+	}
+
+	if c.MaxRetries != 2 {
+		t.Errorf("MaxRetries = %d, want %d", c.MaxRetries, 2)
+	}
+}
+
+func TestNewConfig_ConfigFileInvalidValue(t *testing.T) {
+	setEnv(t)
+	os.Setenv("BUILDKITE_TEST_ENGINE_PROFILE", "ci")
+	os.Setenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE", writeConfigFile(t, ".bktec.yml", "retry_count: 1\nprofiles:\n  ci:\n    retry_count: many\n"))
+	defer os.Clearenv()
+
+	_, err := New()
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	got := invConfigError["BUILDKITE_TEST_ENGINE_RETRY_COUNT"][0].Error()
+	want := `was "many", must be a number (set by retry_count in profile "ci" of ` + os.Getenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE") + ")"
+	if got != want {
+		t.Errorf("BUILDKITE_TEST_ENGINE_RETRY_COUNT error = %q, want %q", got, want)
+	}
+}
+
+func TestNewConfig_ConfigFileUnknownProfile(t *testing.T) {
+	setEnv(t)
+	os.Setenv("BUILDKITE_TEST_ENGINE_PROFILE", "nightly")
+	os.Setenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE", writeConfigFile(t, ".bktec.yml", "profiles:\n  ci:\n  unit:\n"))
+	defer os.Clearenv()
+
+	_, err := New()
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	got := invConfigError["BUILDKITE_TEST_ENGINE_PROFILE"][0].Error()
+	if !strings.HasSuffix(got, "must be one of the profiles in "+os.Getenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE")+": ci, unit") {
+		t.Errorf("BUILDKITE_TEST_ENGINE_PROFILE error = %q, want the list of profiles", got)
+	}
+}
+
+func TestNewConfig_ConfigFileParseError(t *testing.T) {
+	setEnv(t)
+	path := writeConfigFile(t, ".bktec.yml", "retry_count 1\n")
+	os.Setenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE", path)
+	defer os.Clearenv()
+
+	_, err := New()
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	want := path + ` is invalid: line 1: expected "key: value"`
+	if diff := cmp.Diff(err.Error(), want); diff != "" {
+		t.Errorf("config.New() error diff (-got +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// flagDefinition is a command-line flag that stands in for an environment variable.
+type flagDefinition struct {
+	name   string
+	env    string
+	usage  string
+	isBool bool
+	// secret flags have their value masked in Sources.
+	secret bool
+}
+
+// flagDefinitions are the command-line flags of all the configuration fields.
+var flagDefinitions = []flagDefinition{
+	{name: "access-token", env: "BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", usage: "access token of the Test Engine API", secret: true},
+	{name: "base-url", env: "BUILDKITE_TEST_ENGINE_BASE_URL", usage: "base URL of the Test Engine API"},
+	{name: "organization-slug", env: "BUILDKITE_ORGANIZATION_SLUG", usage: "slug of the Buildkite organization"},
+	{name: "suite-slug", env: "BUILDKITE_TEST_ENGINE_SUITE_SLUG", usage: "slug of the Test Engine suite"},
+	{name: "build-id", env: "BUILDKITE_BUILD_ID", usage: "ID of the build, identifying the test plan along with the step ID"},
+	{name: "step-id", env: "BUILDKITE_STEP_ID", usage: "ID of the step, identifying the test plan along with the build ID"},
+	{name: "branch", env: "BUILDKITE_BRANCH", usage: "git branch of the build"},
+	{name: "default-branch", env: "BUILDKITE_PIPELINE_DEFAULT_BRANCH", usage: "default branch of the pipeline, on which all the tests are run"},
+	{name: "parallelism", env: "BUILDKITE_PARALLEL_JOB_COUNT", usage: "number of parallel nodes"},
+	{name: "node-index", env: "BUILDKITE_PARALLEL_JOB", usage: "index of this node, from 0"},
+	{name: "runner", env: "BUILDKITE_TEST_ENGINE_TEST_RUNNER", usage: "test runner, e.g. rspec, jest or pytest"},
+	{name: "test-cmd", env: "BUILDKITE_TEST_ENGINE_TEST_CMD", usage: "command to run the tests"},
+	{name: "retry-cmd", env: "BUILDKITE_TEST_ENGINE_RETRY_CMD", usage: "command to retry the failed tests"},
+	{name: "retry-count", env: "BUILDKITE_TEST_ENGINE_RETRY_COUNT", usage: "number of times the failed tests are retried"},
+	{name: "test-file-pattern", env: "BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN", usage: "glob pattern of the test files"},
+	{name: "test-file-exclude-pattern", env: "BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN", usage: "glob pattern of the test files to exclude"},
+	{name: "result-path", env: "BUILDKITE_TEST_ENGINE_RESULT_PATH", usage: "path of the report written by the test runner"},
+	{name: "split-by-example", env: "BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE", usage: "split slow test files by example", isBool: true},
+	{name: "mode", env: "BUILDKITE_TEST_ENGINE_MODE", usage: "mode of running the test plan, either static or dynamic"},
+	{name: "batch-size", env: "BUILDKITE_TEST_ENGINE_BATCH_SIZE", usage: "number of tests claimed at a time in dynamic mode"},
+	{name: "local-concurrency", env: "BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY", usage: "number of test processes run concurrently on this node"},
+	{name: "plan-file", env: "BUILDKITE_TEST_ENGINE_PLAN_FILE", usage: "path of a test plan file to read the test plan from"},
+	{name: "plan-output-file", env: "BUILDKITE_TEST_ENGINE_PLAN_OUTPUT_FILE", usage: "path to write the test plan to"},
+	{name: "timing-cache-path", env: "BUILDKITE_TEST_ENGINE_TIMING_CACHE_PATH", usage: "path of the local timing cache file"},
+	{name: "junit-report-path", env: "BUILDKITE_TEST_ENGINE_JUNIT_REPORT_PATH", usage: "path to write the JUnit XML report of the run to"},
+	{name: "summary-path", env: "BUILDKITE_TEST_ENGINE_SUMMARY_PATH", usage: "path to write the JSON summary of the run to"},
+	{name: "annotation-path", env: "BUILDKITE_TEST_ENGINE_ANNOTATION_PATH", usage: "path to write the Markdown annotation of the run to"},
+	{name: "annotate", env: "BUILDKITE_TEST_ENGINE_ANNOTATE", usage: "annotate the build with buildkite-agent", isBool: true},
+	{name: "agent-path", env: "BUILDKITE_TEST_ENGINE_AGENT_PATH", usage: "path of the buildkite-agent binary"},
+	{name: "select-changed-tests", env: "BUILDKITE_TEST_ENGINE_SELECT_CHANGED_TESTS", usage: "run only the tests affected by the changes", isBool: true},
+	{name: "changed-base-ref", env: "BUILDKITE_TEST_ENGINE_CHANGED_BASE_REF", usage: "git ref to find the changed files against"},
+	{name: "changed-mapping-file", env: "BUILDKITE_TEST_ENGINE_CHANGED_MAPPING_FILE", usage: "path of the file mapping source files to test files"},
+	{name: "ci-provider", env: "BUILDKITE_TEST_ENGINE_CI_PROVIDER", usage: "CI provider, detected from the environment by default: buildkite, github_actions, gitlab, circleci or jenkins"},
+	{name: "config-file", env: "BUILDKITE_TEST_ENGINE_CONFIG_FILE", usage: "path of the config file"},
+	{name: "profile", env: "BUILDKITE_TEST_ENGINE_PROFILE", usage: "profile of the config file"},
+}
+
+// Flags is the values of the configuration flags set on the command line, keyed by the environment variables they stand in for.
+// A flag takes precedence over its environment variable.
+type Flags map[string]string
+
+// RegisterFlags defines the configuration flags on the flag set, and returns the values of the flags once parsed.
+func RegisterFlags(fs *flag.FlagSet) Flags {
+	flags := Flags{}
+	for _, d := range flagDefinitions {
+		fs.Var(flagValue{flags: flags, definition: d}, d.name, fmt.Sprintf("%s (%s)", d.usage, d.env))
+	}
+	return flags
+}
+
+// Getenv returns the value of the flag of the environment variable if it's set, otherwise the value of the environment variable.
+func (f Flags) Getenv(key string) string {
+	if value, ok := f[key]; ok {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+// flagName returns the name of the flag of the environment variable.
+func flagName(env string) string {
+	for _, d := range flagDefinitions {
+		if d.env == env {
+			return d.name
+		}
+	}
+	return ""
+}
+
+// flagValue is the flag.Value that records the value of a flag in Flags.
+type flagValue struct {
+	flags      Flags
+	definition flagDefinition
+}
+
+func (v flagValue) String() string {
+	return v.flags[v.definition.env]
+}
+
+func (v flagValue) Set(value string) error {
+	if v.definition.isBool {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		value = strconv.FormatBool(b)
+	}
+	v.flags[v.definition.env] = value
+	return nil
+}
+
+// IsBoolFlag allows the boolean flags to be set without a value, e.g. --annotate.
+func (v flagValue) IsBoolFlag() bool {
+	return v.definition.isBool
+}
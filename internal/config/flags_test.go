@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func parseFlags(t *testing.T, args ...string) Flags {
+	t.Helper()
+	fs := flag.NewFlagSet("bktec", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("fs.Parse(%q) error = %v", args, err)
+	}
+	return flags
+}
+
+func TestRegisterFlags(t *testing.T) {
+	flags := parseFlags(t, "--runner", "jest", "--retry-count=2", "--annotate", "--split-by-example=false")
+
+	want := Flags{
+		"BUILDKITE_TEST_ENGINE_TEST_RUNNER":      "jest",
+		"BUILDKITE_TEST_ENGINE_RETRY_COUNT":      "2",
+		"BUILDKITE_TEST_ENGINE_ANNOTATE":         "true",
+		"BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE": "false",
+	}
+
+	if diff := cmp.Diff(flags, want); diff != "" {
+		t.Errorf("RegisterFlags() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRegisterFlags_InvalidBool(t *testing.T) {
+	fs := flag.NewFlagSet("bktec", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	RegisterFlags(fs)
+
+	if err := fs.Parse([]string{"--annotate=maybe"}); err == nil {
+		t.Errorf("fs.Parse() error = nil, want error")
+	}
+}
+
+func TestRegisterFlags_Usage(t *testing.T) {
+	fs := flag.NewFlagSet("bktec", flag.ContinueOnError)
+	RegisterFlags(fs)
+
+	// Every flag documents the environment variable it stands in for.
+	fs.VisitAll(func(f *flag.Flag) {
+		if !strings.Contains(f.Usage, "(BUILDKITE_") {
+			t.Errorf("usage of --%s = %q, want the environment variable", f.Name, f.Usage)
+		}
+	})
+}
+
+func TestNewWithFlags(t *testing.T) {
+	setEnv(t)
+	os.Setenv("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "1")
+	defer os.Clearenv()
+
+	flags := parseFlags(t,
+		"--runner", "jest",
+		"--test-cmd", "yarn test {{testExamples}}",
+		"--retry-count", "3",
+		"--result-path", "tmp/jest.json",
+		"--parallelism", "4",
+		"--node-index", "2",
+		"--annotate",
+	)
+
+	c, err := NewWithFlags(flags)
+	if err != nil {
+		t.Errorf("config.NewWithFlags() error = %v", err)
+	}
+
+	want := Config{
+		Parallelism:      4,
+		NodeIndex:        2,
+		ServerBaseUrl:    "https://build.kite",
+		Identifier:       "123/456",
+		TestCommand:      "yarn test {{testExamples}}",
+		AccessToken:      "my_token",
+		OrganizationSlug: "my_org",
+		ResultPath:       "tmp/jest.json",
+		SuiteSlug:        "my_suite",
+		TestRunner:       "jest",
+		MaxRetries:       3,
+		Annotate:         true,
+		Mode:             "static",
+		BatchSize:        5,
+		LocalConcurrency: 1,
+		ChangedBaseRef:   "origin/main",
+		AgentPath:        "buildkite-agent",
+		CIProvider:       "buildkite",
+	}
+
+	if diff := cmp.Diff(c, want, cmpopts.IgnoreUnexported(Config{})); diff != "" {
+		t.Errorf("config.NewWithFlags() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewWithFlags_InvalidValue(t *testing.T) {
+	setEnv(t)
+	defer os.Clearenv()
+
+	_, err := NewWithFlags(parseFlags(t, "--node-index", "60"))
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.NewWithFlags() error = %v, want InvalidConfigError", err)
+	}
+
+	want := "BUILDKITE_PARALLEL_JOB was 60, must not be greater than 59 (set by --node-index)"
+	if diff := cmp.Diff(err.Error(), want); diff != "" {
+		t.Errorf("config.NewWithFlags() error diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestFlagsGetenv(t *testing.T) {
+	os.Setenv("BUILDKITE_TEST_ENGINE_PLAN_FILE", "tmp/env.json")
+	defer os.Clearenv()
+
+	if got := (Flags{}).Getenv("BUILDKITE_TEST_ENGINE_PLAN_FILE"); got != "tmp/env.json" {
+		t.Errorf("Flags.Getenv() = %q, want %q", got, "tmp/env.json")
+	}
+
+	flags := parseFlags(t, "--plan-file", "tmp/flag.json")
+	if got := flags.Getenv("BUILDKITE_TEST_ENGINE_PLAN_FILE"); got != "tmp/flag.json" {
+		t.Errorf("Flags.Getenv() = %q, want %q", got, "tmp/flag.json")
+	}
+}
+
+func TestConfigSources(t *testing.T) {
+	setEnv(t)
+	os.Setenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE", writeConfigFile(t, ".bktec.yml", "retry_count: 2\n"))
+	os.Unsetenv("BUILDKITE_TEST_ENGINE_RESULT_PATH")
+	defer os.Clearenv()
+
+	c, err := NewWithFlags(parseFlags(t, "--result-path", "tmp/jest.json"))
+	if err != nil {
+		t.Fatalf("config.NewWithFlags() error = %v", err)
+	}
+
+	got := c.Sources()
+
+	want := []Source{
+		{Env: "BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", Value: "****oken", From: "environment"},
+		{Env: "BUILDKITE_TEST_ENGINE_BASE_URL", Value: "https://build.kite", From: "environment"},
+		{Env: "BUILDKITE_ORGANIZATION_SLUG", Value: "my_org", From: "environment"},
+		{Env: "BUILDKITE_TEST_ENGINE_SUITE_SLUG", Value: "my_suite", From: "environment"},
+		{Env: "BUILDKITE_BUILD_ID", Value: "123", From: "environment"},
+		{Env: "BUILDKITE_STEP_ID", Value: "456", From: "environment"},
+		{Env: "BUILDKITE_PARALLEL_JOB_COUNT", Value: "60", From: "environment"},
+		{Env: "BUILDKITE_PARALLEL_JOB", Value: "7", From: "environment"},
+		{Env: "BUILDKITE_TEST_ENGINE_TEST_RUNNER", Value: "rspec", From: "environment"},
+		{Env: "BUILDKITE_TEST_ENGINE_TEST_CMD", Value: "bin/rspec {{testExamples}}", From: "environment"},
+		{Env: "BUILDKITE_TEST_ENGINE_RETRY_COUNT", Value: "2", From: "retry_count in " + c.ConfigFile},
+		{Env: "BUILDKITE_TEST_ENGINE_RESULT_PATH", Value: "tmp/jest.json", From: "--result-path"},
+		{Env: "BUILDKITE_TEST_ENGINE_CONFIG_FILE", Value: c.ConfigFile, From: "environment"},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Sources() diff (-got +want):\n%s", diff)
+	}
+}
@@ -2,7 +2,6 @@ package config
 
 import (
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 )
@@ -25,58 +24,119 @@ import (
 // - BUILDKITE_TEST_ENGINE_TEST_CMD (TestCommand)
 // - BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN (TestFilePattern)
 // - BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN (TestFileExcludePattern)
+// - BUILDKITE_TEST_ENGINE_PLAN_FILE (PlanFile)
+// - BUILDKITE_TEST_ENGINE_PLAN_OUTPUT_FILE (PlanOutputFile)
+// - BUILDKITE_TEST_ENGINE_TIMING_CACHE_PATH (TimingCachePath)
+// - BUILDKITE_TEST_ENGINE_JUNIT_REPORT_PATH (JUnitReportPath)
+// - BUILDKITE_TEST_ENGINE_SUMMARY_PATH (SummaryPath)
+// - BUILDKITE_TEST_ENGINE_ANNOTATION_PATH (AnnotationPath)
+// - BUILDKITE_TEST_ENGINE_ANNOTATE (Annotate)
+// - BUILDKITE_TEST_ENGINE_AGENT_PATH (AgentPath)
+// - BUILDKITE_TEST_ENGINE_MODE (Mode)
+// - BUILDKITE_TEST_ENGINE_BATCH_SIZE (BatchSize)
+// - BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY (LocalConcurrency)
+// - BUILDKITE_TEST_ENGINE_SELECT_CHANGED_TESTS (SelectChangedTests)
+// - BUILDKITE_TEST_ENGINE_CHANGED_BASE_REF (ChangedBaseRef)
+// - BUILDKITE_TEST_ENGINE_CHANGED_MAPPING_FILE (ChangedMappingFile)
+// - BUILDKITE_TEST_ENGINE_CI_PROVIDER (CIProvider)
+// - BUILDKITE_PIPELINE_DEFAULT_BRANCH (DefaultBranch)
 // - BUILDKITE_BRANCH (Branch)
 //
-// If we are going to support other CI environment in the future,
-// we will need to change where we read the configuration from.
+// Each environment variable is overridden by its command-line flag, see Flags.
+// The test runner, commands, file patterns, retry count and result path
+// fall back to the config file when neither is set, see readFromFile.
+//
+// On other CI providers than Buildkite, the build and step IDs, node index, parallelism and branch
+// fall back to the environment variables of the provider, see ciProviders.
 func (c *Config) readFromEnv() error {
+	c.readCIProvider()
+
+	c.AccessToken = c.getenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN")
+	c.OrganizationSlug = c.getenv("BUILDKITE_ORGANIZATION_SLUG")
+	c.SuiteSlug = c.getenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG")
 
-	c.AccessToken = os.Getenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN")
-	c.OrganizationSlug = os.Getenv("BUILDKITE_ORGANIZATION_SLUG")
-	c.SuiteSlug = os.Getenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG")
+	c.PlanFile = c.getenv("BUILDKITE_TEST_ENGINE_PLAN_FILE")
+	c.PlanOutputFile = c.getenv("BUILDKITE_TEST_ENGINE_PLAN_OUTPUT_FILE")
+	c.TimingCachePath = c.getenv("BUILDKITE_TEST_ENGINE_TIMING_CACHE_PATH")
+	c.JUnitReportPath = c.getenv("BUILDKITE_TEST_ENGINE_JUNIT_REPORT_PATH")
+	c.SummaryPath = c.getenv("BUILDKITE_TEST_ENGINE_SUMMARY_PATH")
+	c.AnnotationPath = c.getenv("BUILDKITE_TEST_ENGINE_ANNOTATION_PATH")
+	c.Annotate = strings.ToLower(c.getenv("BUILDKITE_TEST_ENGINE_ANNOTATE")) == "true"
+	c.AgentPath = c.getEnvWithDefault("BUILDKITE_TEST_ENGINE_AGENT_PATH", "buildkite-agent")
 
-	buildId := os.Getenv("BUILDKITE_BUILD_ID")
-	if buildId == "" {
-		c.errs.appendFieldError("BUILDKITE_BUILD_ID", "must not be blank")
+	// The build and step IDs identify the test plan in the API,
+	// therefore they are not required when the test plan is read from a file.
+	buildId := c.getenv("BUILDKITE_BUILD_ID")
+	if buildId == "" && !c.HasPlanFile() {
+		c.appendFieldError("BUILDKITE_BUILD_ID", "must not be blank")
 	}
 
-	stepId := os.Getenv("BUILDKITE_STEP_ID")
-	if stepId == "" {
-		c.errs.appendFieldError("BUILDKITE_STEP_ID", "must not be blank")
+	stepId := c.getenv("BUILDKITE_STEP_ID")
+	if stepId == "" && !c.HasPlanFile() {
+		c.appendFieldError("BUILDKITE_STEP_ID", "must not be blank")
 	}
 
 	c.Identifier = fmt.Sprintf("%s/%s", buildId, stepId)
 
-	c.ServerBaseUrl = getEnvWithDefault("BUILDKITE_TEST_ENGINE_BASE_URL", "https://api.buildkite.com")
-	c.TestCommand = os.Getenv("BUILDKITE_TEST_ENGINE_TEST_CMD")
-	c.TestFilePattern = os.Getenv("BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN")
-	c.TestFileExcludePattern = os.Getenv("BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN")
-	c.TestRunner = os.Getenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER")
-	c.ResultPath = os.Getenv("BUILDKITE_TEST_ENGINE_RESULT_PATH")
+	c.ServerBaseUrl = c.getEnvWithDefault("BUILDKITE_TEST_ENGINE_BASE_URL", "https://api.buildkite.com")
+	c.TestCommand = c.getenv("BUILDKITE_TEST_ENGINE_TEST_CMD")
+	c.TestFilePattern = c.getenv("BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN")
+	c.TestFileExcludePattern = c.getenv("BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN")
+	c.TestRunner = c.getenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER")
+	c.ResultPath = c.getenv("BUILDKITE_TEST_ENGINE_RESULT_PATH")
 
-	c.SplitByExample = strings.ToLower(os.Getenv("BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE")) == "true"
+	c.SplitByExample = strings.ToLower(c.getenv("BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE")) == "true"
 
-	// used by Buildkite only, for experimental plans
-	c.Branch = os.Getenv("BUILDKITE_BRANCH")
+	c.Mode = c.getEnvWithDefault("BUILDKITE_TEST_ENGINE_MODE", ModeStatic)
+	batchSize, err := c.getIntEnvWithDefault("BUILDKITE_TEST_ENGINE_BATCH_SIZE", 5)
+	c.BatchSize = batchSize
+	if err != nil {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_BATCH_SIZE", "was %q, must be a number", c.getenv("BUILDKITE_TEST_ENGINE_BATCH_SIZE"))
+	}
 
-	MaxRetries, err := getIntEnvWithDefault("BUILDKITE_TEST_ENGINE_RETRY_COUNT", 0)
-	c.MaxRetries = MaxRetries
+	localConcurrency, err := c.getIntEnvWithDefault("BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY", 1)
+	c.LocalConcurrency = localConcurrency
 	if err != nil {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "was %q, must be a number", os.Getenv("BUILDKITE_TEST_ENGINE_RETRY_COUNT"))
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY", "was %q, must be a number", c.getenv("BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY"))
+	}
+
+	// used for experimental plans
+	c.Branch = c.getenv("BUILDKITE_BRANCH")
+
+	// The changes are compared against the base branch of the pull request,
+	// or the default branch of the pipeline when the build is not for a pull request.
+	c.SelectChangedTests = strings.ToLower(c.getenv("BUILDKITE_TEST_ENGINE_SELECT_CHANGED_TESTS")) == "true"
+	c.DefaultBranch = c.getenv("BUILDKITE_PIPELINE_DEFAULT_BRANCH")
+	baseBranch := c.getEnvWithDefault("BUILDKITE_PULL_REQUEST_BASE_BRANCH", c.getEnvWithDefault("BUILDKITE_PIPELINE_DEFAULT_BRANCH", "main"))
+	c.ChangedBaseRef = c.getEnvWithDefault("BUILDKITE_TEST_ENGINE_CHANGED_BASE_REF", "origin/"+baseBranch)
+	c.ChangedMappingFile = c.getenv("BUILDKITE_TEST_ENGINE_CHANGED_MAPPING_FILE")
+
+	if retryCount := c.getenv("BUILDKITE_TEST_ENGINE_RETRY_COUNT"); retryCount != "" {
+		c.MaxRetries, err = strconv.Atoi(retryCount)
+		if err != nil {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "was %q, must be a number", retryCount)
+		}
+	}
+	c.RetryCommand = c.getenv("BUILDKITE_TEST_ENGINE_RETRY_CMD")
+
+	// Buildkite sets the parallelism of every parallel step,
+	// whereas the other CI providers run a single node unless told otherwise.
+	defaultParallelism, defaultNodeIndex := "", ""
+	if c.CIProvider != CIProviderBuildkite {
+		defaultParallelism, defaultNodeIndex = "1", "0"
 	}
-	c.RetryCommand = os.Getenv("BUILDKITE_TEST_ENGINE_RETRY_CMD")
 
-	parallelism := os.Getenv("BUILDKITE_PARALLEL_JOB_COUNT")
+	parallelism := c.getEnvWithDefault("BUILDKITE_PARALLEL_JOB_COUNT", defaultParallelism)
 	parallelismInt, err := strconv.Atoi(parallelism)
 	if err != nil {
-		c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB_COUNT", "was %q, must be a number", parallelism)
+		c.appendFieldError("BUILDKITE_PARALLEL_JOB_COUNT", "was %q, must be a number", parallelism)
 	}
 	c.Parallelism = parallelismInt
 
-	nodeIndex := os.Getenv("BUILDKITE_PARALLEL_JOB")
+	nodeIndex := c.getEnvWithDefault("BUILDKITE_PARALLEL_JOB", defaultNodeIndex)
 	nodeIndexInt, err := strconv.Atoi(nodeIndex)
 	if err != nil {
-		c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB", "was %q, must be a number", nodeIndex)
+		c.appendFieldError("BUILDKITE_PARALLEL_JOB", "was %q, must be a number", nodeIndex)
 	}
 	c.NodeIndex = nodeIndexInt
 
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -25,6 +26,12 @@ func TestConfigReadFromEnv(t *testing.T) {
 	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN", "spec/feature/**/*_spec.rb")
 	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "result.json")
 	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TIMING_CACHE_PATH", "tmp/timing.json")
+	os.Setenv("BUILDKITE_TEST_ENGINE_JUNIT_REPORT_PATH", "tmp/junit.xml")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUMMARY_PATH", "tmp/summary.json")
+	os.Setenv("BUILDKITE_TEST_ENGINE_ANNOTATION_PATH", "tmp/annotation.md")
+	os.Setenv("BUILDKITE_TEST_ENGINE_ANNOTATE", "true")
+	os.Setenv("BUILDKITE_TEST_ENGINE_AGENT_PATH", "bin/buildkite-agent")
 	defer os.Clearenv()
 
 	c := Config{}
@@ -44,7 +51,18 @@ func TestConfigReadFromEnv(t *testing.T) {
 		TestFilePattern:        "spec/unit/**/*_spec.rb",
 		TestFileExcludePattern: "spec/feature/**/*_spec.rb",
 		TestRunner:             "rspec",
+		Mode:                   "static",
+		BatchSize:              5,
+		LocalConcurrency:       1,
+		ChangedBaseRef:         "origin/main",
 		ResultPath:             "result.json",
+		TimingCachePath:        "tmp/timing.json",
+		JUnitReportPath:        "tmp/junit.xml",
+		SummaryPath:            "tmp/summary.json",
+		AnnotationPath:         "tmp/annotation.md",
+		Annotate:               true,
+		AgentPath:              "bin/buildkite-agent",
+		CIProvider:             "buildkite",
 	}
 
 	if err != nil {
@@ -199,3 +217,106 @@ func TestConfigReadFromEnv_InvalidParallelJobCount(t *testing.T) {
 		t.Errorf("config.readFromEnv() got = %v, want = %v", got, want)
 	}
 }
+
+func TestConfigReadFromEnv_PlanFile(t *testing.T) {
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(planFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("BUILDKITE_TEST_ENGINE_PLAN_FILE", planFile)
+	os.Setenv("BUILDKITE_TEST_ENGINE_PLAN_OUTPUT_FILE", "tmp/plan.json")
+	os.Setenv("BUILDKITE_PARALLEL_JOB", "1")
+	os.Setenv("BUILDKITE_PARALLEL_JOB_COUNT", "10")
+	defer os.Clearenv()
+
+	c := Config{errs: InvalidConfigError{}}
+	err := c.readFromEnv()
+
+	// The build and step IDs are not required when the test plan file exists.
+	if err != nil {
+		t.Errorf("config.readFromEnv() error = %v", err)
+	}
+
+	if c.PlanFile != planFile {
+		t.Errorf("PlanFile = %v, want %v", c.PlanFile, planFile)
+	}
+
+	if c.PlanOutputFile != "tmp/plan.json" {
+		t.Errorf("PlanOutputFile = %v, want %v", c.PlanOutputFile, "tmp/plan.json")
+	}
+}
+
+func TestConfigReadFromEnv_PlanFileNotExist(t *testing.T) {
+	os.Setenv("BUILDKITE_TEST_ENGINE_PLAN_FILE", filepath.Join(t.TempDir(), "plan.json"))
+	os.Setenv("BUILDKITE_PARALLEL_JOB", "1")
+	os.Setenv("BUILDKITE_PARALLEL_JOB_COUNT", "10")
+	defer os.Clearenv()
+
+	c := Config{errs: InvalidConfigError{}}
+	err := c.readFromEnv()
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Errorf("config.readFromEnv() error = %v, want InvalidConfigError", err)
+	}
+
+	if len(invConfigError) != 2 {
+		t.Errorf("config.readFromEnv() error length = %d, want 2", len(invConfigError))
+	}
+}
+
+func TestConfigReadFromEnv_SelectChangedTests(t *testing.T) {
+	cases := []struct {
+		env  map[string]string
+		want string
+	}{
+		{
+			env:  map[string]string{},
+			want: "origin/main",
+		},
+		{
+			env:  map[string]string{"BUILDKITE_PIPELINE_DEFAULT_BRANCH": "trunk"},
+			want: "origin/trunk",
+		},
+		{
+			env: map[string]string{
+				"BUILDKITE_PIPELINE_DEFAULT_BRANCH":  "trunk",
+				"BUILDKITE_PULL_REQUEST_BASE_BRANCH": "release",
+			},
+			want: "origin/release",
+		},
+		{
+			env: map[string]string{
+				"BUILDKITE_PULL_REQUEST_BASE_BRANCH":     "release",
+				"BUILDKITE_TEST_ENGINE_CHANGED_BASE_REF": "upstream/main",
+			},
+			want: "upstream/main",
+		},
+	}
+
+	for _, tc := range cases {
+		os.Clearenv()
+		os.Setenv("BUILDKITE_TEST_ENGINE_SELECT_CHANGED_TESTS", "true")
+		os.Setenv("BUILDKITE_TEST_ENGINE_CHANGED_MAPPING_FILE", "tmp/mapping.json")
+		for key, value := range tc.env {
+			os.Setenv(key, value)
+		}
+
+		c := Config{errs: InvalidConfigError{}}
+		c.readFromEnv()
+
+		if !c.SelectChangedTests {
+			t.Errorf("SelectChangedTests = %v, want %v", c.SelectChangedTests, true)
+		}
+
+		if c.ChangedMappingFile != "tmp/mapping.json" {
+			t.Errorf("ChangedMappingFile = %v, want %v", c.ChangedMappingFile, "tmp/mapping.json")
+		}
+
+		if c.ChangedBaseRef != tc.want {
+			t.Errorf("ChangedBaseRef with %v = %v, want %v", tc.env, c.ChangedBaseRef, tc.want)
+		}
+	}
+	os.Clearenv()
+}
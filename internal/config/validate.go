@@ -2,13 +2,14 @@ package config
 
 import (
 	"net/url"
+	"strings"
 )
 
 // validate checks if the Config struct is valid and returns InvalidConfigError if it's invalid.
 func (c *Config) validate() error {
 
 	if c.MaxRetries < 0 {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "was %d, must be greater than or equal to 0", c.MaxRetries)
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "was %d, must be greater than or equal to 0", c.MaxRetries)
 	}
 
 	// We validate BUILDKITE_PARALLEL_JOB and BUILDKITE_PARALLEL_JOB_COUNT in two steps.
@@ -22,50 +23,96 @@ func (c *Config) validate() error {
 	// We need to validate the range of BUILDKITE_PARALLEL_JOB first before we add the range validation error to BUILDKITE_PARALLEL_JOB_COUNT.
 	if c.errs["BUILDKITE_PARALLEL_JOB"] == nil {
 		if got, min := c.NodeIndex, 0; got < 0 {
-			c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB", "was %d, must be greater than or equal to %d", got, min)
+			c.appendFieldError("BUILDKITE_PARALLEL_JOB", "was %d, must be greater than or equal to %d", got, min)
 		}
 
 		if c.errs["BUILDKITE_PARALLEL_JOB_COUNT"] == nil {
 			if got, max := c.NodeIndex, c.Parallelism-1; got > max {
-				c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB", "was %d, must not be greater than %d", got, max)
+				c.appendFieldError("BUILDKITE_PARALLEL_JOB", "was %d, must not be greater than %d", got, max)
 			}
 		}
 	}
 
 	if c.errs["BUILDKITE_PARALLEL_JOB_COUNT"] == nil {
 		if got, min := c.Parallelism, 1; got < min {
-			c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB_COUNT", "was %d, must be greater than or equal to %d", got, min)
+			c.appendFieldError("BUILDKITE_PARALLEL_JOB_COUNT", "was %d, must be greater than or equal to %d", got, min)
 		}
 
 		if got, max := c.Parallelism, 1000; got > max {
-			c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB_COUNT", "was %d, must not be greater than %d", got, max)
+			c.appendFieldError("BUILDKITE_PARALLEL_JOB_COUNT", "was %d, must not be greater than %d", got, max)
 		}
 	}
 
+	if c.Mode != ModeStatic && c.Mode != ModeDynamic {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_MODE", "was %q, must be either %q or %q", c.Mode, ModeStatic, ModeDynamic)
+	}
+
+	if c.Mode == ModeDynamic && c.BatchSize < 1 {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_BATCH_SIZE", "was %d, must be greater than or equal to 1", c.BatchSize)
+	}
+
+	if c.errs["BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY"] == nil {
+		if got, min := c.LocalConcurrency, 1; got < min {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY", "was %d, must be greater than or equal to %d", got, min)
+		}
+	}
+
+	// Each of the concurrent processes writes its results to its own result path, derived from the result path.
+	// A glob pattern would match the results of the other processes.
+	if c.LocalConcurrency > 1 && strings.ContainsAny(c.ResultPath, "*?[{") {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_RESULT_PATH", "was %q, must not be a glob pattern when local concurrency is greater than 1", c.ResultPath)
+	}
+
+	// Playwright and Cypress write their results to the path set in their own configuration,
+	// rather than to the result path of each process, therefore they can't run concurrently.
+	if c.LocalConcurrency > 1 && (c.TestRunner == "playwright" || c.TestRunner == "cypress") {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY", "was %d, must be 1 when test runner is %s", c.LocalConcurrency, c.TestRunner)
+	}
+
 	if c.ServerBaseUrl != "" {
 		if _, err := url.ParseRequestURI(c.ServerBaseUrl); err != nil {
-			c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_BASE_URL", "must be a valid URL")
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_BASE_URL", "must be a valid URL")
 		}
 	}
 
-	if c.AccessToken == "" {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "must not be blank")
+	// The API is not used when the test plan is read from a file.
+	if !c.HasPlanFile() {
+		if c.AccessToken == "" {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "must not be blank")
+		}
+
+		if c.OrganizationSlug == "" {
+			c.appendFieldError("BUILDKITE_ORGANIZATION_SLUG", "must not be blank")
+		}
+
+		if c.SuiteSlug == "" {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "must not be blank")
+		}
 	}
 
-	if c.OrganizationSlug == "" {
-		c.errs.appendFieldError("BUILDKITE_ORGANIZATION_SLUG", "must not be blank")
+	// The result path is optional for the custom runner, because the adapter returns the results directly.
+	if c.ResultPath == "" && c.TestRunner != "cypress" && c.TestRunner != "custom" {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_RESULT_PATH", "must not be blank")
 	}
 
-	if c.SuiteSlug == "" {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "must not be blank")
+	if c.TestRunner == "" {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "must not be blank")
 	}
 
-	if c.ResultPath == "" && c.TestRunner != "cypress" {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_RESULT_PATH", "must not be blank")
+	// The custom runner delegates to the adapter set as the test command.
+	if c.TestRunner == "custom" && c.TestCommand == "" {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_TEST_CMD", "must not be blank when test runner is custom")
 	}
 
-	if c.TestRunner == "" {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "must not be blank")
+	// The junit runner has no default test command and test file pattern, because it can run any test framework.
+	if c.TestRunner == "junit" {
+		if c.TestCommand == "" {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_TEST_CMD", "must not be blank when test runner is junit")
+		}
+
+		if c.TestFilePattern == "" {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN", "must not be blank when test runner is junit")
+		}
 	}
 
 	if len(c.errs) > 0 {
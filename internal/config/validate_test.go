@@ -2,6 +2,8 @@ package config
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -18,6 +20,11 @@ func createConfig() Config {
 		ResultPath:       "tmp/result-*.json",
 		errs:             InvalidConfigError{},
 		TestRunner:       "rspec",
+		Mode:             "static",
+		BatchSize:        5,
+		LocalConcurrency: 1,
+		ChangedBaseRef:   "origin/main",
+		AgentPath:        "buildkite-agent",
 	}
 }
 
@@ -167,3 +174,162 @@ func TestConfigValidate_ResultPathOptionalWithCypress(t *testing.T) {
 		t.Errorf("config.validate() error = %v", err)
 	}
 }
+
+func TestConfigValidate_JUnit(t *testing.T) {
+	c := createConfig()
+	c.TestRunner = "junit"
+	c.TestCommand = "vendor/bin/phpunit --log-junit {{resultPath}} {{testExamples}}"
+	c.TestFilePattern = "tests/**/*Test.php"
+	err := c.validate()
+
+	if err != nil {
+		t.Errorf("config.validate() error = %v", err)
+	}
+}
+
+func TestConfigValidate_JUnitWithoutTestCommandAndFilePattern(t *testing.T) {
+	c := createConfig()
+	c.TestRunner = "junit"
+	err := c.validate()
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Errorf("config.validate() error = %v, want InvalidConfigError", err)
+		return
+	}
+
+	if len(invConfigError) != 2 {
+		t.Errorf("config.validate() error length = %d, want 2", len(invConfigError))
+	}
+
+	for _, name := range []string{"BUILDKITE_TEST_ENGINE_TEST_CMD", "BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN"} {
+		if len(invConfigError[name]) != 1 {
+			t.Errorf("config.validate() error for %s length = %d, want 1", name, len(invConfigError[name]))
+		}
+	}
+}
+
+func TestConfigValidate_Custom(t *testing.T) {
+	c := createConfig()
+	c.TestRunner = "custom"
+	c.TestCommand = "bin/bktec-adapter"
+	c.ResultPath = ""
+	err := c.validate()
+
+	if err != nil {
+		t.Errorf("config.validate() error = %v", err)
+	}
+}
+
+func TestConfigValidate_CustomWithoutTestCommand(t *testing.T) {
+	c := createConfig()
+	c.TestRunner = "custom"
+	err := c.validate()
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Errorf("config.validate() error = %v, want InvalidConfigError", err)
+		return
+	}
+
+	if len(invConfigError["BUILDKITE_TEST_ENGINE_TEST_CMD"]) != 1 {
+		t.Errorf("config.validate() error for BUILDKITE_TEST_ENGINE_TEST_CMD length = %d, want 1", len(invConfigError["BUILDKITE_TEST_ENGINE_TEST_CMD"]))
+	}
+}
+
+func TestConfigValidate_PlanFile(t *testing.T) {
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(planFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := createConfig()
+	c.PlanFile = planFile
+	c.AccessToken = ""
+	c.OrganizationSlug = ""
+	c.SuiteSlug = ""
+	err := c.validate()
+
+	if err != nil {
+		t.Errorf("config.validate() error = %v", err)
+	}
+}
+
+func TestConfigValidate_Mode(t *testing.T) {
+	cases := []struct {
+		mode      string
+		batchSize int
+		wantField string
+	}{
+		{mode: "static", batchSize: 0},
+		{mode: "dynamic", batchSize: 5},
+		{mode: "banana", batchSize: 5, wantField: "BUILDKITE_TEST_ENGINE_MODE"},
+		{mode: "dynamic", batchSize: 0, wantField: "BUILDKITE_TEST_ENGINE_BATCH_SIZE"},
+	}
+
+	for _, tc := range cases {
+		c := createConfig()
+		c.Mode = tc.mode
+		c.BatchSize = tc.batchSize
+		err := c.validate()
+
+		if tc.wantField == "" {
+			if err != nil {
+				t.Errorf("config.validate() with mode %q and batch size %d error = %v", tc.mode, tc.batchSize, err)
+			}
+			continue
+		}
+
+		var invConfigError InvalidConfigError
+		if !errors.As(err, &invConfigError) {
+			t.Errorf("config.validate() with mode %q and batch size %d error = %v, want InvalidConfigError", tc.mode, tc.batchSize, err)
+			continue
+		}
+
+		if len(invConfigError[tc.wantField]) != 1 {
+			t.Errorf("config.validate() with mode %q and batch size %d error for %s length = %d, want 1", tc.mode, tc.batchSize, tc.wantField, len(invConfigError[tc.wantField]))
+		}
+	}
+}
+
+func TestConfigValidate_LocalConcurrency(t *testing.T) {
+	cases := []struct {
+		localConcurrency int
+		testRunner       string
+		resultPath       string
+		wantField        string
+	}{
+		{localConcurrency: 1, testRunner: "rspec", resultPath: "tmp/*.xml"},
+		{localConcurrency: 4, testRunner: "rspec", resultPath: "tmp/rspec.json"},
+		{localConcurrency: 1, testRunner: "playwright", resultPath: "tmp/playwright.json"},
+		{localConcurrency: 0, testRunner: "rspec", resultPath: "tmp/rspec.json", wantField: "BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY"},
+		{localConcurrency: 4, testRunner: "rspec", resultPath: "tmp/*.xml", wantField: "BUILDKITE_TEST_ENGINE_RESULT_PATH"},
+		{localConcurrency: 4, testRunner: "playwright", resultPath: "tmp/playwright.json", wantField: "BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY"},
+		{localConcurrency: 4, testRunner: "cypress", resultPath: "tmp/cypress.json", wantField: "BUILDKITE_TEST_ENGINE_LOCAL_CONCURRENCY"},
+	}
+
+	for _, tc := range cases {
+		c := createConfig()
+		c.LocalConcurrency = tc.localConcurrency
+		c.TestRunner = tc.testRunner
+		c.ResultPath = tc.resultPath
+		err := c.validate()
+
+		if tc.wantField == "" {
+			if err != nil {
+				t.Errorf("config.validate() with local concurrency %d, test runner %q and result path %q error = %v", tc.localConcurrency, tc.testRunner, tc.resultPath, err)
+			}
+			continue
+		}
+
+		var invConfigError InvalidConfigError
+		if !errors.As(err, &invConfigError) {
+			t.Errorf("config.validate() with local concurrency %d, test runner %q and result path %q error = %v, want InvalidConfigError", tc.localConcurrency, tc.testRunner, tc.resultPath, err)
+			continue
+		}
+
+		if len(invConfigError[tc.wantField]) != 1 {
+			t.Errorf("config.validate() with local concurrency %d, test runner %q and result path %q error for %s length = %d, want 1", tc.localConcurrency, tc.testRunner, tc.resultPath, tc.wantField, len(invConfigError[tc.wantField]))
+		}
+	}
+}
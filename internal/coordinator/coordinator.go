@@ -0,0 +1,119 @@
+package coordinator
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/plan"
+)
+
+// Coordinator serves the queue of a dynamic test plan over HTTP, in the same way as the Test Engine API.
+// It allows running bktec in dynamic mode without Test Engine, e.g. for local testing,
+// by pointing BUILDKITE_TEST_ENGINE_BASE_URL of the nodes to the coordinator.
+type Coordinator struct {
+	mu    sync.Mutex
+	queue []plan.TestCase
+	// claims are the batches claimed by each node, keyed by the node index and the sequence of the claim,
+	// so that a retried claim returns the same batch.
+	claims map[claimKey][]plan.TestCase
+}
+
+type claimKey struct {
+	nodeIndex int
+	sequence  int
+}
+
+// New creates a coordinator with the test cases of all the tasks of the test plan in its queue.
+// The test cases are queued from the longest to the shortest estimated duration,
+// so the long test cases are claimed first, and the short ones fill the gaps at the end.
+func New(testPlan plan.TestPlan) *Coordinator {
+	var queue []plan.TestCase
+	for i := 0; i < len(testPlan.Tasks); i++ {
+		if task, ok := testPlan.Tasks[strconv.Itoa(i)]; ok {
+			queue = append(queue, task.Tests...)
+		}
+	}
+
+	slices.SortStableFunc(queue, func(a, b plan.TestCase) int {
+		return cmp.Compare(b.EstimatedDuration, a.EstimatedDuration)
+	})
+
+	return &Coordinator{
+		queue:  queue,
+		claims: map[claimKey][]plan.TestCase{},
+	}
+}
+
+// Claim removes up to n test cases from the front of the queue and returns them.
+// An empty batch is returned when the queue is drained.
+func (c *Coordinator) Claim(n int) []plan.TestCase {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.take(n)
+}
+
+// ClaimOnce is like Claim, but returns the batch already claimed by the node
+// when the claim with the same sequence is repeated.
+// A sequence lower than 1 doesn't identify a claim, therefore a new batch is always claimed.
+func (c *Coordinator) ClaimOnce(nodeIndex, sequence, n int) []plan.TestCase {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if sequence < 1 {
+		return c.take(n)
+	}
+
+	key := claimKey{nodeIndex: nodeIndex, sequence: sequence}
+	if batch, ok := c.claims[key]; ok {
+		return batch
+	}
+
+	batch := c.take(n)
+	c.claims[key] = batch
+	return batch
+}
+
+// take removes up to n test cases from the front of the queue and returns them.
+// The caller must hold c.mu.
+func (c *Coordinator) take(n int) []plan.TestCase {
+	n = min(n, len(c.queue))
+	batch := c.queue[:n:n]
+	c.queue = c.queue[n:]
+
+	return batch
+}
+
+// ServeHTTP handles the requests of the nodes to claim test cases.
+// The identifier of the test plan in the request is ignored, as the coordinator serves a single test plan.
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/test_plan/claim") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "not found"})
+		return
+	}
+
+	var params api.ClaimTestCasesParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid request: " + err.Error()})
+		return
+	}
+
+	if params.BatchSize < 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "batch_size must be greater than or equal to 1"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]plan.TestCase{"tests": c.ClaimOnce(params.NodeIndex, params.Sequence, params.BatchSize)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
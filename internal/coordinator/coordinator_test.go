@@ -0,0 +1,152 @@
+package coordinator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCoordinatorClaim(t *testing.T) {
+	testPlan := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"0": {NodeNumber: 0, Tests: []plan.TestCase{{Path: "apple", EstimatedDuration: 100}, {Path: "banana", EstimatedDuration: 300}}},
+			"1": {NodeNumber: 1, Tests: []plan.TestCase{{Path: "cherry", EstimatedDuration: 200}, {Path: "durian", EstimatedDuration: 100}}},
+		},
+	}
+
+	c := New(testPlan)
+
+	// The test cases are claimed from the longest to the shortest.
+	want := [][]plan.TestCase{
+		{{Path: "banana", EstimatedDuration: 300}, {Path: "cherry", EstimatedDuration: 200}},
+		{{Path: "apple", EstimatedDuration: 100}},
+		{{Path: "durian", EstimatedDuration: 100}},
+		{},
+	}
+
+	for i, n := range []int{2, 1, 5, 1} {
+		got := c.Claim(n)
+		if diff := cmp.Diff(got, want[i]); diff != "" {
+			t.Errorf("Coordinator.Claim(%d) #%d diff (-got +want):\n%s", n, i, diff)
+		}
+	}
+}
+
+func TestCoordinatorClaimOnce(t *testing.T) {
+	testPlan := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"0": {NodeNumber: 0, Tests: []plan.TestCase{{Path: "apple"}, {Path: "banana"}, {Path: "cherry"}}},
+		},
+	}
+
+	c := New(testPlan)
+
+	cases := []struct {
+		nodeIndex int
+		sequence  int
+		want      []plan.TestCase
+	}{
+		{nodeIndex: 0, sequence: 1, want: []plan.TestCase{{Path: "apple"}}},
+		// The claim is repeated, e.g. because the response was lost, so the same batch is returned.
+		{nodeIndex: 0, sequence: 1, want: []plan.TestCase{{Path: "apple"}}},
+		{nodeIndex: 1, sequence: 1, want: []plan.TestCase{{Path: "banana"}}},
+		{nodeIndex: 0, sequence: 2, want: []plan.TestCase{{Path: "cherry"}}},
+		{nodeIndex: 1, sequence: 2, want: []plan.TestCase{}},
+	}
+
+	for _, tc := range cases {
+		got := c.ClaimOnce(tc.nodeIndex, tc.sequence, 1)
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Errorf("Coordinator.ClaimOnce(%d, %d, 1) diff (-got +want):\n%s", tc.nodeIndex, tc.sequence, diff)
+		}
+	}
+}
+
+func TestCoordinatorServeHTTP(t *testing.T) {
+	testPlan := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"0": {NodeNumber: 0, Tests: []plan.TestCase{{Path: "apple"}, {Path: "banana"}}},
+			"1": {NodeNumber: 1, Tests: []plan.TestCase{{Path: "cherry"}}},
+			"2": {NodeNumber: 2, Tests: []plan.TestCase{}},
+		},
+	}
+
+	svr := httptest.NewServer(New(testPlan))
+	defer svr.Close()
+
+	client := api.NewClient(api.ClientConfig{
+		OrganizationSlug: "my-org",
+		ServerBaseUrl:    svr.URL,
+	})
+
+	// Every test case is claimed by exactly one of the concurrent nodes.
+	var mu sync.Mutex
+	var claimed []string
+	var wg sync.WaitGroup
+	for node := 0; node < 3; node++ {
+		wg.Add(1)
+		go func(node int) {
+			defer wg.Done()
+			for sequence := 1; ; sequence++ {
+				batch, err := client.ClaimTestCases(context.Background(), "my-suite", api.ClaimTestCasesParams{
+					Identifier: "abc123",
+					NodeIndex:  node,
+					BatchSize:  1,
+					Sequence:   sequence,
+				})
+				if err != nil {
+					t.Errorf("ClaimTestCases() error = %v", err)
+					return
+				}
+				if len(batch) == 0 {
+					return
+				}
+
+				mu.Lock()
+				for _, tc := range batch {
+					claimed = append(claimed, tc.Path)
+				}
+				mu.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	if got, want := len(claimed), 3; got != want {
+		t.Errorf("claimed %d test cases %v, want %d", got, claimed, want)
+	}
+}
+
+func TestCoordinatorServeHTTP_InvalidRequest(t *testing.T) {
+	svr := httptest.NewServer(New(plan.TestPlan{}))
+	defer svr.Close()
+
+	cases := []struct {
+		path string
+		body string
+		want int
+	}{
+		{path: "/v2/analytics/organizations/my-org/suites/my-suite/test_plan", body: "{}", want: http.StatusNotFound},
+		{path: "/v2/analytics/organizations/my-org/suites/my-suite/test_plan/claim", body: "batch", want: http.StatusBadRequest},
+		{path: "/v2/analytics/organizations/my-org/suites/my-suite/test_plan/claim", body: `{"batch_size": 0}`, want: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		resp, err := http.Post(svr.URL+tc.path, "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.want {
+			t.Errorf("POST %s %s status = %d, want %d", tc.path, tc.body, resp.StatusCode, tc.want)
+		}
+	}
+}
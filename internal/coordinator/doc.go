@@ -0,0 +1,2 @@
+// Package coordinator provides a local coordinator that serves the queue of a dynamic test plan.
+package coordinator
@@ -2,19 +2,72 @@ package plan
 
 import (
 	"cmp"
+	"os"
 	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// defaultDurationPerByte is the duration per byte used to estimate the duration of a file
+// when there is no timing to calibrate the estimate against.
+const defaultDurationPerByte = 10 * time.Microsecond
+
 // CreateFallbackPlan creates a fallback test plan for the given tests and parallelism.
 // It distributes test cases evenly accross the tasks using deterministic algorithm.
 func CreateFallbackPlan(files []string, parallelism int) TestPlan {
+	return createBinPackedPlan(files, nil, parallelism)
+}
+
+// CreateTimedFallbackPlan creates a fallback test plan for the given tests and parallelism,
+// balancing the tasks by the duration of the files.
+// The duration of a file is taken from timings when available, e.g. the timings fetched from the server.
+// Otherwise, it is estimated from the size of the file.
+func CreateTimedFallbackPlan(files []string, timings map[string]time.Duration, parallelism int) TestPlan {
+	return createBinPackedPlan(files, estimateDurations(files, timings), parallelism)
+}
+
+// createBinPackedPlan distributes the files to the tasks using the longest-processing-time-first algorithm.
+// The files are sorted by duration in descending order, then by name,
+// and each file is assigned to the task with the least total duration.
+// Ties are broken by the lowest node number, which makes the distribution deterministic.
+//
+// A file without a duration is assumed to take the average duration of the files with a duration.
+// When no file has a duration, the files are distributed in a round-robin fashion.
+func createBinPackedPlan(files []string, durations map[string]time.Duration, parallelism int) TestPlan {
+	files = slices.Clone(files)
+
+	var total time.Duration
+	var count int
+	for _, file := range files {
+		if duration, ok := durations[file]; ok {
+			total += duration
+			count++
+		}
+	}
+
+	averageDuration := time.Duration(1)
+	if count > 0 && total > 0 {
+		averageDuration = total / time.Duration(count)
+	}
+
+	durationOf := func(file string) time.Duration {
+		if duration, ok := durations[file]; ok {
+			return duration
+		}
+		return averageDuration
+	}
+
 	// sort all test cases
 	slices.SortFunc(files, func(a, b string) int {
+		if c := cmp.Compare(durationOf(b), durationOf(a)); c != 0 {
+			return c
+		}
 		return cmp.Compare(a, b)
 	})
 
 	tasks := make(map[string]*Task)
+	loads := make([]time.Duration, parallelism)
 	for i := 0; i < parallelism; i++ {
 		tasks[strconv.Itoa(i)] = &Task{
 			NodeNumber: i,
@@ -23,11 +76,21 @@ func CreateFallbackPlan(files []string, parallelism int) TestPlan {
 	}
 
 	// distribute files to tasks
-	for i, file := range files {
-		nodeNumber := i % parallelism
+	for _, file := range files {
+		nodeNumber := 0
+		for i, load := range loads {
+			if load < loads[nodeNumber] {
+				nodeNumber = i
+			}
+		}
+
+		duration := durationOf(file)
+		loads[nodeNumber] += duration
+
 		task := tasks[strconv.Itoa(nodeNumber)]
 		task.Tests = append(task.Tests, TestCase{
-			Path: file,
+			Path:              file,
+			EstimatedDuration: int(durations[file].Milliseconds()),
 		})
 	}
 
@@ -36,3 +99,78 @@ func CreateFallbackPlan(files []string, parallelism int) TestPlan {
 		Fallback: true,
 	}
 }
+
+// estimateDurations returns the duration of each of the given files.
+// The duration of a file is taken from timings when available.
+// Otherwise, it is estimated from the size of the file,
+// using the average duration per byte of the files with timings.
+// Files that have neither a timing nor a size are not included in the result.
+func estimateDurations(files []string, timings map[string]time.Duration) map[string]time.Duration {
+	durations := map[string]time.Duration{}
+	sizes := map[string]int64{}
+
+	// The server may return the paths of the files with a leading "./", e.g. "./spec/apple_spec.rb",
+	// therefore the timings are matched regardless of it.
+	normalizedTimings := map[string]time.Duration{}
+	for path, timing := range timings {
+		normalizedTimings[strings.TrimPrefix(path, "./")] = timing
+	}
+
+	var timedDuration time.Duration
+	var timedSize int64
+	for _, file := range files {
+		size, sizeErr := fileSize(file)
+		timing, hasTiming := normalizedTimings[strings.TrimPrefix(file, "./")]
+
+		if hasTiming {
+			durations[file] = timing
+			if sizeErr == nil {
+				timedDuration += timing
+				timedSize += size
+			}
+		} else if sizeErr == nil {
+			sizes[file] = size
+		}
+	}
+
+	durationPerByte := defaultDurationPerByte
+	if timedDuration > 0 && timedSize > 0 {
+		durationPerByte = timedDuration / time.Duration(timedSize)
+	}
+
+	for file, size := range sizes {
+		durations[file] = time.Duration(size) * durationPerByte
+	}
+
+	return durations
+}
+
+// fileSize returns the size of the file at the given path.
+// If the path is a directory, e.g. a Go package, it returns the total size of the files in the directory.
+func fileSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if !info.IsDir() {
+		return info.Size(), nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+	}
+
+	return size, nil
+}
@@ -1,7 +1,10 @@
 package plan
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -71,3 +74,108 @@ func TestCreateFallbackPlan(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateTimedFallbackPlan(t *testing.T) {
+	files := []string{"a", "b", "c", "d", "e", "f"}
+	timings := map[string]time.Duration{
+		"a": 1 * time.Second,
+		"b": 2 * time.Second,
+		"c": 3 * time.Second,
+		"d": 4 * time.Second,
+		"e": 5 * time.Second,
+		"f": 9 * time.Second,
+	}
+
+	plan := CreateTimedFallbackPlan(files, timings, 2)
+	got := make([][]TestCase, 2)
+	for _, task := range plan.Tasks {
+		got[task.NodeNumber] = task.Tests
+	}
+
+	// The longest files are distributed first, each to the node with the least total duration.
+	want := [][]TestCase{
+		{{Path: "f", EstimatedDuration: 9000}, {Path: "c", EstimatedDuration: 3000}},
+		{{Path: "e", EstimatedDuration: 5000}, {Path: "d", EstimatedDuration: 4000}, {Path: "b", EstimatedDuration: 2000}, {Path: "a", EstimatedDuration: 1000}},
+	}
+
+	if !plan.Fallback {
+		t.Errorf("CreateTimedFallbackPlan(%v, %v) Fallback is %v, want %v", files, timings, plan.Fallback, true)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("CreateTimedFallbackPlan(%v, %v) diff (-got +want):\n%s", files, timings, diff)
+	}
+}
+
+func TestCreateTimedFallbackPlan_EstimateFromFileSize(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		filepath.Join(dir, "small"),
+		filepath.Join(dir, "medium"),
+		filepath.Join(dir, "large"),
+		filepath.Join(dir, "timed"),
+	}
+	sizes := []int{100, 200, 300, 100}
+	for i, file := range files {
+		if err := os.WriteFile(file, make([]byte, sizes[i]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The timed file takes 1ms per byte, which is used to estimate the duration of the other files.
+	timings := map[string]time.Duration{
+		files[3]: 100 * time.Millisecond,
+	}
+
+	plan := CreateTimedFallbackPlan(files, timings, 2)
+	got := make([][]TestCase, 2)
+	for _, task := range plan.Tasks {
+		got[task.NodeNumber] = task.Tests
+	}
+
+	want := [][]TestCase{
+		{{Path: files[2], EstimatedDuration: 300}, {Path: files[3], EstimatedDuration: 100}},
+		{{Path: files[1], EstimatedDuration: 200}, {Path: files[0], EstimatedDuration: 100}},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("CreateTimedFallbackPlan(%v, %v) diff (-got +want):\n%s", files, timings, diff)
+	}
+}
+
+func TestCreateTimedFallbackPlan_WithoutTimings(t *testing.T) {
+	// The files don't exist, therefore their durations can't be estimated,
+	// and they are distributed in the same way as CreateFallbackPlan.
+	files := []string{"a", "c", "b", "e", "d"}
+
+	got := CreateTimedFallbackPlan(files, nil, 3)
+	want := CreateFallbackPlan(files, 3)
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("CreateTimedFallbackPlan(%v, nil) diff (-got +want):\n%s", files, diff)
+	}
+}
+
+func TestCreateTimedFallbackPlan_TimingsWithLeadingDotSlash(t *testing.T) {
+	files := []string{"spec/apple_spec.rb", "spec/banana_spec.rb", "./spec/cherry_spec.rb"}
+	timings := map[string]time.Duration{
+		"./spec/apple_spec.rb":  3 * time.Second,
+		"./spec/banana_spec.rb": 1 * time.Second,
+		"spec/cherry_spec.rb":   2 * time.Second,
+	}
+
+	plan := CreateTimedFallbackPlan(files, timings, 2)
+	got := make([][]TestCase, 2)
+	for _, task := range plan.Tasks {
+		got[task.NodeNumber] = task.Tests
+	}
+
+	want := [][]TestCase{
+		{{Path: "spec/apple_spec.rb", EstimatedDuration: 3000}},
+		{{Path: "./spec/cherry_spec.rb", EstimatedDuration: 2000}, {Path: "spec/banana_spec.rb", EstimatedDuration: 1000}},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("CreateTimedFallbackPlan(%v, %v) diff (-got +want):\n%s", files, timings, diff)
+	}
+}
@@ -0,0 +1,46 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ReadFile reads a test plan from the JSON file at the given path.
+func ReadFile(path string) (TestPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TestPlan{}, fmt.Errorf("failed to read test plan file: %w", err)
+	}
+
+	var testPlan TestPlan
+	if err := json.Unmarshal(data, &testPlan); err != nil {
+		return TestPlan{}, fmt.Errorf("failed to parse test plan file %q: %w", path, err)
+	}
+
+	if len(testPlan.Tasks) == 0 {
+		return TestPlan{}, fmt.Errorf("test plan file %q has no tasks", path)
+	}
+
+	return testPlan, nil
+}
+
+// WriteFile writes the test plan as JSON to the file at the given path.
+// The parent directories of the file are created if they don't exist.
+func WriteFile(path string, testPlan TestPlan) error {
+	data, err := json.MarshalIndent(testPlan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode test plan: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for test plan file: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write test plan file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package plan
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteFileAndReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plans", "plan.json")
+
+	want := TestPlan{
+		Experiment: "vanilla",
+		Tasks: map[string]*Task{
+			"0": {NodeNumber: 0, Tests: []TestCase{{Path: "a.rb", EstimatedDuration: 100}}},
+			"1": {NodeNumber: 1, Tests: []TestCase{{Path: "b.rb", Format: TestCaseFormatExample, Scope: "b", Name: "works"}}},
+		},
+		MutedTests: []TestCase{{Path: "b.rb", Scope: "b", Name: "is flaky"}},
+	}
+
+	if err := WriteFile(path, want); err != nil {
+		t.Fatalf("WriteFile(%q) error = %v", path, err)
+	}
+
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q) error = %v", path, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ReadFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestReadFile_FallbackPlan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	want := CreateFallbackPlan([]string{"a.rb", "b.rb"}, 2)
+	if err := WriteFile(path, want); err != nil {
+		t.Fatalf("WriteFile(%q) error = %v", path, err)
+	}
+
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q) error = %v", path, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ReadFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestReadFile_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"not json": "tasks",
+		"no tasks": `{"tasks": {}}`,
+	}
+
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "plan.json")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := ReadFile(path); err == nil {
+				t.Errorf("ReadFile(%q) error = nil, want error", path)
+			}
+		})
+	}
+}
+
+func TestReadFile_NotFound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	if _, err := ReadFile(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFile(%q) error = %v, want not exist error", path, err)
+	}
+}
@@ -0,0 +1,63 @@
+package plan
+
+import (
+	"cmp"
+	"slices"
+)
+
+// SplitTestCases splits the test cases into n groups of balanced estimated duration,
+// using the longest-processing-time-first algorithm, like the fallback plan.
+// Test cases without an estimated duration are assumed to take the average estimated duration.
+// The test cases keep their relative order within each group, and groups may be empty
+// when there are fewer test cases than groups.
+func SplitTestCases(testCases []TestCase, n int) [][]TestCase {
+	var total, count int
+	for _, tc := range testCases {
+		if tc.EstimatedDuration > 0 {
+			total += tc.EstimatedDuration
+			count++
+		}
+	}
+
+	averageDuration := 1
+	if count > 0 {
+		averageDuration = max(total/count, 1)
+	}
+
+	durationOf := func(tc TestCase) int {
+		if tc.EstimatedDuration > 0 {
+			return tc.EstimatedDuration
+		}
+		return averageDuration
+	}
+
+	// Sort the indices of the test cases by duration in descending order,
+	// keeping the original order of the test cases with the same duration.
+	order := make([]int, len(testCases))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(durationOf(testCases[b]), durationOf(testCases[a]))
+	})
+
+	assignments := make([]int, len(testCases))
+	loads := make([]int, n)
+	for _, i := range order {
+		group := 0
+		for j, load := range loads {
+			if load < loads[group] {
+				group = j
+			}
+		}
+		loads[group] += durationOf(testCases[i])
+		assignments[i] = group
+	}
+
+	groups := make([][]TestCase, n)
+	for i, tc := range testCases {
+		groups[assignments[i]] = append(groups[assignments[i]], tc)
+	}
+
+	return groups
+}
@@ -0,0 +1,46 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitTestCases(t *testing.T) {
+	testCases := []TestCase{
+		{Path: "a", EstimatedDuration: 100},
+		{Path: "b", EstimatedDuration: 300},
+		{Path: "c", EstimatedDuration: 200},
+		{Path: "d", EstimatedDuration: 100},
+		{Path: "e"},
+	}
+
+	// The durations are 300, 200, 175 (average), 100 and 100,
+	// which are assigned to the group with the least load in that order,
+	// resulting in the loads of 400 and 475.
+	got := SplitTestCases(testCases, 2)
+	want := [][]TestCase{
+		{{Path: "a", EstimatedDuration: 100}, {Path: "b", EstimatedDuration: 300}},
+		{{Path: "c", EstimatedDuration: 200}, {Path: "d", EstimatedDuration: 100}, {Path: "e"}},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("SplitTestCases(%v, 2) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestSplitTestCases_WithoutDurations(t *testing.T) {
+	testCases := []TestCase{{Path: "a"}, {Path: "b"}, {Path: "c"}}
+
+	got := SplitTestCases(testCases, 4)
+	want := [][]TestCase{
+		{{Path: "a"}},
+		{{Path: "b"}},
+		{{Path: "c"}},
+		nil,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("SplitTestCases(%v, 4) diff (-got +want):\n%s", testCases, diff)
+	}
+}
@@ -0,0 +1,143 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/runner"
+)
+
+// ErrAgentNotFound is returned by Annotate when the buildkite-agent binary can't be found.
+var ErrAgentNotFound = errors.New("buildkite-agent not found")
+
+// Annotation is a Markdown summary of the failed, passed on retry and muted tests of a node,
+// shown as a Buildkite annotation.
+type Annotation struct {
+	// Context identifies the annotation of the node, so each node has its own annotation.
+	Context string
+	// Style is the style of the annotation, either "error", "warning" or "success".
+	Style string
+	// Body is the Markdown content of the annotation.
+	Body string
+}
+
+// Empty returns true if the annotation has nothing to report, i.e. all tests passed on their first run.
+func (a Annotation) Empty() bool {
+	return a.Style == "success"
+}
+
+// NewAnnotation renders the annotation of the run result on the given node.
+// Failed tests are listed with their location and an excerpt of their failure message.
+func NewAnnotation(runResult runner.RunResult, nodeIndex int) Annotation {
+	var failed, passedOnRetry, muted []runner.TestResult
+	for _, testResult := range sortedTestResults(runResult) {
+		switch {
+		case testResult.Muted:
+			muted = append(muted, testResult)
+		case testResult.Status == runner.TestStatusFailed:
+			failed = append(failed, testResult)
+		case testResult.Flaky():
+			passedOnRetry = append(passedOnRetry, testResult)
+		}
+	}
+
+	annotation := Annotation{
+		Context: fmt.Sprintf("bktec-node-%d", nodeIndex),
+		Style:   "success",
+	}
+	switch {
+	case len(failed) > 0:
+		annotation.Style = "error"
+	case len(passedOnRetry) > 0, len(muted) > 0:
+		annotation.Style = "warning"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#### Buildkite Test Engine: node %d\n\n", nodeIndex)
+	fmt.Fprintf(&b, "%s run: %d failed, %d passed on retry, %d muted.\n",
+		pluralize(len(runResult.TestResults()), "test"), len(failed), len(passedOnRetry), len(muted))
+
+	if len(failed) > 0 {
+		fmt.Fprintf(&b, "\n##### Failed tests\n\n")
+		for _, testResult := range failed {
+			fmt.Fprintf(&b, "- %s\n", describeTest(testResult))
+			if excerpt := FailureExcerpt(testResult.FailureMessage, FailureExcerptLines); len(excerpt) > 0 {
+				fmt.Fprintf(&b, "\n  ```\n")
+				for _, line := range excerpt {
+					fmt.Fprintf(&b, "  %s\n", line)
+				}
+				fmt.Fprintf(&b, "  ```\n")
+			}
+		}
+	}
+
+	if len(passedOnRetry) > 0 {
+		fmt.Fprintf(&b, "\n##### Passed on retry\n\n")
+		for _, testResult := range passedOnRetry {
+			fmt.Fprintf(&b, "- %s, after %s\n", describeTest(testResult), pluralize(testResult.ExecutionCount, "attempt"))
+		}
+	}
+
+	if len(muted) > 0 {
+		fmt.Fprintf(&b, "\n##### Muted tests\n\n")
+		for _, testResult := range muted {
+			fmt.Fprintf(&b, "- %s (%s)\n", describeTest(testResult), testResult.Status)
+		}
+	}
+
+	annotation.Body = b.String()
+	return annotation
+}
+
+// describeTest returns the HTML-escaped scope and name of the test, followed by its location when available.
+func describeTest(testResult runner.TestResult) string {
+	description := html.EscapeString(strings.TrimSpace(testResult.Scope + " " + testResult.Name))
+	if location := testResult.Location(); location != "" {
+		description += fmt.Sprintf(" `%s`", location)
+	}
+	return description
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// WriteAnnotationFile writes the Markdown body of the annotation to the file at the given path.
+// The parent directories of the file are created if they don't exist.
+func WriteAnnotationFile(path string, annotation Annotation) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for annotation file: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(annotation.Body), 0644); err != nil {
+		return fmt.Errorf("failed to write annotation file: %w", err)
+	}
+
+	return nil
+}
+
+// Annotate pipes the annotation to `buildkite-agent annotate`, using the agent binary at the given path.
+// The path can also be a stub script for local testing.
+// ErrAgentNotFound is returned if the agent binary can't be found.
+func Annotate(agentPath string, annotation Annotation) error {
+	path, err := exec.LookPath(agentPath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAgentNotFound, err)
+	}
+
+	cmd := exec.Command(path, "annotate", "--context", annotation.Context, "--style", annotation.Style)
+	cmd.Stdin = strings.NewReader(annotation.Body)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("buildkite-agent annotate failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewAnnotation(t *testing.T) {
+	got := NewAnnotation(newRunResult(), 1)
+
+	want := Annotation{
+		Context: "bktec-node-1",
+		Style:   "error",
+		Body: "#### Buildkite Test Engine: node 1\n" +
+			"\n" +
+			"5 tests run: 1 failed, 1 passed on retry, 1 muted.\n" +
+			"\n" +
+			"##### Failed tests\n" +
+			"\n" +
+			"- Banana is yellow\n" +
+			"\n" +
+			"  ```\n" +
+			"  expected yellow, got brown\n" +
+			"  ```\n" +
+			"\n" +
+			"##### Passed on retry\n" +
+			"\n" +
+			"- Apple is crunchy, after 2 attempts\n" +
+			"\n" +
+			"##### Muted tests\n" +
+			"\n" +
+			"- Banana is curved (failed)\n",
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("NewAnnotation() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewAnnotation_Passed(t *testing.T) {
+	r := runner.NewRunResult([]plan.TestCase{})
+	apple := plan.TestCase{Path: "spec/apple_spec.rb", Scope: "Apple", Name: "is <red>"}
+	r.RecordTestResult(apple, runner.TestStatusFailed)
+	r.RecordTestResult(apple, runner.TestStatusPassed)
+	r.RecordTestLocation(apple, "spec/apple_spec.rb", 3)
+
+	got := NewAnnotation(*r, 0)
+
+	if got.Style != "warning" {
+		t.Errorf("NewAnnotation().Style = %q, want %q", got.Style, "warning")
+	}
+
+	want := "#### Buildkite Test Engine: node 0\n" +
+		"\n" +
+		"1 test run: 0 failed, 1 passed on retry, 0 muted.\n" +
+		"\n" +
+		"##### Passed on retry\n" +
+		"\n" +
+		"- Apple is &lt;red&gt; `spec/apple_spec.rb:3`, after 2 attempts\n"
+	if diff := cmp.Diff(got.Body, want); diff != "" {
+		t.Errorf("NewAnnotation().Body diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewAnnotation_ExecutedTwiceWithoutFailing(t *testing.T) {
+	r := runner.NewRunResult([]plan.TestCase{})
+	apple := plan.TestCase{Path: "spec/apple_spec.rb", Scope: "Apple", Name: "is red"}
+	r.RecordTestResult(apple, runner.TestStatusPassed)
+	r.RecordTestResult(apple, runner.TestStatusPassed)
+
+	got := NewAnnotation(*r, 0)
+
+	if !got.Empty() {
+		t.Errorf("NewAnnotation() = %+v, want empty", got)
+	}
+}
+
+func TestNewAnnotation_Empty(t *testing.T) {
+	r := runner.NewRunResult([]plan.TestCase{})
+	r.RecordTestResult(plan.TestCase{Scope: "Apple", Name: "is red"}, runner.TestStatusPassed)
+
+	if got := NewAnnotation(*r, 0); !got.Empty() {
+		t.Errorf("NewAnnotation().Empty() = false, want true")
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	dir := t.TempDir()
+	agentPath := filepath.Join(dir, "buildkite-agent")
+	stub := "#!/bin/sh\necho \"$@\" > \"$0.args\"\ncat > \"$0.body\"\n"
+	if err := os.WriteFile(agentPath, []byte(stub), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	annotation := Annotation{Context: "bktec-node-0", Style: "error", Body: "#### Failed\n"}
+	if err := Annotate(agentPath, annotation); err != nil {
+		t.Fatalf("Annotate(%q) error = %v", agentPath, err)
+	}
+
+	args, err := os.ReadFile(agentPath + ".args")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(args), "annotate --context bktec-node-0 --style error\n"); diff != "" {
+		t.Errorf("Annotate(%q) args diff (-got +want):\n%s", agentPath, diff)
+	}
+
+	body, err := os.ReadFile(agentPath + ".body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(body), annotation.Body); diff != "" {
+		t.Errorf("Annotate(%q) body diff (-got +want):\n%s", agentPath, diff)
+	}
+}
+
+func TestAnnotate_AgentNotFound(t *testing.T) {
+	agentPath := filepath.Join(t.TempDir(), "buildkite-agent")
+
+	err := Annotate(agentPath, Annotation{Context: "bktec-node-0", Style: "error"})
+	if !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Annotate(%q) error = %v, want %v", agentPath, err, ErrAgentNotFound)
+	}
+}
+
+func TestAnnotate_Error(t *testing.T) {
+	agentPath := filepath.Join(t.TempDir(), "buildkite-agent")
+	if err := os.WriteFile(agentPath, []byte("#!/bin/sh\necho 'no job' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Annotate(agentPath, Annotation{Context: "bktec-node-0", Style: "error"})
+	if err == nil || errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Annotate(%q) error = %v, want annotate error", agentPath, err)
+	}
+}
@@ -0,0 +1,2 @@
+// Package report writes the combined result of a test run, including all retries, to files for other tools.
+package report
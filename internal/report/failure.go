@@ -0,0 +1,33 @@
+package report
+
+import "strings"
+
+// FailureExcerptLines is the maximum number of lines of a failure message shown in the reports.
+const FailureExcerptLines = 5
+
+// FailureExcerpt returns up to maxLines non-blank lines of the failure message,
+// with a trailing "..." line when the message is truncated.
+func FailureExcerpt(message string, maxLines int) []string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(lines) == maxLines {
+			return append(lines, "...")
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+
+	return lines
+}
+
+// firstLine returns the first non-blank line of the message, used as a short message attribute.
+func firstLine(message string) string {
+	for _, line := range strings.Split(message, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,27 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFailureExcerpt(t *testing.T) {
+	message := "expected: true\n\ngot: false\n  at line 1\n  at line 2\n  at line 3\n  at line 4\n"
+
+	got := FailureExcerpt(message, 5)
+	want := []string{"expected: true", "got: false", "  at line 1", "  at line 2", "  at line 3", "..."}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("FailureExcerpt() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestFailureExcerpt_Short(t *testing.T) {
+	got := FailureExcerpt("expected: true\ngot: false", 5)
+	want := []string{"expected: true", "got: false"}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("FailureExcerpt() diff (-got +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,209 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/buildkite/test-engine-client/internal/timing"
+)
+
+// mutedMessage is the reason of the skipped element of a muted test.
+const mutedMessage = "muted by Buildkite Test Engine"
+
+// JUnitTestSuites is the root element of the JUnit XML report.
+type JUnitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       float64          `xml:"time,attr"`
+	TestSuites []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite groups the test cases of a test file.
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      float64         `xml:"time,attr"`
+	TestCases []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase is the outcome of a test case after all its retries.
+// Following the convention of the Maven Surefire reports, the failures of the earlier attempts
+// are reported as flakyFailure elements when the test eventually passed,
+// and as rerunFailure elements when it failed on every attempt.
+type JUnitTestCase struct {
+	Classname     string        `xml:"classname,attr"`
+	Name          string        `xml:"name,attr"`
+	File          string        `xml:"file,attr,omitempty"`
+	Line          int           `xml:"line,attr,omitempty"`
+	Time          float64       `xml:"time,attr"`
+	Failure       *JUnitFailure `xml:"failure"`
+	FlakyFailures []JUnitRerun  `xml:"flakyFailure"`
+	RerunFailures []JUnitRerun  `xml:"rerunFailure"`
+	Skipped       *JUnitSkipped `xml:"skipped"`
+}
+
+// JUnitFailure is the failure of the final attempt of a test case.
+type JUnitFailure struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnitRerun is the failure of an earlier attempt of a test case.
+type JUnitRerun struct {
+	Message    string  `xml:"message,attr,omitempty"`
+	Time       float64 `xml:"time,attr"`
+	StackTrace string  `xml:"stackTrace,omitempty"`
+}
+
+// JUnitSkipped marks a test case that didn't run, or whose outcome is ignored because it is muted.
+type JUnitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// NewJUnitReport builds the JUnit XML report of the run result, with a test suite for each test file.
+// The test suites and test cases are sorted, so the report is the same for the same result.
+func NewJUnitReport(runResult runner.RunResult) JUnitTestSuites {
+	report := JUnitTestSuites{Name: "bktec"}
+	for _, testResult := range sortedTestResults(runResult) {
+		file := testFile(testResult)
+		if len(report.TestSuites) == 0 || report.TestSuites[len(report.TestSuites)-1].Name != file {
+			report.TestSuites = append(report.TestSuites, JUnitTestSuite{Name: file})
+		}
+		suite := &report.TestSuites[len(report.TestSuites)-1]
+
+		testCase := newJUnitTestCase(testResult)
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+		suite.Time += testCase.Time
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+		if testCase.Skipped != nil {
+			suite.Skipped++
+		}
+	}
+
+	for _, suite := range report.TestSuites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Time += suite.Time
+	}
+
+	return report
+}
+
+// sortedTestResults returns the test results of the run sorted by test file, path, scope and name.
+func sortedTestResults(runResult runner.RunResult) []runner.TestResult {
+	testResults := runResult.TestResults()
+	slices.SortFunc(testResults, func(a, b runner.TestResult) int {
+		if c := strings.Compare(testFile(a), testFile(b)); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Scope+"/"+a.Name, b.Scope+"/"+b.Name)
+	})
+	return testResults
+}
+
+// testFile returns the test file of the test result, falling back to its path without the location of the example,
+// e.g. "spec/apple_spec.rb" for "./spec/apple_spec.rb[1:2]" in RSpec or "tests/test_apple.py::test_is_red" in pytest.
+func testFile(testResult runner.TestResult) string {
+	if testResult.File != "" {
+		return timing.FilePath(testResult.File)
+	}
+	return timing.FilePath(testResult.Path)
+}
+
+func newJUnitTestCase(testResult runner.TestResult) JUnitTestCase {
+	testCase := JUnitTestCase{
+		Classname: testResult.Scope,
+		Name:      testResult.Name,
+		File:      testResult.File,
+		Line:      testResult.Line,
+		Time:      testResult.Duration.Seconds(),
+	}
+
+	// The failures of the attempts before the final one.
+	var reruns []JUnitRerun
+	if len(testResult.Attempts) > 1 {
+		for _, attempt := range testResult.Attempts[:len(testResult.Attempts)-1] {
+			if attempt.Status == runner.TestStatusFailed {
+				reruns = append(reruns, JUnitRerun{
+					Message:    firstLine(attempt.FailureMessage),
+					Time:       attempt.Duration.Seconds(),
+					StackTrace: attempt.FailureMessage,
+				})
+			}
+		}
+	}
+
+	switch {
+	case testResult.Muted:
+		testCase.Skipped = &JUnitSkipped{Message: fmt.Sprintf("%s (%s)", mutedMessage, testResult.Status)}
+	case testResult.Status == runner.TestStatusPending:
+		testCase.Skipped = &JUnitSkipped{}
+	case testResult.Status == runner.TestStatusFailed:
+		testCase.Failure = &JUnitFailure{
+			Message: firstLine(testResult.FailureMessage),
+			Text:    testResult.FailureMessage,
+		}
+		testCase.RerunFailures = reruns
+	default:
+		testCase.FlakyFailures = reruns
+	}
+
+	return testCase
+}
+
+// WriteJUnit writes the JUnit XML report of the run result to w.
+func WriteJUnit(w io.Writer, runResult runner.RunResult) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write junit report: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(NewJUnitReport(runResult)); err != nil {
+		return fmt.Errorf("failed to write junit report: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write junit report: %w", err)
+	}
+
+	return nil
+}
+
+// WriteJUnitFile writes the JUnit XML report of the run result to the file at the given path.
+// The parent directories of the file are created if they don't exist.
+func WriteJUnitFile(path string, runResult runner.RunResult) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for junit report: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create junit report: %w", err)
+	}
+	defer f.Close()
+
+	if err := WriteJUnit(f, runResult); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
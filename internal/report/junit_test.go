@@ -0,0 +1,162 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newRunResult() runner.RunResult {
+	apple := plan.TestCase{Path: "spec/apple_spec.rb", Scope: "Apple", Name: "is red"}
+	appleFlaky := plan.TestCase{Path: "spec/apple_spec.rb", Scope: "Apple", Name: "is crunchy"}
+	banana := plan.TestCase{Path: "spec/banana_spec.rb", Scope: "Banana", Name: "is yellow"}
+	bananaMuted := plan.TestCase{Path: "spec/banana_spec.rb", Scope: "Banana", Name: "is curved"}
+	bananaPending := plan.TestCase{Path: "spec/banana_spec.rb", Scope: "Banana", Name: "is ripe"}
+
+	r := runner.NewRunResult([]plan.TestCase{bananaMuted})
+
+	// first run
+	r.RecordTestResult(apple, runner.TestStatusPassed)
+	r.RecordTestDuration(apple, 500*time.Millisecond)
+	r.RecordTestLocation(apple, "spec/apple_spec.rb", 3)
+	r.RecordTestResult(appleFlaky, runner.TestStatusFailed)
+	r.RecordTestDuration(appleFlaky, 1*time.Second)
+	r.RecordTestFailure(appleFlaky, "expected crunchy\n  spec/apple_spec.rb:8")
+	r.RecordTestResult(banana, runner.TestStatusFailed)
+	r.RecordTestDuration(banana, 250*time.Millisecond)
+	r.RecordTestFailure(banana, "expected yellow, got green")
+	r.RecordTestResult(bananaMuted, runner.TestStatusFailed)
+	r.RecordTestResult(bananaPending, runner.TestStatusPending)
+
+	// retry
+	r.RecordTestResult(appleFlaky, runner.TestStatusPassed)
+	r.RecordTestDuration(appleFlaky, 2*time.Second)
+	r.RecordTestResult(banana, runner.TestStatusFailed)
+	r.RecordTestDuration(banana, 750*time.Millisecond)
+	r.RecordTestFailure(banana, "expected yellow, got brown")
+
+	return *r
+}
+
+func TestNewJUnitReport(t *testing.T) {
+	got := NewJUnitReport(newRunResult())
+
+	want := JUnitTestSuites{
+		Name:     "bktec",
+		Tests:    5,
+		Failures: 1,
+		Skipped:  2,
+		Time:     3.25,
+		TestSuites: []JUnitTestSuite{
+			{
+				Name:  "spec/apple_spec.rb",
+				Tests: 2,
+				Time:  2.5,
+				TestCases: []JUnitTestCase{
+					{
+						Classname: "Apple",
+						Name:      "is crunchy",
+						Time:      2,
+						FlakyFailures: []JUnitRerun{
+							{Message: "expected crunchy", Time: 1, StackTrace: "expected crunchy\n  spec/apple_spec.rb:8"},
+						},
+					},
+					{Classname: "Apple", Name: "is red", File: "spec/apple_spec.rb", Line: 3, Time: 0.5},
+				},
+			},
+			{
+				Name:     "spec/banana_spec.rb",
+				Tests:    3,
+				Failures: 1,
+				Skipped:  2,
+				Time:     0.75,
+				TestCases: []JUnitTestCase{
+					{Classname: "Banana", Name: "is curved", Skipped: &JUnitSkipped{Message: "muted by Buildkite Test Engine (failed)"}},
+					{Classname: "Banana", Name: "is ripe", Skipped: &JUnitSkipped{}},
+					{
+						Classname: "Banana",
+						Name:      "is yellow",
+						Time:      0.75,
+						Failure:   &JUnitFailure{Message: "expected yellow, got brown", Text: "expected yellow, got brown"},
+						RerunFailures: []JUnitRerun{
+							{Message: "expected yellow, got green", Time: 0.25, StackTrace: "expected yellow, got green"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("NewJUnitReport() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewJUnitReport_TestFiles(t *testing.T) {
+	r := runner.NewRunResult([]plan.TestCase{})
+
+	// RSpec identifies the examples by their id, and reports their file.
+	appleRed := plan.TestCase{Path: "./spec/apple_spec.rb[1:1]", Scope: "Apple", Name: "is red"}
+	appleSweet := plan.TestCase{Path: "./spec/apple_spec.rb[1:2]", Scope: "Apple", Name: "is sweet"}
+	r.RecordTestResult(appleRed, runner.TestStatusPassed)
+	r.RecordTestLocation(appleRed, "./spec/apple_spec.rb", 3)
+	r.RecordTestResult(appleSweet, runner.TestStatusPassed)
+	r.RecordTestLocation(appleSweet, "./spec/apple_spec.rb", 7)
+
+	// pytest identifies the tests by their node id.
+	r.RecordTestResult(plan.TestCase{Path: "tests/test_banana.py::test_is_yellow", Scope: "tests/test_banana.py", Name: "test_is_yellow"}, runner.TestStatusPassed)
+	r.RecordTestResult(plan.TestCase{Path: "tests/test_banana.py::test_is_curved", Scope: "tests/test_banana.py", Name: "test_is_curved"}, runner.TestStatusPassed)
+
+	// Jest only reports the file.
+	cherry := plan.TestCase{Scope: "Cherry", Name: "is red"}
+	r.RecordTestResult(cherry, runner.TestStatusPassed)
+	r.RecordTestLocation(cherry, "src/cherry.test.js", 0)
+
+	got := NewJUnitReport(*r)
+
+	var suites []string
+	for _, suite := range got.TestSuites {
+		suites = append(suites, fmt.Sprintf("%s (%d)", suite.Name, suite.Tests))
+	}
+
+	want := []string{"spec/apple_spec.rb (2)", "src/cherry.test.js (1)", "tests/test_banana.py (2)"}
+	if diff := cmp.Diff(suites, want); diff != "" {
+		t.Errorf("NewJUnitReport() test suites diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestWriteJUnitFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "junit.xml")
+
+	if err := WriteJUnitFile(path, newRunResult()); err != nil {
+		t.Fatalf("WriteJUnitFile(%q) error = %v", path, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error = %v", path, err)
+	}
+
+	if !bytes.HasPrefix(data, []byte(xml.Header)) {
+		t.Errorf("junit report doesn't start with the XML header:\n%s", data)
+	}
+
+	var got JUnitTestSuites
+	if err := xml.Unmarshal(data, &got); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v", err)
+	}
+
+	want := NewJUnitReport(newRunResult())
+	want.XMLName = xml.Name{Local: "testsuites"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("WriteJUnitFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
@@ -0,0 +1,123 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/runner"
+)
+
+// Summary is the machine-readable summary of a test run on a node.
+type Summary struct {
+	// Status is the overall status of the run, either "passed" or "failed".
+	Status runner.RunStatus `json:"status"`
+	// NodeIndex is the index of the node that ran the tests.
+	NodeIndex int `json:"node_index"`
+	// Identifier is the identifier of the test plan.
+	Identifier string `json:"identifier"`
+	// Fallback is true if the test plan was created by bktec because Test Engine was unavailable.
+	Fallback   bool              `json:"fallback"`
+	Statistics SummaryStatistics `json:"statistics"`
+	Tests      []SummaryTest     `json:"tests"`
+	Timeline   []api.Timeline    `json:"timeline"`
+}
+
+// SummaryStatistics is the JSON representation of runner.RunStatistics.
+type SummaryStatistics struct {
+	Total            int `json:"total"`
+	PassedOnFirstRun int `json:"passed_on_first_run"`
+	PassedOnRetry    int `json:"passed_on_retry"`
+	MutedPassed      int `json:"muted_passed"`
+	MutedFailed      int `json:"muted_failed"`
+	Failed           int `json:"failed"`
+}
+
+// SummaryTest is the outcome of a test case after all its retries.
+type SummaryTest struct {
+	Scope          string            `json:"scope"`
+	Name           string            `json:"name"`
+	Path           string            `json:"path"`
+	Status         runner.TestStatus `json:"status"`
+	ExecutionCount int               `json:"execution_count"`
+	// Flaky is true if the test passed after failing in an earlier attempt.
+	Flaky bool `json:"flaky"`
+	Muted bool `json:"muted"`
+}
+
+// NewSummary builds the summary of the run result on the given node.
+// The tests are sorted by path, scope and name, so the summary is the same for the same result.
+func NewSummary(runResult runner.RunResult, nodeIndex int, identifier string, fallback bool, timeline []api.Timeline) Summary {
+	statistics := runResult.Statistics()
+
+	summary := Summary{
+		Status:     runResult.Status(),
+		NodeIndex:  nodeIndex,
+		Identifier: identifier,
+		Fallback:   fallback,
+		Statistics: SummaryStatistics{
+			Total:            statistics.Total,
+			PassedOnFirstRun: statistics.PassedOnFirstRun,
+			PassedOnRetry:    statistics.PassedOnRetry,
+			MutedPassed:      statistics.MutedPassed,
+			MutedFailed:      statistics.MutedFailed,
+			Failed:           statistics.Failed,
+		},
+		Tests:    []SummaryTest{},
+		Timeline: timeline,
+	}
+
+	if summary.Timeline == nil {
+		summary.Timeline = []api.Timeline{}
+	}
+
+	for _, testResult := range sortedTestResults(runResult) {
+		summary.Tests = append(summary.Tests, SummaryTest{
+			Scope:          testResult.Scope,
+			Name:           testResult.Name,
+			Path:           testResult.Path,
+			Status:         testResult.Status,
+			ExecutionCount: testResult.ExecutionCount,
+			Flaky:          testResult.Flaky(),
+			Muted:          testResult.Muted,
+		})
+	}
+
+	return summary
+}
+
+// WriteSummaryFile writes the summary as JSON to the file at the given path.
+// The parent directories of the file are created if they don't exist.
+func WriteSummaryFile(path string, summary Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for summary file: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+
+	return nil
+}
+
+// ReadSummaryFile reads the summary written by WriteSummaryFile from the file at the given path.
+func ReadSummaryFile(path string) (Summary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to read summary file: %w", err)
+	}
+
+	var summary Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return Summary{}, fmt.Errorf("failed to decode summary file %s: %w", path, err)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,136 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewSummary(t *testing.T) {
+	timeline := []api.Timeline{
+		{Timestamp: "2026-10-16T09:00:00Z", Event: "test_start"},
+		{Timestamp: "2026-10-16T09:01:00Z", Event: "test_end"},
+	}
+
+	got := NewSummary(newRunResult(), 2, "123/456", true, timeline)
+
+	want := Summary{
+		Status:     runner.RunStatusFailed,
+		NodeIndex:  2,
+		Identifier: "123/456",
+		Fallback:   true,
+		Statistics: SummaryStatistics{
+			Total:            5,
+			PassedOnFirstRun: 1,
+			PassedOnRetry:    1,
+			MutedFailed:      1,
+			Failed:           1,
+		},
+		Tests: []SummaryTest{
+			{Scope: "Apple", Name: "is crunchy", Path: "spec/apple_spec.rb", Status: runner.TestStatusPassed, ExecutionCount: 2, Flaky: true},
+			{Scope: "Apple", Name: "is red", Path: "spec/apple_spec.rb", Status: runner.TestStatusPassed, ExecutionCount: 1},
+			{Scope: "Banana", Name: "is curved", Path: "spec/banana_spec.rb", Status: runner.TestStatusFailed, ExecutionCount: 1, Muted: true},
+			{Scope: "Banana", Name: "is ripe", Path: "spec/banana_spec.rb", Status: runner.TestStatusPending, ExecutionCount: 1},
+			{Scope: "Banana", Name: "is yellow", Path: "spec/banana_spec.rb", Status: runner.TestStatusFailed, ExecutionCount: 2},
+		},
+		Timeline: timeline,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("NewSummary() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewSummary_ExecutedTwiceWithoutFailing(t *testing.T) {
+	r := runner.NewRunResult([]plan.TestCase{})
+	apple := plan.TestCase{Path: "spec/apple_spec.rb", Scope: "Apple", Name: "is red"}
+	r.RecordTestResult(apple, runner.TestStatusPassed)
+	r.RecordTestResult(apple, runner.TestStatusPassed)
+
+	got := NewSummary(*r, 0, "123/456", false, nil)
+
+	want := []SummaryTest{
+		{Scope: "Apple", Name: "is red", Path: "spec/apple_spec.rb", Status: runner.TestStatusPassed, ExecutionCount: 2},
+	}
+
+	if diff := cmp.Diff(got.Tests, want); diff != "" {
+		t.Errorf("NewSummary() tests diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestWriteSummaryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "summary.json")
+	summary := NewSummary(runner.RunResult{}, 0, "123/456", false, nil)
+
+	if err := WriteSummaryFile(path, summary); err != nil {
+		t.Fatalf("WriteSummaryFile(%q) error = %v", path, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error = %v", path, err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	want := map[string]any{
+		"status":     "passed",
+		"node_index": float64(0),
+		"identifier": "123/456",
+		"fallback":   false,
+		"statistics": map[string]any{
+			"total":               float64(0),
+			"passed_on_first_run": float64(0),
+			"passed_on_retry":     float64(0),
+			"muted_passed":        float64(0),
+			"muted_failed":        float64(0),
+			"failed":              float64(0),
+		},
+		"tests":    []any{},
+		"timeline": []any{},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("WriteSummaryFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestReadSummaryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+	want := NewSummary(newRunResult(), 3, "123/456", true, []api.Timeline{
+		{Event: "test_start", Timestamp: "2024-06-20T04:46:13.60977Z"},
+	})
+
+	if err := WriteSummaryFile(path, want); err != nil {
+		t.Fatalf("WriteSummaryFile(%q) error = %v", path, err)
+	}
+
+	got, err := ReadSummaryFile(path)
+	if err != nil {
+		t.Fatalf("ReadSummaryFile(%q) error = %v", path, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ReadSummaryFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestReadSummaryFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadSummaryFile(path); err == nil {
+		t.Errorf("ReadSummaryFile(%q) error = nil, want an error", path)
+	}
+}
@@ -5,22 +5,57 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"regexp"
 	"strings"
 	"syscall"
 )
 
 // runAndForwardSignal runs the command and forwards any signals received to the command.
+// The output of the command is written to stdout and stderr, unless the command already has its own writers.
 func runAndForwardSignal(cmd *exec.Cmd) error {
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+
+	fmt.Println(strings.Join(cmd.Args, " "))
+	fmt.Println("")
+
+	return startAndForwardSignal(cmd)
+}
+
+// run runs the command with the environment and output of the runner config, and forwards any signals received to the command.
+// The output is only written to the output of the runner config when the command doesn't have its own writers.
+func (c RunnerConfig) run(cmd *exec.Cmd) error {
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+
+	if c.Output == nil {
+		return runAndForwardSignal(cmd)
+	}
+
+	if cmd.Stderr == nil {
+		cmd.Stderr = c.Output
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = c.Output
+	}
+
+	fmt.Fprintln(c.Output, strings.Join(cmd.Args, " "))
+	fmt.Fprintln(c.Output, "")
+
+	return startAndForwardSignal(cmd)
+}
 
+// startAndForwardSignal starts the command, forwards any signals received to the command, and waits for it to finish.
+func startAndForwardSignal(cmd *exec.Cmd) error {
 	// Create a channel that will be closed when the command finishes.
 	finishCh := make(chan struct{})
 	defer close(finishCh)
 
-	fmt.Println(strings.Join(cmd.Args, " "))
-	fmt.Println("")
-
 	if err := cmd.Start(); err != nil {
 		return err
 	}
@@ -44,6 +79,11 @@ func runAndForwardSignal(cmd *exec.Cmd) error {
 				if sig == syscall.SIGCHLD {
 					continue
 				}
+				// SIGPIPE is received when writing to the stdin of a subprocess that has exited or closed its stdin.
+				// It is meant for us, not the subprocess.
+				if sig == syscall.SIGPIPE {
+					continue
+				}
 				// Ignore the error when sending the signal to the command.
 				_ = cmd.Process.Signal(sig)
 			case <-finishCh:
@@ -69,3 +109,13 @@ func runAndForwardSignal(cmd *exec.Cmd) error {
 
 	return nil
 }
+
+// testNamePattern returns a pattern matching any of the given test names.
+func testNamePattern(testNames []string) string {
+	escapedTestNames := make([]string, len(testNames))
+	for i, testName := range testNames {
+		escapedTestNames[i] = regexp.QuoteMeta(testName)
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(escapedTestNames, "|"))
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+)
+
+// Concurrent is a runner that runs the test cases in several processes of a runner concurrently.
+// The test cases are split into balanced sub-batches, one for each process,
+// and the results of the processes are merged into a single result.
+// Other than Run, the methods are delegated to the runner of the first process.
+type Concurrent struct {
+	TestRunner
+	runners []TestRunner
+	outputs []*prefixWriter
+}
+
+// NewConcurrent creates a runner that runs n processes of the runner created by newRunner concurrently.
+// Each process has its own result path, derived from the result path of the config,
+// the TEST_ENV_NUMBER environment variable (empty for the first process, then "2", "3", and so on),
+// and its output prefixed with its number.
+func NewConcurrent(c RunnerConfig, n int, newRunner func(RunnerConfig) TestRunner) Concurrent {
+	runners := make([]TestRunner, n)
+	outputs := make([]*prefixWriter, n)
+
+	var mu sync.Mutex
+	for i := 0; i < n; i++ {
+		outputs[i] = &prefixWriter{
+			w:      os.Stdout,
+			mu:     &mu,
+			prefix: fmt.Sprintf("[%d] ", i+1),
+		}
+
+		processConfig := c
+		processConfig.ResultPath = processResultPath(c.ResultPath, i)
+		processConfig.Env = append(c.Env[:len(c.Env):len(c.Env)],
+			"TEST_ENV_NUMBER="+testEnvNumber(i),
+			fmt.Sprintf("PARALLEL_TEST_GROUPS=%d", n),
+		)
+		processConfig.Output = outputs[i]
+
+		runners[i] = newRunner(processConfig)
+	}
+
+	return Concurrent{
+		TestRunner: runners[0],
+		runners:    runners,
+		outputs:    outputs,
+	}
+}
+
+// Run splits the test cases into sub-batches, and runs them in the processes concurrently.
+// The results of the processes are recorded into the result once all the processes finish.
+// Processes without any test case are not run, as most test runners would run all the tests.
+//
+// Error is returned if any of the processes returns an error.
+func (c Concurrent) Run(result *RunResult, testCases []plan.TestCase, retry bool) error {
+	batches := plan.SplitTestCases(testCases, len(c.runners))
+	results := make([]*RunResult, len(batches))
+	errs := make([]error, len(batches))
+
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		if len(batch) == 0 {
+			continue
+		}
+
+		results[i] = NewRunResult([]plan.TestCase{})
+		wg.Add(1)
+		go func(i int, batch []plan.TestCase) {
+			defer wg.Done()
+			errs[i] = c.runners[i].Run(results[i], batch, retry)
+			c.outputs[i].Flush()
+		}(i, batch)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if r != nil {
+			result.merge(r)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// processResultPath returns the result path of the process with the given index,
+// by appending the number of the process to the name of the file, e.g. "tmp/rspec-2.json".
+func processResultPath(resultPath string, index int) string {
+	if resultPath == "" {
+		return ""
+	}
+
+	ext := filepath.Ext(resultPath)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(resultPath, ext), index+1, ext)
+}
+
+// testEnvNumber returns the value of TEST_ENV_NUMBER of the process with the given index,
+// following the convention of the parallel_tests gem, where the first process has an empty value.
+func testEnvNumber(index int) string {
+	if index == 0 {
+		return ""
+	}
+	return fmt.Sprint(index + 1)
+}
+
+// prefixWriter writes each line to the underlying writer with a prefix.
+// Incomplete lines are buffered until they are completed or flushed,
+// so that the lines of the concurrent processes sharing the same mutex are not interleaved.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+
+	i := bytes.LastIndexByte(p.buf, '\n')
+	if i < 0 {
+		return len(b), nil
+	}
+
+	lines := p.buf[:i+1]
+	p.buf = append([]byte(nil), p.buf[i+1:]...)
+
+	if err := p.write(lines); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush writes the buffered incomplete line, if any.
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+
+	line := append(p.buf, '\n')
+	p.buf = nil
+	return p.write(line)
+}
+
+func (p *prefixWriter) write(lines []byte) error {
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) > 0 {
+			out.WriteString(p.prefix)
+			out.Write(line)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.w.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,230 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// fakeRunner records the test cases it runs, and fails the tests named "is sweet".
+type fakeRunner struct {
+	RunnerConfig
+	mu      *sync.Mutex
+	batches *[][]plan.TestCase
+	err     error
+}
+
+func (f fakeRunner) Run(result *RunResult, testCases []plan.TestCase, retry bool) error {
+	f.mu.Lock()
+	*f.batches = append(*f.batches, testCases)
+	f.mu.Unlock()
+
+	for _, tc := range testCases {
+		if tc.Name == "is sweet" && !retry {
+			result.RecordTestResult(tc, TestStatusFailed)
+		} else {
+			result.RecordTestResult(tc, TestStatusPassed)
+		}
+	}
+
+	if f.err != nil {
+		result.err = f.err
+	}
+	return f.err
+}
+
+func (f fakeRunner) GetExamples(files []string) ([]plan.TestCase, error) { return nil, nil }
+func (f fakeRunner) GetFiles() ([]string, error)                         { return nil, nil }
+func (f fakeRunner) Name() string                                        { return "Fake" }
+
+func TestNewConcurrent(t *testing.T) {
+	var configs []RunnerConfig
+	NewConcurrent(RunnerConfig{ResultPath: "tmp/rspec.json"}, 3, func(c RunnerConfig) TestRunner {
+		configs = append(configs, c)
+		return fakeRunner{RunnerConfig: c}
+	})
+
+	want := []RunnerConfig{
+		{ResultPath: "tmp/rspec-1.json", Env: []string{"TEST_ENV_NUMBER=", "PARALLEL_TEST_GROUPS=3"}},
+		{ResultPath: "tmp/rspec-2.json", Env: []string{"TEST_ENV_NUMBER=2", "PARALLEL_TEST_GROUPS=3"}},
+		{ResultPath: "tmp/rspec-3.json", Env: []string{"TEST_ENV_NUMBER=3", "PARALLEL_TEST_GROUPS=3"}},
+	}
+
+	if diff := cmp.Diff(configs, want, cmpopts.IgnoreFields(RunnerConfig{}, "Output")); diff != "" {
+		t.Errorf("NewConcurrent() configs diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestConcurrentRun(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]plan.TestCase
+	concurrent := NewConcurrent(RunnerConfig{}, 2, func(c RunnerConfig) TestRunner {
+		return fakeRunner{RunnerConfig: c, mu: &mu, batches: &batches}
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "apple_spec.rb:1", Scope: "apple", Name: "is red", EstimatedDuration: 300},
+		{Path: "apple_spec.rb:5", Scope: "apple", Name: "is sweet", EstimatedDuration: 100},
+		{Path: "banana_spec.rb:1", Scope: "banana", Name: "is yellow", EstimatedDuration: 200},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := concurrent.Run(result, testCases, false)
+
+	if err != nil {
+		t.Errorf("Concurrent.Run(%q) error = %v", testCases, err)
+	}
+
+	if len(batches) != 2 {
+		t.Errorf("Concurrent.Run(%q) ran %d batches, want 2", testCases, len(batches))
+	}
+
+	wantFailedTests := []plan.TestCase{testCases[1]}
+	if diff := cmp.Diff(result.FailedTests(), wantFailedTests); diff != "" {
+		t.Errorf("Concurrent.Run(%q) RunResult.FailedTests() diff (-got +want):\n%s", testCases, diff)
+	}
+
+	// The failed test is retried in a single process.
+	err = concurrent.Run(result, result.FailedTests(), true)
+
+	if err != nil {
+		t.Errorf("Concurrent.Run(%q) error = %v", testCases, err)
+	}
+
+	if len(batches) != 3 {
+		t.Errorf("Concurrent.Run(%q) ran %d batches, want 3", testCases, len(batches))
+	}
+
+	wantStatistics := RunStatistics{
+		Total:            3,
+		PassedOnFirstRun: 2,
+		PassedOnRetry:    1,
+	}
+
+	if diff := cmp.Diff(result.Statistics(), wantStatistics); diff != "" {
+		t.Errorf("Concurrent.Run(%q) RunResult.Statistics() diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestConcurrentRun_Error(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]plan.TestCase
+	exitError := exec.Command("false").Run()
+	concurrent := NewConcurrent(RunnerConfig{}, 2, func(c RunnerConfig) TestRunner {
+		runner := fakeRunner{RunnerConfig: c, mu: &mu, batches: &batches}
+		if c.Env[0] == "TEST_ENV_NUMBER=2" {
+			runner.err = exitError
+		}
+		return runner
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "apple_spec.rb:1", Scope: "apple", Name: "is red"},
+		{Path: "banana_spec.rb:1", Scope: "banana", Name: "is yellow"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := concurrent.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("Concurrent.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	if !errors.As(err, new(*exec.ExitError)) {
+		t.Errorf("Concurrent.Run(%q) error type = %T (%v), want *exec.ExitError", testCases, err, err)
+	}
+}
+
+func TestConcurrentRun_GoTestOutput(t *testing.T) {
+	changeGoTestCwd(t)
+
+	concurrent := NewConcurrent(RunnerConfig{ResultPath: filepath.Join(t.TempDir(), "result.json")}, 2, func(c RunnerConfig) TestRunner {
+		return NewGoTest(c)
+	})
+
+	var out bytes.Buffer
+	for _, output := range concurrent.outputs {
+		output.w = &out
+	}
+
+	testCases := []plan.TestCase{
+		{Path: "./fruits", EstimatedDuration: 200},
+		{Path: "./vegetables", EstimatedDuration: 100},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	if err := concurrent.Run(result, testCases, false); err != nil {
+		t.Errorf("Concurrent.Run(%q) error = %v", testCases, err)
+	}
+
+	// The output of go test is decoded from the event stream, and prefixed with the number of the process.
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		if !strings.HasPrefix(line, "[1] ") && !strings.HasPrefix(line, "[2] ") {
+			t.Errorf("Concurrent.Run(%q) output line %q, want it prefixed with the number of the process", testCases, line)
+		}
+	}
+
+	for _, want := range []string{"[1] --- FAIL: TestBanana/green", "[2] --- PASS: TestTomato"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Concurrent.Run(%q) output = %q, want it to contain %q", testCases, out.String(), want)
+		}
+	}
+}
+
+func TestRunnerConfigRun_EnvAndOutput(t *testing.T) {
+	var out bytes.Buffer
+	c := RunnerConfig{
+		Env:    []string{"TEST_ENV_NUMBER=2"},
+		Output: &prefixWriter{w: &out, mu: &sync.Mutex{}, prefix: "[2] "},
+	}
+
+	cmd := exec.Command("sh", "-c", "echo number $TEST_ENV_NUMBER; echo oops >&2")
+	if err := c.run(cmd); err != nil {
+		t.Errorf("RunnerConfig.run(%q) error = %v", cmd.Args, err)
+	}
+
+	want := "[2] sh -c echo number $TEST_ENV_NUMBER; echo oops >&2\n[2] \n[2] number 2\n[2] oops\n"
+	if diff := cmp.Diff(out.String(), want); diff != "" {
+		t.Errorf("RunnerConfig.run(%q) output diff (-got +want):\n%s", cmd.Args, diff)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &prefixWriter{w: &out, mu: &sync.Mutex{}, prefix: "[1] "}
+
+	w.Write([]byte("hello"))
+	w.Write([]byte(" world\nfoo\nba"))
+	if got, want := out.String(), "[1] hello world\n[1] foo\n"; got != want {
+		t.Errorf("prefixWriter output = %q, want %q", got, want)
+	}
+
+	w.Flush()
+	if got, want := out.String(), "[1] hello world\n[1] foo\n[1] ba\n"; got != want {
+		t.Errorf("prefixWriter output after Flush() = %q, want %q", got, want)
+	}
+}
+
+func TestProcessResultPath(t *testing.T) {
+	cases := []struct {
+		resultPath string
+		index      int
+		want       string
+	}{
+		{resultPath: "tmp/rspec.json", index: 0, want: "tmp/rspec-1.json"},
+		{resultPath: "tmp/junit.xml", index: 3, want: "tmp/junit-4.xml"},
+		{resultPath: "report", index: 1, want: "report-2"},
+		{resultPath: "", index: 1, want: ""},
+	}
+
+	for _, tc := range cases {
+		if got := processResultPath(tc.resultPath, tc.index); got != tc.want {
+			t.Errorf("processResultPath(%q, %d) = %q, want %q", tc.resultPath, tc.index, got, tc.want)
+		}
+	}
+}
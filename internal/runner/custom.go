@@ -0,0 +1,210 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+
+	"github.com/buildkite/test-engine-client/internal/debug"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/kballard/go-shellquote"
+)
+
+// CustomProtocolVersion is the version of the protocol between bktec and a custom runner adapter.
+const CustomProtocolVersion = 1
+
+// Custom is a runner that delegates to an external adapter executable, set as the test command.
+// For each method of the TestRunner interface, bktec runs the adapter,
+// writes a CustomRequest as JSON to its stdin, and reads a CustomResponse as JSON from its stdout.
+// The adapter can write anything else, e.g. the output of the tests, to stderr.
+type Custom struct {
+	RunnerConfig
+	// name returns the name reported by the adapter, which is only requested once.
+	name func() string
+}
+
+func NewCustom(c RunnerConfig) Custom {
+	custom := Custom{
+		RunnerConfig: c,
+	}
+	custom.name = sync.OnceValue(custom.requestName)
+	return custom
+}
+
+// CustomRequest is the request sent to the adapter.
+type CustomRequest struct {
+	Version int    `json:"version"`
+	Method  string `json:"method"`
+	// TestFilePattern and TestFileExcludePattern are sent with the "get_files" method.
+	TestFilePattern        string `json:"test_file_pattern,omitempty"`
+	TestFileExcludePattern string `json:"test_file_exclude_pattern,omitempty"`
+	// Files is sent with the "get_examples" method.
+	Files []string `json:"files,omitempty"`
+	// TestCases, Retry and ResultPath are sent with the "run" method.
+	TestCases  []plan.TestCase `json:"test_cases,omitempty"`
+	Retry      bool            `json:"retry,omitempty"`
+	ResultPath string          `json:"result_path,omitempty"`
+}
+
+// CustomTestResult is the result of a single test case in the response to the "run" method.
+// Duration is the duration of the test in seconds.
+// Duration, FailureMessage, File and Line are optional.
+type CustomTestResult struct {
+	plan.TestCase
+	Status         TestStatus `json:"status"`
+	Duration       float64    `json:"duration,omitempty"`
+	FailureMessage string     `json:"failure_message,omitempty"`
+	File           string     `json:"file,omitempty"`
+	Line           int        `json:"line,omitempty"`
+}
+
+// CustomResponse is the response read from the adapter.
+// When the adapter fails to handle the request, it returns the reason in Error.
+type CustomResponse struct {
+	Error    string             `json:"error,omitempty"`
+	Name     string             `json:"name,omitempty"`
+	Files    []string           `json:"files,omitempty"`
+	Examples []plan.TestCase    `json:"examples,omitempty"`
+	Results  []CustomTestResult `json:"results,omitempty"`
+}
+
+// Name returns the name reported by the adapter.
+// If the adapter fails to report its name, "Custom" is returned.
+// The adapter is only asked for its name the first time.
+func (c Custom) Name() string {
+	if c.name == nil {
+		return c.requestName()
+	}
+	return c.name()
+}
+
+func (c Custom) requestName() string {
+	response, err := c.call(CustomRequest{Method: "name"})
+	if err != nil || response.Name == "" {
+		debug.Println("Failed to get the name of the custom runner:", err)
+		return "Custom"
+	}
+	return response.Name
+}
+
+// GetFiles returns an array of file names discovered by the adapter.
+func (c Custom) GetFiles() ([]string, error) {
+	response, err := c.call(CustomRequest{
+		Method:                 "get_files",
+		TestFilePattern:        c.TestFilePattern,
+		TestFileExcludePattern: c.TestFileExcludePattern,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	debug.Println("Discovered", len(response.Files), "files")
+
+	if len(response.Files) == 0 {
+		return nil, fmt.Errorf("no files found by custom runner")
+	}
+
+	return response.Files, nil
+}
+
+// GetExamples returns an array of test examples within the given files, as reported by the adapter.
+func (c Custom) GetExamples(files []string) ([]plan.TestCase, error) {
+	response, err := c.call(CustomRequest{
+		Method: "get_examples",
+		Files:  files,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Examples, nil
+}
+
+// Run asks the adapter to run the given test cases, and records the results returned by the adapter.
+// The adapter is expected to exit successfully when tests fail, and report the failures in the results.
+//
+// Error is returned if the adapter fails to run, exits prematurely, exits with a non-zero status,
+// or returns an error in the response.
+func (c Custom) Run(result *RunResult, testCases []plan.TestCase, retry bool) error {
+	response, err := c.call(CustomRequest{
+		Method:     "run",
+		TestCases:  testCases,
+		Retry:      retry,
+		ResultPath: c.ResultPath,
+	})
+	if err != nil {
+		if ProcessSignaledError := new(ProcessSignaledError); !errors.As(err, &ProcessSignaledError) {
+			fmt.Println("Buildkite Test Engine Client: Failed to read custom runner output, tests will not be retried.")
+		}
+		result.err = err
+		return err
+	}
+
+	for _, testResult := range response.Results {
+		result.recordTestResult(TestResult{
+			TestCase:       testResult.TestCase,
+			Status:         testResult.Status,
+			Duration:       secondsToDuration(testResult.Duration),
+			FailureMessage: testResult.FailureMessage,
+			File:           testResult.File,
+			Line:           testResult.Line,
+		})
+	}
+
+	return nil
+}
+
+// call runs the adapter with the given request, and returns the response of the adapter.
+func (c Custom) call(request CustomRequest) (CustomResponse, error) {
+	request.Version = CustomProtocolVersion
+
+	words, err := shellquote.Split(c.TestCommand)
+	if err != nil {
+		return CustomResponse{}, fmt.Errorf("failed to build command: %w", err)
+	}
+
+	if len(words) == 0 {
+		return CustomResponse{}, fmt.Errorf("custom runner command is empty")
+	}
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return CustomResponse{}, fmt.Errorf("failed to encode custom runner request: %w", err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(words[0], words[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+
+	debug.Printf("Sending %q request to custom runner", request.Method)
+
+	// Running the tests can take a long time, so signals are forwarded to the adapter,
+	// and its stderr is printed as the output of the tests.
+	// For the other methods, the stderr of the adapter is only shown when it fails.
+	if request.Method == "run" {
+		if err := c.run(cmd); err != nil {
+			return CustomResponse{}, err
+		}
+	} else {
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return CustomResponse{}, fmt.Errorf("custom runner failed to handle %q request: %w\n%s", request.Method, err, stderr.Bytes())
+		}
+	}
+
+	var response CustomResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return CustomResponse{}, fmt.Errorf("failed to parse custom runner response: %v", err)
+	}
+
+	if response.Error != "" {
+		return CustomResponse{}, fmt.Errorf("custom runner failed to handle %q request: %s", request.Method, response.Error)
+	}
+
+	return response, nil
+}
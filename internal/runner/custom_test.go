@@ -0,0 +1,245 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kballard/go-shellquote"
+)
+
+func TestCustomName(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "./testdata/custom/adapter.sh",
+	})
+
+	if got, want := custom.Name(), "Fruit"; got != want {
+		t.Errorf("Custom.Name() = %q, want %q", got, want)
+	}
+}
+
+func TestCustomName_AdapterFailed(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "false",
+	})
+
+	if got, want := custom.Name(), "Custom"; got != want {
+		t.Errorf("Custom.Name() = %q, want %q", got, want)
+	}
+}
+
+func TestCustomName_RequestedOnce(t *testing.T) {
+	requests := filepath.Join(t.TempDir(), "requests")
+	custom := NewCustom(RunnerConfig{
+		TestCommand: shellquote.Join("sh", "-c", `cat >> "$0"; echo >> "$0"; echo '{"name": "Fruit"}'`, requests),
+	})
+
+	for i := 0; i < 3; i++ {
+		if got, want := custom.Name(), "Fruit"; got != want {
+			t.Errorf("Custom.Name() = %q, want %q", got, want)
+		}
+	}
+
+	data, err := os.ReadFile(requests)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error = %v", requests, err)
+	}
+
+	if got := strings.Count(string(data), "\n"); got != 1 {
+		t.Errorf("Custom.Name() sent %d requests to the adapter, want 1", got)
+	}
+}
+
+func TestCustomGetFiles(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "./testdata/custom/adapter.sh",
+	})
+
+	got, err := custom.GetFiles()
+	if err != nil {
+		t.Errorf("Custom.GetFiles() error = %v", err)
+	}
+
+	want := []string{
+		"fruits/apple.fruit",
+		"fruits/banana.fruit",
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Custom.GetFiles() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestCustomGetExamples(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "./testdata/custom/adapter.sh",
+	})
+
+	files := []string{"fruits/apple.fruit"}
+	got, err := custom.GetExamples(files)
+	if err != nil {
+		t.Errorf("Custom.GetExamples(%q) error = %v", files, err)
+	}
+
+	want := []plan.TestCase{
+		{Path: "fruits/apple.fruit:1", Scope: "apple", Name: "is red"},
+		{Path: "fruits/apple.fruit:5", Scope: "apple", Name: "is sweet"},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Custom.GetExamples(%q) diff (-got +want):\n%s", files, diff)
+	}
+}
+
+func TestCustomRun(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "./testdata/custom/adapter.sh",
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "fruits/apple.fruit"},
+		{Path: "fruits/banana.fruit"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := custom.Run(result, testCases, false)
+
+	if err != nil {
+		t.Errorf("Custom.Run(%q) error = %v", testCases, err)
+	}
+
+	if result.Status() != RunStatusFailed {
+		t.Errorf("Custom.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusFailed)
+	}
+
+	wantFailedTests := []plan.TestCase{
+		{Path: "fruits/apple.fruit:5", Scope: "apple", Name: "is sweet"},
+	}
+
+	if diff := cmp.Diff(result.FailedTests(), wantFailedTests); diff != "" {
+		t.Errorf("Custom.Run(%q) RunResult.FailedTests() diff (-got +want):\n%s", testCases, diff)
+	}
+
+	apple := plan.TestCase{Path: "fruits/apple.fruit:1", Scope: "apple", Name: "is red"}
+	if got, want := result.tests[testIdentifier(apple)].Duration, 500*time.Millisecond; got != want {
+		t.Errorf("Custom.Run(%q) duration of %q = %v, want %v", testCases, apple.Name, got, want)
+	}
+
+	sweet := result.tests[testIdentifier(wantFailedTests[0])]
+	if got, want := sweet.FailureMessage, "expected sweet, got sour"; got != want {
+		t.Errorf("Custom.Run(%q) failure message of %q = %q, want %q", testCases, sweet.Name, got, want)
+	}
+
+	if got, want := sweet.Location(), "fruits/apple.fruit:5"; got != want {
+		t.Errorf("Custom.Run(%q) location of %q = %q, want %q", testCases, sweet.Name, got, want)
+	}
+
+	// The failed test is retried, and passes.
+	err = custom.Run(result, result.FailedTests(), true)
+
+	if err != nil {
+		t.Errorf("Custom.Run(%q) error = %v", testCases, err)
+	}
+
+	if result.Status() != RunStatusPassed {
+		t.Errorf("Custom.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusPassed)
+	}
+
+	wantStatistics := RunStatistics{
+		Total:            3,
+		PassedOnFirstRun: 1,
+		PassedOnRetry:    1,
+	}
+
+	if diff := cmp.Diff(result.Statistics(), wantStatistics); diff != "" {
+		t.Errorf("Custom.Run(%q) RunResult.Statistics() diff (-got +want):\n%s", testCases, diff)
+	}
+
+	wantAttempts := []TestAttempt{
+		{Status: TestStatusFailed, FailureMessage: "expected sweet, got sour"},
+		{Status: TestStatusPassed},
+	}
+
+	if diff := cmp.Diff(sweet.Attempts, wantAttempts); diff != "" {
+		t.Errorf("Custom.Run(%q) attempts of %q diff (-got +want):\n%s", testCases, sweet.Name, diff)
+	}
+}
+
+func TestCustomRun_AdapterError(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: `sh -c 'cat > /dev/null; echo "{\"error\": \"fruit basket is empty\"}"'`,
+	})
+
+	testCases := []plan.TestCase{}
+	result := NewRunResult([]plan.TestCase{})
+	err := custom.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("Custom.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	if err == nil || !strings.Contains(err.Error(), "fruit basket is empty") {
+		t.Errorf("Custom.Run(%q) error = %v, want error containing %q", testCases, err, "fruit basket is empty")
+	}
+}
+
+func TestCustomRun_InvalidResponse(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "sh -c 'cat > /dev/null; echo not json'",
+	})
+
+	testCases := []plan.TestCase{}
+	result := NewRunResult([]plan.TestCase{})
+	err := custom.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("Custom.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	if err == nil || !strings.Contains(err.Error(), "failed to parse custom runner response") {
+		t.Errorf("Custom.Run(%q) error = %v, want parse error", testCases, err)
+	}
+}
+
+func TestCustomRun_CommandFailed(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "false",
+	})
+
+	testCases := []plan.TestCase{}
+	result := NewRunResult([]plan.TestCase{})
+	err := custom.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("Custom.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	exitError := new(exec.ExitError)
+	if !errors.As(err, &exitError) {
+		t.Errorf("Custom.Run(%q) error type = %T (%v), want *exec.ExitError", testCases, err, err)
+	}
+}
+
+func TestCustomRun_SignaledError(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "./testdata/segv.sh",
+	})
+
+	testCases := []plan.TestCase{}
+	result := NewRunResult([]plan.TestCase{})
+	err := custom.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("Custom.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	signalError := new(ProcessSignaledError)
+	if !errors.As(err, &signalError) {
+		t.Errorf("Custom.Run(%q) error type = %T (%v), want *ErrProcessSignaled", testCases, err, err)
+	}
+}
@@ -1,10 +1,15 @@
 package runner
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/buildkite/test-engine-client/internal/debug"
 	"github.com/buildkite/test-engine-client/internal/plan"
@@ -33,23 +38,196 @@ func NewCypress(c RunnerConfig) Cypress {
 	}
 }
 
+// Run executes the test command with the given test cases.
+// When the ResultPath is set, the mochawesome JSON reports matching the ResultPath are parsed
+// and each test is recorded in the RunResult, allowing failed specs to be retried.
+// When the ResultPath is not set, the result of the run is determined by the exit status of the command.
+//
+// Because Cypress runs tests at the spec level, retrying a test reruns the whole spec file,
+// but only the results of the retried tests are recorded.
 func (c Cypress) Run(result *RunResult, testCases []plan.TestCase, retry bool) error {
-	testPaths := make([]string, len(testCases))
-	for i, tc := range testCases {
-		testPaths[i] = tc.Path
+	testPaths := []string{}
+	for _, tc := range testCases {
+		if !slices.Contains(testPaths, tc.Path) {
+			testPaths = append(testPaths, tc.Path)
+		}
 	}
+
 	cmdName, cmdArgs, err := c.commandNameAndArgs(c.TestCommand, testPaths)
 	if err != nil {
 		result.err = err
 		return fmt.Errorf("failed to build command: %w", err)
 	}
 
+	if c.ResultPath != "" {
+		// Cypress runs each spec file separately, and most reporters write a report per spec file.
+		// Remove the reports from the previous run so they are not mistaken for the results of this run.
+		if err := c.removeReports(); err != nil {
+			result.err = err
+			return err
+		}
+	}
+
 	cmd := exec.Command(cmdName, cmdArgs...)
 
-	err = runAndForwardSignal(cmd)
+	err = c.run(cmd)
+
+	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
+		result.err = err
+		return err
+	}
+
+	if c.ResultPath == "" {
+		result.err = err
+		return err
+	}
+
+	reports, parseErr := c.ParseReports(c.ResultPath)
+	if parseErr != nil {
+		fmt.Println("Buildkite Test Engine Client: Failed to read Cypress output, tests will not be retried.")
+		result.err = err
+		return err
+	}
+
+	retriedTests := map[string]bool{}
+	if retry {
+		for _, tc := range testCases {
+			retriedTests[testIdentifier(tc)] = true
+		}
+	}
+
+	for _, report := range reports {
+		for _, suite := range report.Results {
+			for _, testResult := range c.getTestResultsFromSuite(suite, suite.File, []string{}) {
+				if retry && !retriedTests[testIdentifier(testResult.TestCase)] {
+					continue
+				}
+				result.recordTestResult(testResult)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getTestResultsFromSuite recursively traverses the mochawesome suite and returns the results of all tests.
+// Only the root suite carries the spec file, therefore the file is passed down to the sub-suites.
+func (c Cypress) getTestResultsFromSuite(suite CypressReportSuite, file string, ancestorTitles []string) []TestResult {
+	var testResults []TestResult
+
+	if suite.Title != "" {
+		ancestorTitles = append(slices.Clone(ancestorTitles), suite.Title)
+	}
+
+	for _, test := range suite.Tests {
+		var status TestStatus
+		switch test.State {
+		case "failed":
+			status = TestStatusFailed
+		case "passed":
+			status = TestStatusPassed
+		case "pending":
+			status = TestStatusPending
+		}
+
+		testResults = append(testResults, TestResult{
+			TestCase: plan.TestCase{
+				// The scope and name has to match with the scope generated by Buildkite test collector.
+				// In Buildkite test collector, the scope is the full title of the parent suite,
+				// i.e. the titles of all the describe blocks joined with a space.
+				Scope: strings.Join(ancestorTitles, " "),
+				Name:  test.Title,
+				Path:  file,
+			},
+			Status:         status,
+			Duration:       time.Duration(test.Duration * float64(time.Millisecond)),
+			FailureMessage: test.Err.Message,
+			File:           file,
+		})
+	}
+
+	for _, subSuite := range suite.Suites {
+		testResults = append(testResults, c.getTestResultsFromSuite(subSuite, file, ancestorTitles)...)
+	}
+
+	return testResults
+}
+
+// CypressTest represents a single test in a mochawesome report.
+type CypressTest struct {
+	Title     string `json:"title"`
+	FullTitle string `json:"fullTitle"`
+	State     string `json:"state"`
+	// Duration is the duration of the test in milliseconds.
+	Duration float64 `json:"duration"`
+	Err      struct {
+		Message string `json:"message"`
+	} `json:"err"`
+}
+
+// CypressReportSuite represents a suite in a mochawesome report.
+// The root suite of each result is the spec file, and its title is empty.
+type CypressReportSuite struct {
+	Title  string               `json:"title"`
+	File   string               `json:"file"`
+	Tests  []CypressTest        `json:"tests"`
+	Suites []CypressReportSuite `json:"suites"`
+}
+
+// CypressReport is the structure for mochawesome JSON report.
+type CypressReport struct {
+	Stats struct {
+		Tests    int `json:"tests"`
+		Passes   int `json:"passes"`
+		Failures int `json:"failures"`
+	} `json:"stats"`
+	Results []CypressReportSuite `json:"results"`
+}
+
+// ParseReports reads and parses all mochawesome JSON reports matching the given glob pattern.
+func (c Cypress) ParseReports(pattern string) ([]CypressReport, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cypress output: %v", err)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("failed to read cypress output: no files found with pattern %q", pattern)
+	}
+
+	var reports []CypressReport
+	for _, path := range paths {
+		var report CypressReport
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cypress output: %v", err)
+		}
+
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, fmt.Errorf("failed to parse cypress output: %s", err)
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// removeReports removes the reports matching the ResultPath.
+func (c Cypress) removeReports() error {
+	paths, err := filepath.Glob(c.ResultPath)
+	if err != nil {
+		return fmt.Errorf("invalid result path %q: %v", c.ResultPath, err)
+	}
+
+	for _, path := range paths {
+		debug.Println("Removing previous Cypress report", path)
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove previous cypress output: %v", err)
+		}
+	}
 
-	result.err = err
-	return err
+	return nil
 }
 
 func (c Cypress) GetFiles() ([]string, error) {
@@ -2,9 +2,14 @@ package runner
 
 import (
 	"errors"
+	"fmt"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/buildkite/test-engine-client/internal/plan"
 	"github.com/google/go-cmp/cmp"
@@ -100,6 +105,136 @@ func TestCypressRun_SignaledError(t *testing.T) {
 	}
 }
 
+func TestCypressRun_WithResultPath(t *testing.T) {
+	dir := t.TempDir()
+
+	// Simulate Cypress writing a mochawesome report for each spec file.
+	cypress := NewCypress(RunnerConfig{
+		TestCommand: fmt.Sprintf(`sh -c 'cp "$0" "$1" "$2"' ./testdata/cypress/mochawesome/failing_spec.json ./testdata/cypress/mochawesome/passing_spec.json %s`, dir),
+		ResultPath:  filepath.Join(dir, "*.json"),
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "cypress/e2e/failing_spec.cy.js"},
+		{Path: "cypress/e2e/passing_spec.cy.js"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := cypress.Run(result, testCases, false)
+
+	if err != nil {
+		t.Errorf("Cypress.Run(%q) error = %v", testCases, err)
+	}
+
+	if result.Status() != RunStatusFailed {
+		t.Errorf("Cypress.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusFailed)
+	}
+
+	if len(result.tests) != 4 {
+		t.Errorf("Cypress.Run(%q) len(RunResult.tests) = %d, want 4", testCases, len(result.tests))
+	}
+
+	wantFailedTests := []plan.TestCase{
+		{Scope: "Failing spec", Name: "fails", Path: "cypress/e2e/failing_spec.cy.js"},
+	}
+
+	if diff := cmp.Diff(result.FailedTests(), wantFailedTests); diff != "" {
+		t.Errorf("Cypress.Run(%q) RunResult.FailedTests() diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestCypressRun_Retry(t *testing.T) {
+	dir := t.TempDir()
+
+	// The passing spec report contains tests that are not being retried.
+	cypress := NewCypress(RunnerConfig{
+		TestCommand: fmt.Sprintf(`sh -c 'cp "$0" "$1"' ./testdata/cypress/mochawesome/passing_spec.json %s`, dir),
+		ResultPath:  filepath.Join(dir, "*.json"),
+	})
+
+	testCases := []plan.TestCase{
+		{Scope: "Passing spec greeting", Name: "says hello", Path: "cypress/e2e/passing_spec.cy.js"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := cypress.Run(result, testCases, true)
+
+	if err != nil {
+		t.Errorf("Cypress.Run(%q) error = %v", testCases, err)
+	}
+
+	if result.Status() != RunStatusPassed {
+		t.Errorf("Cypress.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusPassed)
+	}
+
+	if len(result.tests) != 1 {
+		t.Errorf("Cypress.Run(%q) len(RunResult.tests) = %d, want 1", testCases, len(result.tests))
+	}
+}
+
+func TestCypressParseReports(t *testing.T) {
+	cypress := NewCypress(RunnerConfig{})
+
+	reports, err := cypress.ParseReports("./testdata/cypress/mochawesome/*.json")
+	if err != nil {
+		t.Errorf("Cypress.ParseReports() error = %v", err)
+	}
+
+	var got []TestResult
+	for _, report := range reports {
+		for _, suite := range report.Results {
+			got = append(got, cypress.getTestResultsFromSuite(suite, suite.File, []string{})...)
+		}
+	}
+
+	want := []TestResult{
+		{
+			TestCase:       plan.TestCase{Scope: "Failing spec", Name: "fails", Path: "cypress/e2e/failing_spec.cy.js"},
+			Status:         TestStatusFailed,
+			Duration:       12 * time.Millisecond,
+			FailureMessage: "AssertionError: expected true to be false",
+			File:           "cypress/e2e/failing_spec.cy.js",
+		},
+		{
+			TestCase: plan.TestCase{Scope: "Passing spec", Name: "has a title", Path: "cypress/e2e/passing_spec.cy.js"},
+			Status:   TestStatusPassed,
+			Duration: 87 * time.Millisecond,
+			File:     "cypress/e2e/passing_spec.cy.js",
+		},
+		{
+			TestCase: plan.TestCase{Scope: "Passing spec greeting", Name: "says hello", Path: "cypress/e2e/passing_spec.cy.js"},
+			Status:   TestStatusPassed,
+			Duration: 45 * time.Millisecond,
+			File:     "cypress/e2e/passing_spec.cy.js",
+		},
+		{
+			TestCase: plan.TestCase{Scope: "Passing spec greeting", Name: "says goodbye", Path: "cypress/e2e/passing_spec.cy.js"},
+			Status:   TestStatusPending,
+			File:     "cypress/e2e/passing_spec.cy.js",
+		},
+	}
+
+	// Sort the test results by scope and name when comparing
+	sorter := cmp.Transformer("Sort", func(in []TestResult) []TestResult {
+		out := append([]TestResult(nil), in...) // Copy input to avoid mutating it
+		slices.SortFunc(out, func(a, b TestResult) int {
+			return strings.Compare(a.Scope+"/"+a.Name, b.Scope+"/"+b.Name)
+		})
+		return out
+	})
+
+	if diff := cmp.Diff(got, want, sorter); diff != "" {
+		t.Errorf("Cypress.ParseReports() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestCypressParseReports_NoReports(t *testing.T) {
+	cypress := NewCypress(RunnerConfig{})
+
+	_, err := cypress.ParseReports("./testdata/cypress/mochawesome/*.xml")
+	if err == nil {
+		t.Errorf("Cypress.ParseReports() error = nil, want error")
+	}
+}
+
 func TestCypressGetFiles(t *testing.T) {
 	cypress := NewCypress(RunnerConfig{})
 
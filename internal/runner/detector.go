@@ -2,6 +2,7 @@ package runner
 
 import (
 	"errors"
+	"io"
 
 	"github.com/buildkite/test-engine-client/internal/config"
 	"github.com/buildkite/test-engine-client/internal/plan"
@@ -14,6 +15,11 @@ type RunnerConfig struct {
 	TestFileExcludePattern string
 	RetryTestCommand       string
 	ResultPath             string
+	// Env is the additional environment of the test command, in the form of "key=value".
+	Env []string
+	// Output is where the output of the test command is written.
+	// The output is written to stdout and stderr when it's nil.
+	Output io.Writer
 }
 
 type TestRunner interface {
@@ -24,7 +30,35 @@ type TestRunner interface {
 }
 
 func DetectRunner(cfg config.Config) (TestRunner, error) {
-	var runnerConfig = RunnerConfig{
+	runnerConfig := newRunnerConfig(cfg)
+
+	newRunner, err := runnerConstructor(cfg.TestRunner)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.LocalConcurrency > 1 {
+		return NewConcurrent(runnerConfig, cfg.LocalConcurrency, newRunner), nil
+	}
+
+	return newRunner(runnerConfig), nil
+}
+
+// EffectiveConfig returns the config of the runner of the configuration,
+// with the default test command, retry command and test file pattern of the runner
+// in place of the ones that are not configured.
+func EffectiveConfig(cfg config.Config) (RunnerConfig, error) {
+	newRunner, err := runnerConstructor(cfg.TestRunner)
+	if err != nil {
+		return RunnerConfig{}, err
+	}
+
+	// Every runner embeds its RunnerConfig, see RunnerConfig.runnerConfig.
+	return newRunner(newRunnerConfig(cfg)).(interface{ runnerConfig() RunnerConfig }).runnerConfig(), nil
+}
+
+func newRunnerConfig(cfg config.Config) RunnerConfig {
+	return RunnerConfig{
 		TestRunner:             cfg.TestRunner,
 		TestCommand:            cfg.TestCommand,
 		TestFilePattern:        cfg.TestFilePattern,
@@ -32,17 +66,35 @@ func DetectRunner(cfg config.Config) (TestRunner, error) {
 		RetryTestCommand:       cfg.RetryCommand,
 		ResultPath:             cfg.ResultPath,
 	}
+}
+
+// runnerConfig returns the config of the runner embedding it.
+func (r RunnerConfig) runnerConfig() RunnerConfig {
+	return r
+}
 
-	switch cfg.TestRunner {
+// runnerConstructor returns the function creating the runner with the given name.
+func runnerConstructor(name string) (func(RunnerConfig) TestRunner, error) {
+	switch name {
 	case "rspec":
-		return NewRspec(runnerConfig), nil
+		return func(c RunnerConfig) TestRunner { return NewRspec(c) }, nil
 	case "jest":
-		return NewJest(runnerConfig), nil
+		return func(c RunnerConfig) TestRunner { return NewJest(c) }, nil
 	case "cypress":
-		return NewCypress(runnerConfig), nil
+		return func(c RunnerConfig) TestRunner { return NewCypress(c) }, nil
 	case "playwright":
-		return NewPlaywright(runnerConfig), nil
+		return func(c RunnerConfig) TestRunner { return NewPlaywright(c) }, nil
+	case "pytest":
+		return func(c RunnerConfig) TestRunner { return NewPytest(c) }, nil
+	case "gotest":
+		return func(c RunnerConfig) TestRunner { return NewGoTest(c) }, nil
+	case "junit":
+		return func(c RunnerConfig) TestRunner { return NewJUnit(c) }, nil
+	case "vitest":
+		return func(c RunnerConfig) TestRunner { return NewVitest(c) }, nil
+	case "custom":
+		return func(c RunnerConfig) TestRunner { return NewCustom(c) }, nil
 	default:
-		return nil, errors.New("runner value is invalid, possible values are 'rspec', 'jest', 'cypress', 'playwright'")
+		return nil, errors.New("runner value is invalid, possible values are 'rspec', 'jest', 'cypress', 'playwright', 'pytest', 'gotest', 'junit', 'vitest', 'custom'")
 	}
 }
@@ -0,0 +1,40 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	cfg := config.Config{
+		TestRunner:       "jest",
+		TestCommand:      "yarn jest {{testExamples}} --json --outputFile {{resultPath}}",
+		ResultPath:       "tmp/jest.json",
+		LocalConcurrency: 2,
+	}
+
+	got, err := EffectiveConfig(cfg)
+	if err != nil {
+		t.Fatalf("EffectiveConfig() error = %v", err)
+	}
+
+	want := RunnerConfig{
+		TestRunner:       "jest",
+		TestCommand:      "yarn jest {{testExamples}} --json --outputFile {{resultPath}}",
+		TestFilePattern:  "**/{__tests__/**/*,*.spec,*.test}.{ts,js,tsx,jsx}",
+		RetryTestCommand: "npx jest --testNamePattern '{{testNamePattern}}' --json --testLocationInResults --outputFile {{resultPath}}",
+		ResultPath:       "tmp/jest.json",
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("EffectiveConfig() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestEffectiveConfig_UnknownRunner(t *testing.T) {
+	if _, err := EffectiveConfig(config.Config{TestRunner: "mocha"}); err == nil {
+		t.Errorf("EffectiveConfig() error = nil, want an error")
+	}
+}
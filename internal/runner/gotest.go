@@ -0,0 +1,588 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/debug"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/kballard/go-shellquote"
+)
+
+// GoTest is the runner for the native `go test` tool.
+// Unlike the other runners, it works with packages rather than files.
+type GoTest struct {
+	RunnerConfig
+}
+
+func NewGoTest(g RunnerConfig) GoTest {
+	if g.TestCommand == "" {
+		g.TestCommand = "go test -json {{testExamples}}"
+	}
+
+	if g.TestFilePattern == "" {
+		g.TestFilePattern = "**/*_test.go"
+	}
+
+	if g.RetryTestCommand == "" {
+		g.RetryTestCommand = "go test -json {{testExamples}} -run {{testNamePattern}}"
+	}
+
+	return GoTest{
+		RunnerConfig: g,
+	}
+}
+
+func (g GoTest) Name() string {
+	return "go test"
+}
+
+// GetFiles returns an array of packages containing the test files found using the discovery pattern.
+// The packages are returned as relative paths, e.g. "./internal/api", which can be passed to `go test`.
+func (g GoTest) GetFiles() ([]string, error) {
+	debug.Println("Discovering test files with include pattern:", g.TestFilePattern, "exclude pattern:", g.TestFileExcludePattern)
+	files, err := discoverTestFiles(g.TestFilePattern, g.TestFileExcludePattern)
+	debug.Println("Discovered", len(files), "files")
+
+	if err != nil {
+		return nil, err
+	}
+
+	packages := []string{}
+	for _, file := range files {
+		dir := filepath.ToSlash(filepath.Dir(file))
+		if isIgnoredGoDir(dir) {
+			continue
+		}
+
+		pkg := "./" + dir
+		if dir == "." {
+			pkg = "."
+		}
+
+		if !slices.Contains(packages, pkg) {
+			packages = append(packages, pkg)
+		}
+	}
+
+	debug.Println("Discovered", len(packages), "packages")
+
+	if len(packages) == 0 {
+		return nil, fmt.Errorf("no files found with pattern %q and exclude pattern %q", g.TestFilePattern, g.TestFileExcludePattern)
+	}
+
+	return packages, nil
+}
+
+// isIgnoredGoDir reports whether the go tool ignores the directory when matching packages,
+// i.e. the directory is inside testdata or vendor, or a directory starting with "." or "_".
+func isIgnoredGoDir(dir string) bool {
+	for _, elem := range strings.Split(dir, "/") {
+		if elem == "testdata" || elem == "vendor" || (elem != "." && (strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes the test command with the given test cases.
+// If retry is true, the failed tests are run using the retry test command
+// with a `-run '^(TestA|TestB)$'` expression, once for each package.
+// Test cases in example format are run in the same way using the test command.
+//
+// Error is returned if the command fails to run, exits prematurely, or if the
+// output cannot be parsed.
+//
+// Test failure is not considered an error, and is instead returned as a RunResult.
+func (g GoTest) Run(result *RunResult, testCases []plan.TestCase, retry bool) error {
+	if retry {
+		// Retrying a subtest runs its top level test, which runs the other subtests too.
+		// Only the results of the retried tests are recorded.
+		retriedTests := map[string]bool{}
+		for _, tc := range testCases {
+			retriedTests[testIdentifier(tc)] = true
+		}
+
+		packages, testNamesByPackage := groupGoTestsByPackage(testCases)
+		for _, pkg := range packages {
+			commandName, commandArgs, err := g.retryCommandNameAndArgs(g.RetryTestCommand, pkg, testNamesByPackage[pkg])
+			if err != nil {
+				result.err = err
+				return fmt.Errorf("failed to build command: %w", err)
+			}
+
+			if err := g.runAndRecord(result, exec.Command(commandName, commandArgs...), retriedTests); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var testPaths []string
+	var examples []plan.TestCase
+	for _, testCase := range testCases {
+		if testCase.Format == plan.TestCaseFormatExample {
+			examples = append(examples, testCase)
+			continue
+		}
+		testPaths = append(testPaths, testCase.Path)
+	}
+
+	if len(testPaths) > 0 || len(examples) == 0 {
+		commandName, commandArgs, err := g.commandNameAndArgs(g.TestCommand, testPaths)
+		if err != nil {
+			result.err = err
+			return fmt.Errorf("failed to build command: %w", err)
+		}
+
+		if err := g.runAndRecord(result, exec.Command(commandName, commandArgs...), nil); err != nil {
+			return err
+		}
+	}
+
+	// The -run expression applies to every package given to `go test`,
+	// therefore the examples of each package are run separately.
+	packages, testNamesByPackage := groupGoTestsByPackage(examples)
+	for _, pkg := range packages {
+		commandName, commandArgs, err := g.commandNameAndArgs(g.TestCommand, []string{pkg})
+		if err != nil {
+			result.err = err
+			return fmt.Errorf("failed to build command: %w", err)
+		}
+		commandArgs = append(commandArgs, "-run", goTestRunExpression(testNamesByPackage[pkg]))
+
+		if err := g.runAndRecord(result, exec.Command(commandName, commandArgs...), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runAndRecord runs the command, writes the `go test -json` event stream to the result path,
+// and records the test results, or only those of the retried tests when retriedTests is not nil.
+// Only the output of the tests is printed to the output of the runner, so the log reads the same as a plain `go test -v` run.
+func (g GoTest) runAndRecord(result *RunResult, cmd *exec.Cmd, retriedTests map[string]bool) error {
+	f, err := os.Create(g.ResultPath)
+	if err != nil {
+		result.err = err
+		return fmt.Errorf("failed to create go test output file: %w", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if g.Output != nil {
+		out = g.Output
+	}
+	printer := &goTestOutputPrinter{w: out}
+	cmd.Stdout = io.MultiWriter(f, printer)
+
+	err = g.run(cmd)
+	printer.Flush()
+	f.Close()
+
+	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
+		result.err = err
+		return err
+	}
+
+	report, parseErr := g.ParseReport(g.ResultPath)
+	if parseErr != nil {
+		fmt.Println("Buildkite Test Engine Client: Failed to read go test output, tests will not be retried.")
+		result.err = err
+		return err
+	}
+
+	// If the command failed but no test failed, there is nothing to retry,
+	// therefore we need to bubble up the error.
+	if err != nil && !slices.ContainsFunc(report.TestResults, func(r TestResult) bool { return r.Status == TestStatusFailed }) {
+		fmt.Println("Buildkite Test Engine Client: go test failed without any failed test, tests will not be retried.")
+		result.err = err
+		return err
+	}
+
+	// A package that failed without any failed test, e.g. it failed to build, can't be retried
+	// even if the tests of other packages failed, therefore we need to bubble up the error too.
+	if len(report.FailedPackages) > 0 {
+		fmt.Printf("Buildkite Test Engine Client: go test failed in %s without any failed test, tests will not be retried.\n", strings.Join(report.FailedPackages, ", "))
+		if err == nil {
+			err = fmt.Errorf("go test failed in %s", strings.Join(report.FailedPackages, ", "))
+		}
+		result.err = err
+		return err
+	}
+
+	modulePath, moduleDir := findGoModule()
+	for _, testResult := range report.TestResults {
+		if retriedTests != nil && !retriedTests[testIdentifier(testResult.TestCase)] {
+			continue
+		}
+		testResult.File = goPackageDir(modulePath, moduleDir, testResult.Path)
+		testResult.TestFile = testResult.File
+		result.recordTestResult(testResult)
+	}
+
+	return nil
+}
+
+// findGoModule returns the path and directory of the module containing the working directory,
+// or empty strings if there is none.
+func findGoModule() (modulePath string, moduleDir string) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", ""
+	}
+
+	for {
+		if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) > 1 && fields[0] == "module" {
+					return strings.Trim(fields[1], `"`), dir
+				}
+			}
+			return "", ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// goPackageDir returns the directory of the package with the import path, relative to the working directory,
+// in the same form as GetFiles, e.g. "./internal/api" for "github.com/buildkite/test-engine-client/internal/api".
+// An empty string is returned if the package is not in the module.
+func goPackageDir(modulePath, moduleDir, importPath string) string {
+	if modulePath == "" {
+		return ""
+	}
+
+	var rel string
+	switch {
+	case importPath == modulePath:
+		rel = "."
+	case strings.HasPrefix(importPath, modulePath+"/"):
+		rel = strings.TrimPrefix(importPath, modulePath+"/")
+	default:
+		return ""
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	dir, err := filepath.Rel(cwd, filepath.Join(moduleDir, filepath.FromSlash(rel)))
+	if err != nil {
+		return ""
+	}
+
+	dir = filepath.ToSlash(dir)
+	if dir == "." || strings.HasPrefix(dir, "../") {
+		return dir
+	}
+	return "./" + dir
+}
+
+// GoTestEvent represents a single event in the `go test -json` output.
+// For more details, see `go doc test2json`.
+type GoTestEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Elapsed float64 `json:"Elapsed"`
+	Output  string  `json:"Output"`
+}
+
+// GoTestReport is the result of the `go test -json` event stream.
+type GoTestReport struct {
+	TestResults []TestResult
+	// FailedPackages are the packages that failed without any failed test,
+	// e.g. because they failed to build or their TestMain failed.
+	FailedPackages []string
+}
+
+// ParseReport reads the `go test -json` event stream at the given path and returns the test results.
+// When a test has subtests, only the subtests are returned, because the result of
+// the parent test is derived from its subtests. A parent test that fails on its own,
+// while all its subtests pass, is returned as failed.
+func (g GoTest) ParseReport(path string) (GoTestReport, error) {
+	var report GoTestReport
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, fmt.Errorf("failed to read go test output: %v", err)
+	}
+
+	var testResults []TestResult
+	// failedPackages are the packages that failed, in order, and packagesWithFailedTests those with a failed test.
+	var failedPackages []string
+	packagesWithFailedTests := map[string]bool{}
+	// parents maps the identifier of each parent test to whether any of its subtests failed.
+	parents := map[string]bool{}
+	// outputs maps the identifier of each test to its output, which is the failure message when the test fails.
+	outputs := map[string]*strings.Builder{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		// Lines that are not JSON, e.g. build errors in older versions of Go, are ignored.
+		if !bytes.HasPrefix(line, []byte("{")) {
+			continue
+		}
+
+		var event GoTestEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return report, fmt.Errorf("failed to parse go test output: %v", err)
+		}
+
+		if event.Test == "" {
+			if event.Action == "fail" && !slices.Contains(failedPackages, event.Package) {
+				failedPackages = append(failedPackages, event.Package)
+			}
+			continue
+		}
+
+		identifier := event.Package + "/" + event.Test
+
+		var status TestStatus
+		switch event.Action {
+		case "output":
+			// The "=== RUN" and "--- FAIL" frames are not part of the failure message.
+			if strings.HasPrefix(event.Output, "=== ") || strings.HasPrefix(event.Output, "--- ") {
+				continue
+			}
+			if outputs[identifier] == nil {
+				outputs[identifier] = &strings.Builder{}
+			}
+			outputs[identifier].WriteString(event.Output)
+			continue
+		case "pass":
+			status = TestStatusPassed
+		case "fail":
+			status = TestStatusFailed
+		case "skip":
+			status = TestStatusPending
+		default:
+			continue
+		}
+
+		if status == TestStatusFailed {
+			packagesWithFailedTests[event.Package] = true
+		}
+
+		if idx := strings.LastIndex(event.Test, "/"); idx >= 0 {
+			parent := event.Package + "/" + event.Test[:idx]
+			parents[parent] = parents[parent] || status == TestStatusFailed
+		}
+
+		testResult := TestResult{
+			TestCase: mapGoTestEventToTestCase(event),
+			Status:   status,
+			Duration: secondsToDuration(event.Elapsed),
+		}
+		if status == TestStatusFailed && outputs[identifier] != nil {
+			testResult.FailureMessage = strings.TrimSpace(outputs[identifier].String())
+		}
+
+		testResults = append(testResults, testResult)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("failed to read go test output: %v", err)
+	}
+
+	report.TestResults = slices.DeleteFunc(testResults, func(r TestResult) bool {
+		subtestFailed, isParent := parents[r.Identifier]
+		return isParent && (r.Status != TestStatusFailed || subtestFailed)
+	})
+
+	for _, pkg := range failedPackages {
+		if !packagesWithFailedTests[pkg] {
+			report.FailedPackages = append(report.FailedPackages, pkg)
+		}
+	}
+
+	return report, nil
+}
+
+func mapGoTestEventToTestCase(event GoTestEvent) plan.TestCase {
+	// The parent tests of a subtest form the scope, e.g. "TestFruit/apple/red"
+	// has "<package>/TestFruit/apple" as scope and "red" as name.
+	scope, name := event.Package, event.Test
+	if idx := strings.LastIndex(event.Test, "/"); idx >= 0 {
+		scope, name = event.Package+"/"+event.Test[:idx], event.Test[idx+1:]
+	}
+
+	return plan.TestCase{
+		Identifier: event.Package + "/" + event.Test,
+		Name:       name,
+		Path:       event.Package,
+		Scope:      scope,
+	}
+}
+
+// groupGoTestsByPackage groups the top level test names of the test cases by package.
+// The subtests are run by running their top level test.
+func groupGoTestsByPackage(testCases []plan.TestCase) ([]string, map[string][]string) {
+	var packages []string
+	testNamesByPackage := map[string][]string{}
+
+	for _, testCase := range testCases {
+		testName := strings.TrimPrefix(testCase.Scope+"/"+testCase.Name, testCase.Path+"/")
+		testName, _, _ = strings.Cut(testName, "/")
+
+		if _, ok := testNamesByPackage[testCase.Path]; !ok {
+			packages = append(packages, testCase.Path)
+		}
+		if !slices.Contains(testNamesByPackage[testCase.Path], testName) {
+			testNamesByPackage[testCase.Path] = append(testNamesByPackage[testCase.Path], testName)
+		}
+	}
+
+	return packages, testNamesByPackage
+}
+
+// goTestRunExpression returns a `-run` expression matching exactly the given top level tests.
+func goTestRunExpression(testNames []string) string {
+	return "^" + testNamePattern(testNames) + "$"
+}
+
+// commandNameAndArgs replaces the "{{testExamples}}" placeholder in the test command with the packages.
+// It returns the command name and arguments to run the tests.
+func (g GoTest) commandNameAndArgs(cmd string, packages []string) (string, []string, error) {
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return "", []string{}, err
+	}
+
+	idx := slices.Index(words, "{{testExamples}}")
+	if idx < 0 {
+		words = append(words, packages...)
+	} else {
+		words = slices.Replace(words, idx, idx+1, packages...)
+	}
+
+	return words[0], words[1:], nil
+}
+
+// retryCommandNameAndArgs replaces the "{{testExamples}}" placeholder with the package,
+// and the "{{testNamePattern}}" placeholder with a `-run` expression matching the given tests.
+func (g GoTest) retryCommandNameAndArgs(cmd string, pkg string, testNames []string) (string, []string, error) {
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return "", []string{}, err
+	}
+
+	idx := slices.Index(words, "{{testNamePattern}}")
+	if idx < 0 {
+		err := fmt.Errorf("couldn't find '{{testNamePattern}}' sentinel in retry command")
+		return "", []string{}, err
+	}
+	words = slices.Replace(words, idx, idx+1, goTestRunExpression(testNames))
+
+	idx = slices.Index(words, "{{testExamples}}")
+	if idx < 0 {
+		words = append(words, pkg)
+	} else {
+		words = slices.Replace(words, idx, idx+1, pkg)
+	}
+
+	return words[0], words[1:], nil
+}
+
+var goTestListPattern = regexp.MustCompile(`^(Test|Example|Fuzz)\w*$`)
+
+// GetExamples returns an array of the top level tests within the given packages.
+// The tests are listed using `go test -list`, which doesn't run any test.
+func (g GoTest) GetExamples(packages []string) ([]plan.TestCase, error) {
+	cmdName, cmdArgs, err := g.commandNameAndArgs(g.TestCommand, packages)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdArgs = append(cmdArgs, "-list", ".")
+
+	debug.Printf("Running `%s %s` to list examples", cmdName, strings.Join(cmdArgs, " "))
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(cmdName, cmdArgs...)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to list go tests: %s%s", output, stderr.Bytes())
+	}
+
+	var testCases []plan.TestCase
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		var event GoTestEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("failed to parse go test output: %v", err)
+		}
+
+		testName := strings.TrimSpace(event.Output)
+		if event.Action != "output" || !goTestListPattern.MatchString(testName) {
+			continue
+		}
+
+		event.Test = testName
+		testCases = append(testCases, mapGoTestEventToTestCase(event))
+	}
+
+	return testCases, nil
+}
+
+// goTestOutputPrinter is an io.Writer that decodes the `go test -json` event stream
+// and writes the output of each event to w.
+// Lines that are not JSON are written as they are.
+type goTestOutputPrinter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (p *goTestOutputPrinter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+
+	for {
+		idx := bytes.IndexByte(p.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		p.printLine(p.buf[:idx+1])
+		p.buf = p.buf[idx+1:]
+	}
+
+	return len(b), nil
+}
+
+// Flush prints the remaining incomplete line, if any.
+func (p *goTestOutputPrinter) Flush() {
+	if len(p.buf) > 0 {
+		p.printLine(p.buf)
+		p.buf = nil
+	}
+}
+
+func (p *goTestOutputPrinter) printLine(line []byte) {
+	var event GoTestEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		p.w.Write(line)
+		return
+	}
+	io.WriteString(p.w, event.Output)
+}
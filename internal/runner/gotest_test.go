@@ -0,0 +1,543 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// changeGoTestCwd changes the working directory to the Go module in testdata.
+// The module is not part of any workspace, therefore workspace mode is turned off.
+func changeGoTestCwd(t *testing.T) {
+	t.Helper()
+	t.Setenv("GOWORK", "off")
+	changeCwd(t, "./testdata/gotest")
+}
+
+func TestNewGoTest(t *testing.T) {
+	cases := []struct {
+		input RunnerConfig
+		want  RunnerConfig
+	}{
+		//default
+		{
+			input: RunnerConfig{},
+			want: RunnerConfig{
+				TestCommand:            "go test -json {{testExamples}}",
+				TestFilePattern:        "**/*_test.go",
+				TestFileExcludePattern: "",
+				RetryTestCommand:       "go test -json {{testExamples}} -run {{testNamePattern}}",
+			},
+		},
+		// custom
+		{
+			input: RunnerConfig{
+				TestCommand:            "go test -json -race {{testExamples}}",
+				TestFilePattern:        "internal/**/*_test.go",
+				TestFileExcludePattern: "internal/e2e",
+				RetryTestCommand:       "go test -json -race -count 1 {{testExamples}} -run {{testNamePattern}}",
+			},
+			want: RunnerConfig{
+				TestCommand:            "go test -json -race {{testExamples}}",
+				TestFilePattern:        "internal/**/*_test.go",
+				TestFileExcludePattern: "internal/e2e",
+				RetryTestCommand:       "go test -json -race -count 1 {{testExamples}} -run {{testNamePattern}}",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		got := NewGoTest(c.input)
+		if diff := cmp.Diff(got.RunnerConfig, c.want); diff != "" {
+			t.Errorf("NewGoTest(%v) diff (-got +want):\n%s", c.input, diff)
+		}
+	}
+}
+
+func TestGoTestRun(t *testing.T) {
+	changeGoTestCwd(t)
+
+	goTest := NewGoTest(RunnerConfig{
+		ResultPath: "result.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(goTest.ResultPath)
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "./fruits"},
+		{Path: "./vegetables"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := goTest.Run(result, testCases, false)
+
+	if err != nil {
+		t.Errorf("GoTest.Run(%q) error = %v", testCases, err)
+	}
+
+	wantFailedTests := []plan.TestCase{
+		{
+			Identifier: "example.com/gotest/fruits/TestBanana/green",
+			Name:       "green",
+			Path:       "example.com/gotest/fruits",
+			Scope:      "example.com/gotest/fruits/TestBanana",
+		},
+	}
+
+	if diff := cmp.Diff(result.FailedTests(), wantFailedTests); diff != "" {
+		t.Errorf("GoTest.Run(%q) RunResult.FailedTests() diff (-got +want):\n%s", testCases, diff)
+	}
+
+	if got := result.Statistics().Total; got != 5 {
+		t.Errorf("GoTest.Run(%q) RunResult.Statistics().Total = %d, want %d", testCases, got, 5)
+	}
+
+	// The file of each test is the directory of its package, as returned by GetFiles.
+	for _, testResult := range result.TestResults() {
+		want := "./" + filepath.Base(testResult.Path)
+		if testResult.File != want {
+			t.Errorf("GoTest.Run(%q) TestResult.File of %s = %q, want %q", testCases, testResult.Identifier, testResult.File, want)
+		}
+		if got := testResult.TestFilePath(); got != want {
+			t.Errorf("GoTest.Run(%q) TestResult.TestFilePath() of %s = %q, want %q", testCases, testResult.Identifier, got, want)
+		}
+	}
+}
+
+func TestGoTestRun_Retry(t *testing.T) {
+	changeGoTestCwd(t)
+
+	goTest := NewGoTest(RunnerConfig{
+		ResultPath: "result.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(goTest.ResultPath)
+	})
+
+	testCases := []plan.TestCase{
+		{
+			Identifier: "example.com/gotest/fruits/TestBanana/green",
+			Name:       "green",
+			Path:       "example.com/gotest/fruits",
+			Scope:      "example.com/gotest/fruits/TestBanana",
+		},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := goTest.Run(result, testCases, true)
+
+	if err != nil {
+		t.Errorf("GoTest.Run(%q) error = %v", testCases, err)
+	}
+
+	// The whole TestBanana is retried, but only the result of TestBanana/green is recorded.
+	wantStatistics := RunStatistics{
+		Total:  1,
+		Failed: 1,
+	}
+
+	if diff := cmp.Diff(result.Statistics(), wantStatistics); diff != "" {
+		t.Errorf("GoTest.Run(%q) RunResult.Statistics() diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestGoTestRun_SignaledError(t *testing.T) {
+	goTest := NewGoTest(RunnerConfig{
+		TestCommand: "./testdata/segv.sh",
+		ResultPath:  "result.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(goTest.ResultPath)
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "./doesnt-matter"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := goTest.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("GoTest.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	signalError := new(ProcessSignaledError)
+	if !errors.As(err, &signalError) {
+		t.Errorf("GoTest.Run(%q) error type = %T (%v), want *ErrProcessSignaled", testCases, err, err)
+	}
+}
+
+func TestGoTestRun_CommandFailed(t *testing.T) {
+	goTest := NewGoTest(RunnerConfig{
+		TestCommand: "false",
+		ResultPath:  "result.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(goTest.ResultPath)
+	})
+
+	testCases := []plan.TestCase{}
+	result := NewRunResult([]plan.TestCase{})
+	err := goTest.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("GoTest.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	exitError := new(exec.ExitError)
+	if !errors.As(err, &exitError) {
+		t.Errorf("GoTest.Run(%q) error type = %T (%v), want *exec.ExitError", testCases, err, err)
+	}
+}
+
+func TestGoTestParseReport(t *testing.T) {
+	goTest := NewGoTest(RunnerConfig{})
+
+	report, err := goTest.ParseReport("./testdata/gotest/report.json")
+	if err != nil {
+		t.Errorf("GoTest.ParseReport() error = %v", err)
+	}
+	got := report.TestResults
+
+	want := []TestResult{
+		{
+			TestCase: plan.TestCase{
+				Identifier: "example.com/gotest/fruits/TestApple",
+				Name:       "TestApple",
+				Path:       "example.com/gotest/fruits",
+				Scope:      "example.com/gotest/fruits",
+			},
+			Status: TestStatusPassed,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "example.com/gotest/fruits/TestBanana/yellow",
+				Name:       "yellow",
+				Path:       "example.com/gotest/fruits",
+				Scope:      "example.com/gotest/fruits/TestBanana",
+			},
+			Status: TestStatusPassed,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "example.com/gotest/fruits/TestBanana/green",
+				Name:       "green",
+				Path:       "example.com/gotest/fruits",
+				Scope:      "example.com/gotest/fruits/TestBanana",
+			},
+			Status:         TestStatusFailed,
+			FailureMessage: "fruits_test.go:15: banana is not green",
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "example.com/gotest/vegetables/TestTomato",
+				Name:       "TestTomato",
+				Path:       "example.com/gotest/vegetables",
+				Scope:      "example.com/gotest/vegetables",
+			},
+			Status: TestStatusPassed,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "example.com/gotest/vegetables/TestPumpkin",
+				Name:       "TestPumpkin",
+				Path:       "example.com/gotest/vegetables",
+				Scope:      "example.com/gotest/vegetables",
+			},
+			Status: TestStatusPending,
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("GoTest.ParseReport() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestGoTestParseReport_ParentFailed(t *testing.T) {
+	report := `{"Action":"pass","Package":"example.com/gotest/fruits","Test":"TestCherry/red"}
+{"Action":"fail","Package":"example.com/gotest/fruits","Test":"TestCherry"}
+`
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, []byte(report), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	goTest := NewGoTest(RunnerConfig{})
+
+	got, err := goTest.ParseReport(path)
+	if err != nil {
+		t.Errorf("GoTest.ParseReport() error = %v", err)
+	}
+
+	// TestCherry fails after its subtest passed, so both are returned.
+	want := []TestResult{
+		{
+			TestCase: plan.TestCase{
+				Identifier: "example.com/gotest/fruits/TestCherry/red",
+				Name:       "red",
+				Path:       "example.com/gotest/fruits",
+				Scope:      "example.com/gotest/fruits/TestCherry",
+			},
+			Status: TestStatusPassed,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "example.com/gotest/fruits/TestCherry",
+				Name:       "TestCherry",
+				Path:       "example.com/gotest/fruits",
+				Scope:      "example.com/gotest/fruits",
+			},
+			Status: TestStatusFailed,
+		},
+	}
+
+	if diff := cmp.Diff(got.TestResults, want); diff != "" {
+		t.Errorf("GoTest.ParseReport() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestGoTestParseReport_FailedPackages(t *testing.T) {
+	report := `{"Action":"fail","Package":"example.com/gotest/fruits","Test":"TestBanana"}
+{"Action":"fail","Package":"example.com/gotest/fruits"}
+{"Action":"output","Package":"example.com/gotest/vegetables","Output":"FAIL\texample.com/gotest/vegetables [build failed]\n"}
+{"Action":"fail","Package":"example.com/gotest/vegetables"}
+{"Action":"pass","Package":"example.com/gotest/nuts"}
+`
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, []byte(report), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	goTest := NewGoTest(RunnerConfig{})
+
+	got, err := goTest.ParseReport(path)
+	if err != nil {
+		t.Errorf("GoTest.ParseReport() error = %v", err)
+	}
+
+	// The fruits package fails because of TestBanana, but the vegetables package fails on its own.
+	want := []string{"example.com/gotest/vegetables"}
+
+	if diff := cmp.Diff(got.FailedPackages, want); diff != "" {
+		t.Errorf("GoTest.ParseReport() FailedPackages diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestGoTestRun_PackageFailedWithFailedTests(t *testing.T) {
+	report := `{"Action":"fail","Package":"example.com/gotest/fruits","Test":"TestBanana"}
+{"Action":"fail","Package":"example.com/gotest/fruits"}
+{"Action":"fail","Package":"example.com/gotest/vegetables"}
+`
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, []byte(report), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	goTest := NewGoTest(RunnerConfig{
+		TestCommand: "cat " + path,
+		ResultPath:  filepath.Join(t.TempDir(), "result.json"),
+	})
+
+	testCases := []plan.TestCase{}
+	result := NewRunResult([]plan.TestCase{})
+	err := goTest.Run(result, testCases, false)
+
+	if err == nil {
+		t.Errorf("GoTest.Run(%q) error = nil, want an error", testCases)
+	}
+
+	if result.Status() != RunStatusError {
+		t.Errorf("GoTest.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+}
+
+func TestGoPackageDir(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		moduleDir  string
+		importPath string
+		want       string
+	}{
+		{moduleDir: cwd, importPath: "example.com/app", want: "."},
+		{moduleDir: cwd, importPath: "example.com/app/internal/api", want: "./internal/api"},
+		{moduleDir: filepath.Dir(cwd), importPath: "example.com/app/" + filepath.Base(cwd) + "/api", want: "./api"},
+		{moduleDir: cwd, importPath: "example.com/application", want: ""},
+		{moduleDir: cwd, importPath: "golang.org/x/mod", want: ""},
+	}
+
+	for _, tc := range cases {
+		got := goPackageDir("example.com/app", tc.moduleDir, tc.importPath)
+		if got != tc.want {
+			t.Errorf("goPackageDir(%q, %q, %q) = %q, want %q", "example.com/app", tc.moduleDir, tc.importPath, got, tc.want)
+		}
+	}
+}
+
+func TestGoTestRetryCommandNameAndArgs(t *testing.T) {
+	testNames := []string{"TestApple", "TestBanana"}
+	retryCommand := "go test -json {{testExamples}} -run {{testNamePattern}}"
+
+	goTest := NewGoTest(RunnerConfig{
+		RetryTestCommand: retryCommand,
+	})
+
+	gotName, gotArgs, err := goTest.retryCommandNameAndArgs(retryCommand, "example.com/gotest/fruits", testNames)
+	if err != nil {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) error = %v", retryCommand, testNames, err)
+	}
+
+	wantName := "go"
+	wantArgs := []string{"test", "-json", "example.com/gotest/fruits", "-run", "^(TestApple|TestBanana)$"}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) diff (-got +want):\n%s", retryCommand, testNames, diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) diff (-got +want):\n%s", retryCommand, testNames, diff)
+	}
+}
+
+func TestGoTestRetryCommandNameAndArgs_WithoutTestNamePatternPlaceholder(t *testing.T) {
+	testNames := []string{"TestApple"}
+	retryCommand := "go test -json {{testExamples}}"
+
+	goTest := NewGoTest(RunnerConfig{
+		RetryTestCommand: retryCommand,
+	})
+
+	gotName, gotArgs, err := goTest.retryCommandNameAndArgs(retryCommand, "example.com/gotest/fruits", testNames)
+
+	wantName := ""
+	wantArgs := []string{}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) diff (-got +want):\n%s", retryCommand, testNames, diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) diff (-got +want):\n%s", retryCommand, testNames, diff)
+	}
+
+	wantError := "couldn't find '{{testNamePattern}}' sentinel in retry command"
+	if err == nil || err.Error() != wantError {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) error = %v, want %q", retryCommand, testNames, err, wantError)
+	}
+}
+
+func TestGroupGoTestsByPackage(t *testing.T) {
+	testCases := []plan.TestCase{
+		{Name: "green", Path: "example.com/gotest/fruits", Scope: "example.com/gotest/fruits/TestBanana"},
+		{Name: "TestTomato", Path: "example.com/gotest/vegetables", Scope: "example.com/gotest/vegetables"},
+		{Name: "brown", Path: "example.com/gotest/fruits", Scope: "example.com/gotest/fruits/TestBanana"},
+		{Name: "red", Path: "example.com/gotest/fruits", Scope: "example.com/gotest/fruits/TestApple/fuji"},
+	}
+
+	gotPackages, gotTestNames := groupGoTestsByPackage(testCases)
+
+	wantPackages := []string{"example.com/gotest/fruits", "example.com/gotest/vegetables"}
+	wantTestNames := map[string][]string{
+		"example.com/gotest/fruits":     {"TestBanana", "TestApple"},
+		"example.com/gotest/vegetables": {"TestTomato"},
+	}
+
+	if diff := cmp.Diff(gotPackages, wantPackages); diff != "" {
+		t.Errorf("groupGoTestsByPackage(%v) packages diff (-got +want):\n%s", testCases, diff)
+	}
+	if diff := cmp.Diff(gotTestNames, wantTestNames); diff != "" {
+		t.Errorf("groupGoTestsByPackage(%v) test names diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestGoTestGetFiles(t *testing.T) {
+	changeGoTestCwd(t)
+	goTest := NewGoTest(RunnerConfig{})
+
+	got, err := goTest.GetFiles()
+	if err != nil {
+		t.Errorf("GoTest.GetFiles() error = %v", err)
+	}
+
+	want := []string{
+		"./fruits",
+		"./vegetables",
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("GoTest.GetFiles() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestIsIgnoredGoDir(t *testing.T) {
+	cases := map[string]bool{
+		".":                       false,
+		"internal/api":            false,
+		"internal/api/testdata":   true,
+		"testdata/gotest/fruits":  true,
+		"vendor/github.com/pkg":   true,
+		"_examples/basic":         true,
+		"internal/.cache/package": true,
+	}
+
+	for dir, want := range cases {
+		if got := isIgnoredGoDir(dir); got != want {
+			t.Errorf("isIgnoredGoDir(%q) = %v, want %v", dir, got, want)
+		}
+	}
+}
+
+func TestGoTestGetExamples(t *testing.T) {
+	changeGoTestCwd(t)
+	goTest := NewGoTest(RunnerConfig{})
+
+	packages := []string{"./fruits"}
+	got, err := goTest.GetExamples(packages)
+
+	want := []plan.TestCase{
+		{
+			Identifier: "example.com/gotest/fruits/TestApple",
+			Name:       "TestApple",
+			Path:       "example.com/gotest/fruits",
+			Scope:      "example.com/gotest/fruits",
+		},
+		{
+			Identifier: "example.com/gotest/fruits/TestBanana",
+			Name:       "TestBanana",
+			Path:       "example.com/gotest/fruits",
+			Scope:      "example.com/gotest/fruits",
+		},
+	}
+
+	if err != nil {
+		t.Errorf("GoTest.GetExamples(%q) error = %v", packages, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("GoTest.GetExamples(%q) diff (-got +want):\n%s", packages, diff)
+	}
+}
+
+func TestGoTestOutputPrinter(t *testing.T) {
+	var out bytes.Buffer
+	printer := &goTestOutputPrinter{w: &out}
+
+	printer.Write([]byte(`{"Action":"output","Test":"TestApple","Output":"=== RUN   TestApple\n"}` + "\n" + `{"Action":"pass","Test":"Te`))
+	printer.Write([]byte(`stApple"}` + "\n" + "# example.com/gotest/fruits\n" + `{"Action":"output","Output":"ok\n"}`))
+	printer.Flush()
+
+	want := "=== RUN   TestApple\n# example.com/gotest/fruits\nok\n"
+	if diff := cmp.Diff(out.String(), want); diff != "" {
+		t.Errorf("goTestOutputPrinter output diff (-got +want):\n%s", diff)
+	}
+}
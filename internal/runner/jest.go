@@ -6,9 +6,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"regexp"
+	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/buildkite/test-engine-client/internal/debug"
 	"github.com/buildkite/test-engine-client/internal/plan"
@@ -58,37 +59,78 @@ func (j Jest) GetFiles() ([]string, error) {
 	return files, nil
 }
 
+// Run executes the test command with the given test cases.
+// If retry is true, it will run the retry test command with a test name pattern matching the given test cases.
+// Otherwise, test files are run together using the test command,
+// and test examples are run with a test name pattern for each file they belong to.
+//
+// Error is returned if the command fails to run, exits prematurely, or if the
+// output cannot be parsed.
+//
+// Test failure is not considered an error, and is instead returned as a RunResult.
 func (j Jest) Run(result *RunResult, testCases []plan.TestCase, retry bool) error {
-	var cmd *exec.Cmd
-	var err error
-
-	if !retry {
-		testPaths := make([]string, len(testCases))
+	if retry {
+		testNames := make([]string, len(testCases))
 		for i, testCase := range testCases {
-			testPaths[i] = testCase.Path
+			testNames[i] = fmt.Sprintf("%s %s", testCase.Scope, testCase.Name)
+		}
+		commandName, commandArgs, err := j.retryCommandNameAndArgs(j.RetryTestCommand, testNames)
+		if err != nil {
+			result.err = err
+			return fmt.Errorf("failed to build command: %w", err)
 		}
+
+		return j.runAndRecord(result, exec.Command(commandName, commandArgs...))
+	}
+
+	var testPaths []string
+	var examplePaths []string
+	examplesByPath := map[string][]string{}
+	for _, testCase := range testCases {
+		if testCase.Format != plan.TestCaseFormatExample {
+			testPaths = append(testPaths, testCase.Path)
+			continue
+		}
+
+		if _, ok := examplesByPath[testCase.Path]; !ok {
+			examplePaths = append(examplePaths, testCase.Path)
+		}
+		examplesByPath[testCase.Path] = append(examplesByPath[testCase.Path], fmt.Sprintf("%s %s", testCase.Scope, testCase.Name))
+	}
+
+	if len(testPaths) > 0 || len(examplePaths) == 0 {
 		commandName, commandArgs, err := j.commandNameAndArgs(j.TestCommand, testPaths)
 		if err != nil {
 			result.err = err
 			return fmt.Errorf("failed to build command: %w", err)
 		}
 
-		cmd = exec.Command(commandName, commandArgs...)
-	} else {
-		testNames := make([]string, len(testCases))
-		for i, testCase := range testCases {
-			testNames[i] = fmt.Sprintf("%s %s", testCase.Scope, testCase.Name)
+		if err := j.runAndRecord(result, exec.Command(commandName, commandArgs...)); err != nil {
+			return err
 		}
-		commandName, commandArgs, err := j.retryCommandNameAndArgs(j.RetryTestCommand, testNames)
+	}
+
+	// Jest only accepts a single test name pattern for each run,
+	// therefore the examples of each file are run separately.
+	for _, path := range examplePaths {
+		commandName, commandArgs, err := j.commandNameAndArgs(j.TestCommand, []string{path})
 		if err != nil {
 			result.err = err
 			return fmt.Errorf("failed to build command: %w", err)
 		}
+		commandArgs = append(commandArgs, "--testNamePattern", testNamePattern(examplesByPath[path]))
 
-		cmd = exec.Command(commandName, commandArgs...)
+		if err := j.runAndRecord(result, exec.Command(commandName, commandArgs...)); err != nil {
+			return err
+		}
 	}
 
-	err = runAndForwardSignal(cmd)
+	return nil
+}
+
+// runAndRecord runs the command and records the test results from the Jest report in the RunResult.
+func (j Jest) runAndRecord(result *RunResult, cmd *exec.Cmd) error {
+	err := j.run(cmd)
 
 	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
 		result.err = err
@@ -103,6 +145,7 @@ func (j Jest) Run(result *RunResult, testCases []plan.TestCase, retry bool) erro
 	}
 
 	for _, testResult := range report.TestResults {
+		file := relativePath(testResult.Name)
 		for _, example := range testResult.AssertionResults {
 			var status TestStatus
 			switch example.Status {
@@ -112,30 +155,75 @@ func (j Jest) Run(result *RunResult, testCases []plan.TestCase, retry bool) erro
 				status = TestStatusPassed
 			}
 
-			// The scope and name has to match with the scope generated by Buildkite test collector.
-			// For more details, see:
-			// [Buildkite Test Collector - Jest implementation](https://github.com/buildkite/test-collector-javascript/blob/42b803a618a15a07edf0169038ef4b5eba88f98d/jest/reporter.js#L40)
-			testCase := plan.TestCase{
-				Name:  example.Title,
-				Scope: strings.Join(example.AncestorTitles, " "),
-			}
-			result.RecordTestResult(testCase, status)
+			result.recordTestResult(example.testResult(mapJestExampleToTestCase(example), status, file))
 		}
 	}
 
 	return nil
 }
 
+func mapJestExampleToTestCase(example JestExample) plan.TestCase {
+	// The scope and name has to match with the scope generated by Buildkite test collector.
+	// For more details, see:
+	// [Buildkite Test Collector - Jest implementation](https://github.com/buildkite/test-collector-javascript/blob/42b803a618a15a07edf0169038ef4b5eba88f98d/jest/reporter.js#L40)
+	return plan.TestCase{
+		Name:  example.Title,
+		Scope: strings.Join(example.AncestorTitles, " "),
+	}
+}
+
 type JestExample struct {
 	Name           string   `json:"fullName"`
 	Status         string   `json:"status"`
 	Title          string   `json:"title"`
 	AncestorTitles []string `json:"ancestorTitles"`
+	// Duration is the duration of the example in milliseconds.
+	Duration        float64  `json:"duration"`
+	FailureMessages []string `json:"failureMessages"`
+	// Location is only reported with the --testLocationInResults option.
+	Location *JestLocation `json:"location"`
+}
+
+// JestLocation is the location of an example in its test file.
+type JestLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// testResult returns the result of the example with the given test case and status,
+// along with its duration, failure messages and location within the given file.
+func (e JestExample) testResult(testCase plan.TestCase, status TestStatus, file string) TestResult {
+	testResult := TestResult{
+		TestCase:       testCase,
+		Status:         status,
+		Duration:       time.Duration(e.Duration * float64(time.Millisecond)),
+		FailureMessage: strings.Join(e.FailureMessages, "\n"),
+		File:           file,
+	}
+	if e.Location != nil {
+		testResult.Line = e.Location.Line
+	}
+	return testResult
+}
+
+// relativePath returns the path relative to the current working directory,
+// as the test files are reported with their absolute path by Jest and Vitest.
+func relativePath(path string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(cwd, path); err == nil {
+		return rel
+	}
+	return path
 }
 
 type JestReport struct {
 	NumFailedTests int
 	TestResults    []struct {
+		// Name is the absolute path of the test file.
+		Name             string `json:"name"`
 		AssertionResults []JestExample
 	}
 }
@@ -188,14 +276,7 @@ func (j Jest) retryCommandNameAndArgs(cmd string, testCases []string) (string, [
 		return "", []string{}, err
 	}
 
-	escapedTestCases := make([]string, len(testCases))
-	for i, testCase := range testCases {
-		escapedTestCases[i] = regexp.QuoteMeta(testCase)
-	}
-
-	testNamePattern := fmt.Sprintf("(%s)", strings.Join(escapedTestCases, "|"))
-
-	words = slices.Replace(words, idx, idx+1, testNamePattern)
+	words = slices.Replace(words, idx, idx+1, testNamePattern(testCases))
 
 	outputIdx := slices.Index(words, "{{resultPath}}")
 	if outputIdx < 0 {
@@ -207,6 +288,64 @@ func (j Jest) retryCommandNameAndArgs(cmd string, testCases []string) (string, [
 	return words[0], words[1:], err
 }
 
+// GetExamples returns an array of test examples within the given files.
+//
+// Jest doesn't have a dry run mode, so the examples are collected by running the test command
+// with a test name pattern that doesn't match any test. Jest still loads the test files to collect the tests,
+// but reports all of them as skipped without running them.
 func (j Jest) GetExamples(files []string) ([]plan.TestCase, error) {
-	return nil, fmt.Errorf("not supported in Jest")
+	// Create a temporary file to store the JSON output of the collection run.
+	f, err := os.CreateTemp("", "jest-examples-*.json")
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to create temporary file for jest examples: %v", err)
+	}
+
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	collector := j
+	collector.ResultPath = f.Name()
+
+	cmdName, cmdArgs, err := collector.commandNameAndArgs(j.TestCommand, files)
+	if err != nil {
+		return nil, err
+	}
+
+	// "$^" never matches a non-empty test name.
+	cmdArgs = append(cmdArgs, "--testNamePattern", "$^")
+
+	debug.Printf("Running `%s %s` to collect examples", cmdName, strings.Join(cmdArgs, " "))
+
+	output, err := exec.Command(cmdName, cmdArgs...).CombinedOutput()
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to collect jest examples: %s", output)
+	}
+
+	report, err := j.ParseReport(f.Name())
+	if err != nil {
+		return []plan.TestCase{}, err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to get working directory: %v", err)
+	}
+
+	var testCases []plan.TestCase
+	for _, testResult := range report.TestResults {
+		path, err := filepath.Rel(cwd, testResult.Name)
+		if err != nil {
+			path = testResult.Name
+		}
+
+		for _, example := range testResult.AssertionResults {
+			testCase := mapJestExampleToTestCase(example)
+			testCase.Path = path
+			testCases = append(testCases, testCase)
+		}
+	}
+
+	return testCases, nil
 }
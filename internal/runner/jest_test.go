@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
 	"syscall"
 	"testing"
 
@@ -196,6 +198,37 @@ func TestJestRun_SignaledError(t *testing.T) {
 	}
 }
 
+func TestJestRun_WithExamples(t *testing.T) {
+	changeCwd(t, "./testdata/jest")
+
+	jest := NewJest(RunnerConfig{
+		TestCommand: "jest {{testExamples}} --json --outputFile {{resultPath}}",
+		ResultPath:  "jest.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(jest.ResultPath)
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "spells/expelliarmus.spec.js", Scope: "expelliarmus", Name: "disarms the opponent", Format: plan.TestCaseFormatExample},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := jest.Run(result, testCases, false)
+
+	if err != nil {
+		t.Errorf("Jest.Run(%q) error = %v", testCases, err)
+	}
+
+	if len(result.tests) != 1 {
+		t.Errorf("Jest.Run(%q) len(RunResult.tests) = %d, want 1", testCases, len(result.tests))
+	}
+
+	if result.Status() != RunStatusPassed {
+		t.Errorf("Jest.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusPassed)
+	}
+}
+
 func TestJestCommandNameAndArgs_WithInterpolationPlaceholder(t *testing.T) {
 	testCases := []string{"spec/user.spec.js", "spec/billing.spec.js"}
 	testCommand := "jest {{testExamples}} --outputFile {{resultPath}}"
@@ -366,3 +399,44 @@ func TestJestGetFiles(t *testing.T) {
 		t.Errorf("Jest.GetFiles() diff (-got +want):\n%s", diff)
 	}
 }
+
+func TestJestGetExamples(t *testing.T) {
+	changeCwd(t, "./testdata/jest")
+
+	jest := NewJest(RunnerConfig{
+		TestCommand: "jest {{testExamples}} --json --outputFile {{resultPath}}",
+	})
+
+	files := []string{"failure.spec.js", "spells/expelliarmus.spec.js"}
+	got, err := jest.GetExamples(files)
+
+	want := []plan.TestCase{
+		{
+			Name:  "for sure",
+			Path:  "failure.spec.js",
+			Scope: "this will fail",
+		},
+		{
+			Name:  "disarms the opponent",
+			Path:  "spells/expelliarmus.spec.js",
+			Scope: "expelliarmus",
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Jest.GetExamples(%q) error = %v", files, err)
+	}
+
+	// Sort the examples by path as Jest doesn't guarantee the order of the test files.
+	sorter := cmp.Transformer("Sort", func(in []plan.TestCase) []plan.TestCase {
+		out := append([]plan.TestCase(nil), in...) // Copy input to avoid mutating it
+		slices.SortFunc(out, func(a, b plan.TestCase) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+		return out
+	})
+
+	if diff := cmp.Diff(got, want, sorter); diff != "" {
+		t.Errorf("Jest.GetExamples(%q) diff (-got +want):\n%s", files, diff)
+	}
+}
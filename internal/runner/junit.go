@@ -0,0 +1,316 @@
+package runner
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/debug"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/kballard/go-shellquote"
+)
+
+// JUnit is a generic runner for any test framework that can write JUnit XML reports,
+// e.g. PHPUnit, Gradle or dotnet test.
+// It has no default test command or test file pattern, both have to be configured.
+type JUnit struct {
+	RunnerConfig
+}
+
+func NewJUnit(j RunnerConfig) JUnit {
+	if j.RetryTestCommand == "" {
+		j.RetryTestCommand = j.TestCommand
+	}
+
+	return JUnit{
+		RunnerConfig: j,
+	}
+}
+
+func (j JUnit) Name() string {
+	return "JUnit"
+}
+
+// GetFiles returns an array of file names using the discovery pattern.
+func (j JUnit) GetFiles() ([]string, error) {
+	debug.Println("Discovering test files with include pattern:", j.TestFilePattern, "exclude pattern:", j.TestFileExcludePattern)
+	files, err := discoverTestFiles(j.TestFilePattern, j.TestFileExcludePattern)
+	debug.Println("Discovered", len(files), "files")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found with pattern %q and exclude pattern %q", j.TestFilePattern, j.TestFileExcludePattern)
+	}
+
+	return files, nil
+}
+
+// Run executes the test command with the given test cases, and reads the results
+// from the JUnit XML reports matching the result path glob.
+// If retry is true, it will run the command using the retry test command,
+// otherwise it will use the test command.
+// Because the retry command runs the whole file of each failed test,
+// only the results of the retried tests are recorded when retrying.
+//
+// Error is returned if the command fails to run, exits prematurely, or if the
+// output cannot be parsed.
+//
+// Test failure is not considered an error, and is instead returned as a RunResult.
+func (j JUnit) Run(result *RunResult, testCases []plan.TestCase, retry bool) error {
+	command := j.TestCommand
+
+	if retry {
+		command = j.RetryTestCommand
+	}
+
+	testPaths := []string{}
+	for _, tc := range testCases {
+		if !slices.Contains(testPaths, tc.Path) {
+			testPaths = append(testPaths, tc.Path)
+		}
+	}
+
+	commandName, commandArgs, err := j.commandNameAndArgs(command, testPaths)
+	if err != nil {
+		result.err = err
+		return fmt.Errorf("failed to build command: %w", err)
+	}
+
+	// Many tools write a report per test file or class.
+	// Remove the reports from the previous run so they are not mistaken for the results of this run.
+	if err := j.removeReports(); err != nil {
+		result.err = err
+		return err
+	}
+
+	cmd := exec.Command(commandName, commandArgs...)
+
+	err = j.run(cmd)
+
+	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
+		result.err = err
+		return err
+	}
+
+	testResults, parseErr := j.ParseReports(j.ResultPath)
+	if parseErr != nil {
+		fmt.Println("Buildkite Test Engine Client: Failed to read JUnit output, tests will not be retried.")
+		result.err = err
+		return err
+	}
+
+	retriedTests := map[string]bool{}
+	if retry {
+		for _, tc := range testCases {
+			retriedTests[testIdentifier(tc)] = true
+		}
+	}
+
+	for _, testResult := range testResults {
+		if retry && !retriedTests[testIdentifier(testResult.TestCase)] {
+			continue
+		}
+		result.recordTestResult(testResult)
+	}
+
+	return nil
+}
+
+func (j JUnit) removeReports() error {
+	paths, err := filepath.Glob(j.ResultPath)
+	if err != nil {
+		return fmt.Errorf("invalid result path %q: %v", j.ResultPath, err)
+	}
+
+	for _, path := range paths {
+		debug.Println("Removing previous JUnit report", path)
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove previous junit output: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ParseReports reads the JUnit XML reports matching the given glob pattern
+// and returns the test results of all reports.
+func (j JUnit) ParseReports(pattern string) ([]TestResult, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid result path %q: %v", pattern, err)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no junit report found with pattern %q", pattern)
+	}
+
+	var testResults []TestResult
+	for _, path := range paths {
+		report, err := parseJUnitReport(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, testCase := range report.AllTestCases() {
+			testResults = append(testResults, TestResult{
+				TestCase:       mapJUnitTestCaseToTestCase(testCase),
+				Status:         testCase.Status(),
+				Duration:       secondsToDuration(testCase.Time),
+				FailureMessage: testCase.FailureMessage(),
+				File:           testCase.File,
+				Line:           testCase.Line,
+			})
+		}
+	}
+
+	return testResults, nil
+}
+
+func mapJUnitTestCaseToTestCase(testCase JUnitTestCase) plan.TestCase {
+	// Not every tool writes the file attribute, e.g. Gradle and dotnet don't.
+	// In that case, the classname is used as the path, which most of these tools accept as a test filter.
+	path := testCase.File
+	if path == "" {
+		path = testCase.Classname
+	}
+
+	return plan.TestCase{
+		Identifier: testCase.Classname + "." + testCase.Name,
+		Name:       testCase.Name,
+		Path:       path,
+		Scope:      testCase.Classname,
+	}
+}
+
+// commandNameAndArgs replaces the "{{testExamples}}" placeholder in the test command with the test cases,
+// and the "{{resultPath}}" placeholder with the result path.
+// It returns the command name and arguments to run the tests.
+func (j JUnit) commandNameAndArgs(cmd string, testCases []string) (string, []string, error) {
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return "", []string{}, err
+	}
+
+	if len(words) == 0 {
+		return "", []string{}, fmt.Errorf("test command is empty")
+	}
+
+	idx := slices.Index(words, "{{testExamples}}")
+	if idx < 0 {
+		words = append(words, testCases...)
+	} else {
+		words = slices.Replace(words, idx, idx+1, testCases...)
+	}
+
+	idx = slices.Index(words, "{{resultPath}}")
+	if idx >= 0 {
+		words = slices.Replace(words, idx, idx+1, j.ResultPath)
+	}
+
+	return words[0], words[1:], nil
+}
+
+func (j JUnit) GetExamples(files []string) ([]plan.TestCase, error) {
+	return nil, fmt.Errorf("not supported in JUnit")
+}
+
+// JUnitTestCase represents a single testcase element in a JUnit XML report.
+type JUnitTestCase struct {
+	Classname string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	File      string        `xml:"file,attr"`
+	Line      int           `xml:"line,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure"`
+	Error     *JUnitFailure `xml:"error"`
+	Skipped   *struct{}     `xml:"skipped"`
+}
+
+// JUnitFailure represents a failure or error element of a testcase.
+// The text usually contains the message and the stack trace of the failure.
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// FailureMessage returns the failure message of the testcase, or an empty string if it didn't fail.
+func (tc JUnitTestCase) FailureMessage() string {
+	failure := tc.Failure
+	if failure == nil {
+		failure = tc.Error
+	}
+	if failure == nil {
+		return ""
+	}
+
+	text := strings.TrimSpace(failure.Text)
+	switch {
+	case text == "":
+		return failure.Message
+	case failure.Message == "" || strings.Contains(text, failure.Message):
+		return text
+	default:
+		return failure.Message + "\n" + text
+	}
+}
+
+// Status returns the status of the testcase.
+// A testcase with an error is considered failed.
+func (tc JUnitTestCase) Status() TestStatus {
+	switch {
+	case tc.Failure != nil, tc.Error != nil:
+		return TestStatusFailed
+	case tc.Skipped != nil:
+		return TestStatusPending
+	default:
+		return TestStatusPassed
+	}
+}
+
+// JUnitTestSuite represents a testsuite element in a JUnit XML report.
+// Test suites can be nested.
+type JUnitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	TestCases  []JUnitTestCase  `xml:"testcase"`
+	TestSuites []JUnitTestSuite `xml:"testsuite"`
+}
+
+// AllTestCases returns the testcases of the suite and all its nested suites.
+func (ts JUnitTestSuite) AllTestCases() []JUnitTestCase {
+	testCases := ts.TestCases
+	for _, suite := range ts.TestSuites {
+		testCases = append(testCases, suite.AllTestCases()...)
+	}
+	return testCases
+}
+
+// JUnitReport is the structure for JUnit XML report.
+// The root element of the report can be either testsuites or testsuite,
+// so the root element is parsed as a test suite.
+type JUnitReport struct {
+	JUnitTestSuite
+}
+
+// parseJUnitReport reads and parses the JUnit XML report at the given path.
+func parseJUnitReport(path string) (JUnitReport, error) {
+	var report JUnitReport
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return JUnitReport{}, fmt.Errorf("failed to read junit output: %v", err)
+	}
+
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return JUnitReport{}, fmt.Errorf("failed to parse junit output: %s", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,339 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kballard/go-shellquote"
+)
+
+func TestNewJUnit(t *testing.T) {
+	cases := []struct {
+		input RunnerConfig
+		want  RunnerConfig
+	}{
+		// retry command defaults to test command
+		{
+			input: RunnerConfig{
+				TestCommand:     "vendor/bin/phpunit --log-junit {{resultPath}} {{testExamples}}",
+				TestFilePattern: "tests/**/*Test.php",
+				ResultPath:      "tmp/junit.xml",
+			},
+			want: RunnerConfig{
+				TestCommand:      "vendor/bin/phpunit --log-junit {{resultPath}} {{testExamples}}",
+				TestFilePattern:  "tests/**/*Test.php",
+				RetryTestCommand: "vendor/bin/phpunit --log-junit {{resultPath}} {{testExamples}}",
+				ResultPath:       "tmp/junit.xml",
+			},
+		},
+		// custom
+		{
+			input: RunnerConfig{
+				TestCommand:            "./gradlew test {{testExamples}}",
+				TestFilePattern:        "src/test/**/*Test.java",
+				TestFileExcludePattern: "src/test/java/com/example/e2e",
+				RetryTestCommand:       "./gradlew test --rerun {{testExamples}}",
+				ResultPath:             "build/test-results/test/*.xml",
+			},
+			want: RunnerConfig{
+				TestCommand:            "./gradlew test {{testExamples}}",
+				TestFilePattern:        "src/test/**/*Test.java",
+				TestFileExcludePattern: "src/test/java/com/example/e2e",
+				RetryTestCommand:       "./gradlew test --rerun {{testExamples}}",
+				ResultPath:             "build/test-results/test/*.xml",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		got := NewJUnit(c.input)
+		if diff := cmp.Diff(got.RunnerConfig, c.want); diff != "" {
+			t.Errorf("NewJUnit(%v) diff (-got +want):\n%s", c.input, diff)
+		}
+	}
+}
+
+func TestJUnitRun(t *testing.T) {
+	dir := t.TempDir()
+
+	junit := NewJUnit(RunnerConfig{
+		TestCommand: fmt.Sprintf(`sh -c 'cp "$0" "$1" "$2"' ./testdata/junit/phpunit.xml ./testdata/junit/gradle.xml %s`, dir),
+		ResultPath:  filepath.Join(dir, "*.xml"),
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "tests/Unit/AppleTest.php"},
+		{Path: "tests/Unit/BananaTest.php"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := junit.Run(result, testCases, false)
+
+	if err != nil {
+		t.Errorf("JUnit.Run(%q) error = %v", testCases, err)
+	}
+
+	if result.Status() != RunStatusFailed {
+		t.Errorf("JUnit.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusFailed)
+	}
+
+	wantStatistics := RunStatistics{
+		Total:            5,
+		PassedOnFirstRun: 2,
+		Failed:           2,
+	}
+
+	if diff := cmp.Diff(result.Statistics(), wantStatistics); diff != "" {
+		t.Errorf("JUnit.Run(%q) RunResult.Statistics() diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestJUnitRun_Retry(t *testing.T) {
+	dir := t.TempDir()
+
+	// The report contains tests that are not being retried.
+	junit := NewJUnit(RunnerConfig{
+		TestCommand: fmt.Sprintf(`sh -c 'cp "$0" "$1"' ./testdata/junit/gradle.xml %s`, dir),
+		ResultPath:  filepath.Join(dir, "*.xml"),
+	})
+
+	testCases := []plan.TestCase{
+		{Scope: "com.example.TomatoTest", Name: "isRed()", Path: "com.example.TomatoTest"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := junit.Run(result, testCases, true)
+
+	if err != nil {
+		t.Errorf("JUnit.Run(%q) error = %v", testCases, err)
+	}
+
+	if len(result.tests) != 1 {
+		t.Errorf("JUnit.Run(%q) len(RunResult.tests) = %d, want 1", testCases, len(result.tests))
+	}
+}
+
+func TestJUnitRun_CommandFailed(t *testing.T) {
+	dir := t.TempDir()
+
+	junit := NewJUnit(RunnerConfig{
+		TestCommand: "false",
+		ResultPath:  filepath.Join(dir, "*.xml"),
+	})
+
+	testCases := []plan.TestCase{}
+	result := NewRunResult([]plan.TestCase{})
+	err := junit.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("JUnit.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	exitError := new(exec.ExitError)
+	if !errors.As(err, &exitError) {
+		t.Errorf("JUnit.Run(%q) error type = %T (%v), want *exec.ExitError", testCases, err, err)
+	}
+}
+
+func TestJUnitRun_SignaledError(t *testing.T) {
+	junit := NewJUnit(RunnerConfig{
+		TestCommand: "./testdata/segv.sh",
+		ResultPath:  filepath.Join(t.TempDir(), "*.xml"),
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "./doesnt-matter.php"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := junit.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("JUnit.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	signalError := new(ProcessSignaledError)
+	if !errors.As(err, &signalError) {
+		t.Errorf("JUnit.Run(%q) error type = %T (%v), want *ErrProcessSignaled", testCases, err, err)
+	}
+}
+
+func TestJUnitParseReports(t *testing.T) {
+	junit := NewJUnit(RunnerConfig{})
+
+	got, err := junit.ParseReports("./testdata/junit/*.xml")
+	if err != nil {
+		t.Errorf("JUnit.ParseReports() error = %v", err)
+	}
+
+	want := []TestResult{
+		{
+			TestCase: plan.TestCase{
+				Identifier: "com.example.TomatoTest.isAFruit()",
+				Name:       "isAFruit()",
+				Path:       "com.example.TomatoTest",
+				Scope:      "com.example.TomatoTest",
+			},
+			Status:   TestStatusPassed,
+			Duration: 10 * time.Millisecond,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "com.example.TomatoTest.isRed()",
+				Name:       "isRed()",
+				Path:       "com.example.TomatoTest",
+				Scope:      "com.example.TomatoTest",
+			},
+			Status:         TestStatusFailed,
+			Duration:       2 * time.Millisecond,
+			FailureMessage: "org.opentest4j.AssertionFailedError: expected: <red> but was: <green>",
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "Tests.Unit.AppleTest.testIsRed",
+				Name:       "testIsRed",
+				Path:       "tests/Unit/AppleTest.php",
+				Scope:      "Tests.Unit.AppleTest",
+			},
+			Status:   TestStatusPassed,
+			Duration: 1 * time.Millisecond,
+			File:     "tests/Unit/AppleTest.php",
+			Line:     9,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "Tests.Unit.AppleTest.testIsSweet",
+				Name:       "testIsSweet",
+				Path:       "tests/Unit/AppleTest.php",
+				Scope:      "Tests.Unit.AppleTest",
+			},
+			Status:         TestStatusFailed,
+			Duration:       2 * time.Millisecond,
+			FailureMessage: "Error: Call to undefined method Tests\\Unit\\Apple::sweetness()",
+			File:           "tests/Unit/AppleTest.php",
+			Line:           14,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "Tests.Unit.BananaTest.testIsYellow",
+				Name:       "testIsYellow",
+				Path:       "tests/Unit/BananaTest.php",
+				Scope:      "Tests.Unit.BananaTest",
+			},
+			Status:   TestStatusPending,
+			Duration: 1 * time.Millisecond,
+			File:     "tests/Unit/BananaTest.php",
+			Line:     9,
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("JUnit.ParseReports() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestJUnitParseReports_NoReports(t *testing.T) {
+	junit := NewJUnit(RunnerConfig{})
+
+	pattern := filepath.Join(t.TempDir(), "*.xml")
+	_, err := junit.ParseReports(pattern)
+	if err == nil {
+		t.Errorf("JUnit.ParseReports(%q) error = nil, want error", pattern)
+	}
+}
+
+func TestJUnitGetFiles(t *testing.T) {
+	junit := NewJUnit(RunnerConfig{
+		TestFilePattern: "testdata/junit/*.xml",
+	})
+
+	got, err := junit.GetFiles()
+	if err != nil {
+		t.Errorf("JUnit.GetFiles() error = %v", err)
+	}
+
+	want := []string{
+		"testdata/junit/gradle.xml",
+		"testdata/junit/phpunit.xml",
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("JUnit.GetFiles() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestJUnitCommandNameAndArgs_WithInterpolationPlaceholder(t *testing.T) {
+	testCases := []string{"tests/Unit/AppleTest.php", "tests/Unit/BananaTest.php"}
+	testCommand := "vendor/bin/phpunit --log-junit {{resultPath}} {{testExamples}}"
+
+	junit := NewJUnit(RunnerConfig{
+		TestCommand: testCommand,
+		ResultPath:  "junit.xml",
+	})
+
+	gotName, gotArgs, err := junit.commandNameAndArgs(testCommand, testCases)
+	if err != nil {
+		t.Errorf("commandNameAndArgs(%q, %q) error = %v", testCases, testCommand, err)
+	}
+
+	wantName := "vendor/bin/phpunit"
+	wantArgs := []string{"--log-junit", "junit.xml", "tests/Unit/AppleTest.php", "tests/Unit/BananaTest.php"}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+}
+
+func TestJUnitCommandNameAndArgs_WithoutTestExamplesPlaceholder(t *testing.T) {
+	testCases := []string{"com.example.TomatoTest"}
+	testCommand := "./gradlew test --tests"
+
+	junit := NewJUnit(RunnerConfig{
+		TestCommand: testCommand,
+	})
+
+	gotName, gotArgs, err := junit.commandNameAndArgs(testCommand, testCases)
+	if err != nil {
+		t.Errorf("commandNameAndArgs(%q, %q) error = %v", testCases, testCommand, err)
+	}
+
+	wantName := "./gradlew"
+	wantArgs := []string{"test", "--tests", "com.example.TomatoTest"}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+}
+
+func TestJUnitCommandNameAndArgs_InvalidTestCommand(t *testing.T) {
+	testCases := []string{"tests/Unit/AppleTest.php"}
+	testCommand := "vendor/bin/phpunit --options '{{testExamples}}"
+
+	junit := NewJUnit(RunnerConfig{
+		TestCommand: testCommand,
+	})
+
+	gotName, gotArgs, err := junit.commandNameAndArgs(testCommand, testCases)
+
+	wantName := ""
+	wantArgs := []string{}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("commandNameAndArgs() diff (-got +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("commandNameAndArgs() diff (-got +want):\n%s", diff)
+	}
+	if !errors.Is(err, shellquote.UnterminatedSingleQuoteError) {
+		t.Errorf("commandNameAndArgs() error = %v, want %v", err, shellquote.UnterminatedSingleQuoteError)
+	}
+}
@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// placeholders are the placeholders the runners replace in the test and retry commands.
+var placeholders = []string{"{{testExamples}}", "{{resultPath}}", "{{testNamePattern}}"}
+
+// placeholderPattern matches a placeholder in a word of a command, e.g. "{{resultPath}}" in "--outputFile={{resultPath}}".
+var placeholderPattern = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+
+// CheckPlaceholders returns an error if a placeholder in the command of the runner is malformed, unknown,
+// or is part of a word of the command, in which case the runner wouldn't replace it.
+// Only vitest replaces "{{resultPath}}" within a word, e.g. "--outputFile={{resultPath}}".
+func CheckPlaceholders(runnerName string, command string) error {
+	words, err := shellquote.Split(command)
+	if err != nil {
+		return fmt.Errorf("couldn't parse command %q: %w", command, err)
+	}
+
+	for _, word := range words {
+		for _, placeholder := range placeholderPattern.FindAllString(word, -1) {
+			if !slices.Contains(placeholders, placeholder) {
+				return fmt.Errorf("unknown placeholder %q, the placeholders are %s", placeholder, strings.Join(placeholders, ", "))
+			}
+
+			if word != placeholder && !(runnerName == "vitest" && placeholder == "{{resultPath}}") {
+				return fmt.Errorf("placeholder %q must be a separate word of the command, but is part of %q", placeholder, word)
+			}
+		}
+
+		if rest := placeholderPattern.ReplaceAllString(word, ""); strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+			return fmt.Errorf("malformed placeholder in %q, placeholders must be written like %s", word, placeholders[0])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"testing"
+)
+
+func TestCheckPlaceholders(t *testing.T) {
+	cases := []struct {
+		runner  string
+		command string
+	}{
+		{runner: "rspec", command: "bundle exec rspec --format json --out {{resultPath}} {{testExamples}}"},
+		{runner: "jest", command: "npx jest --testNamePattern '{{testNamePattern}}' --json --outputFile {{resultPath}}"},
+		{runner: "vitest", command: "npx vitest run {{testExamples}} --reporter=json --outputFile={{resultPath}}"},
+		{runner: "rspec", command: "bin/rspec"},
+	}
+
+	for _, tc := range cases {
+		if err := CheckPlaceholders(tc.runner, tc.command); err != nil {
+			t.Errorf("CheckPlaceholders(%q, %q) error = %v", tc.runner, tc.command, err)
+		}
+	}
+}
+
+func TestCheckPlaceholders_Invalid(t *testing.T) {
+	cases := []struct {
+		runner  string
+		command string
+		want    string
+	}{
+		{
+			runner:  "rspec",
+			command: "bin/rspec --out {{resultpath}} {{testExamples}}",
+			want:    `unknown placeholder "{{resultpath}}", the placeholders are {{testExamples}}, {{resultPath}}, {{testNamePattern}}`,
+		},
+		{
+			runner:  "rspec",
+			command: "bin/rspec --out={{resultPath}} {{testExamples}}",
+			want:    `placeholder "{{resultPath}}" must be a separate word of the command, but is part of "--out={{resultPath}}"`,
+		},
+		{
+			runner:  "pytest",
+			command: "pytest {{testExamples} --junit-xml {{resultPath}}",
+			want:    `malformed placeholder in "{{testExamples}", placeholders must be written like {{testExamples}}`,
+		},
+		{
+			runner:  "jest",
+			command: "npx jest '{{ testExamples }}'",
+			want:    `unknown placeholder "{{ testExamples }}", the placeholders are {{testExamples}}, {{resultPath}}, {{testNamePattern}}`,
+		},
+		{
+			runner:  "jest",
+			command: "npx jest '{{testExamples}}",
+			want:    `couldn't parse command "npx jest '{{testExamples}}": Unterminated single-quoted string`,
+		},
+	}
+
+	for _, tc := range cases {
+		err := CheckPlaceholders(tc.runner, tc.command)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("CheckPlaceholders(%q, %q) error = %v, want %q", tc.runner, tc.command, err, tc.want)
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"os/exec"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/buildkite/test-engine-client/internal/debug"
 	"github.com/buildkite/test-engine-client/internal/plan"
@@ -30,26 +32,54 @@ func NewPlaywright(p RunnerConfig) Playwright {
 		p.TestFilePattern = "**/{*.spec,*.test}.{ts,js}"
 	}
 
+	if p.RetryTestCommand == "" {
+		p.RetryTestCommand = "npx playwright test --grep '{{testNamePattern}}'"
+	}
+
 	return Playwright{
 		RunnerConfig: p,
 	}
 }
 
+// Run executes the test command with the given test cases.
+// If retry is true, it will run the retry test command with a grep pattern
+// matching only the given test cases, otherwise it will run the test command with the test files.
 func (p Playwright) Run(result *RunResult, testCases []plan.TestCase, retry bool) error {
-	testPaths := make([]string, len(testCases))
-	for i, tc := range testCases {
-		testPaths[i] = tc.Path
-	}
+	var cmd *exec.Cmd
 
-	cmdName, cmdArgs, err := p.commandNameAndArgs(p.TestCommand, testPaths)
-	if err != nil {
-		result.err = err
-		return fmt.Errorf("failed to build command: %w", err)
-	}
+	if !retry {
+		testPaths := make([]string, len(testCases))
+		for i, tc := range testCases {
+			testPaths[i] = tc.Path
+		}
 
-	cmd := exec.Command(cmdName, cmdArgs...)
+		cmdName, cmdArgs, err := p.commandNameAndArgs(p.TestCommand, testPaths)
+		if err != nil {
+			result.err = err
+			return fmt.Errorf("failed to build command: %w", err)
+		}
+
+		cmd = exec.Command(cmdName, cmdArgs...)
+	} else {
+		// Playwright matches the grep pattern against the project name, file name, describe titles
+		// and test title separated by spaces, e.g. "chromium example.spec.js group test".
+		// The scope already contains all of them, prefixed by a space because the root suite has no title.
+		// Matching the project qualified title ensures that a test is only retried in the project it failed in.
+		testNames := make([]string, len(testCases))
+		for i, tc := range testCases {
+			testNames[i] = strings.TrimLeft(tc.Scope, " ")
+		}
+
+		cmdName, cmdArgs, err := p.retryCommandNameAndArgs(p.RetryTestCommand, testNames)
+		if err != nil {
+			result.err = err
+			return fmt.Errorf("failed to build command: %w", err)
+		}
+
+		cmd = exec.Command(cmdName, cmdArgs...)
+	}
 
-	err = runAndForwardSignal(cmd)
+	err := p.run(cmd)
 
 	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
 		result.err = err
@@ -63,10 +93,20 @@ func (p Playwright) Run(result *RunResult, testCases []plan.TestCase, retry bool
 		return err
 	}
 
+	retriedTests := map[string]bool{}
+	if retry {
+		for _, tc := range testCases {
+			retriedTests[testIdentifier(tc)] = true
+		}
+	}
+
 	for _, suite := range report.Suites {
 		testResults := p.getTestResultsFromSuite(suite, suite.Title)
 		for _, testResult := range testResults {
-			result.RecordTestResult(testResult.TestCase, testResult.Status)
+			if retry && !retriedTests[testIdentifier(testResult.TestCase)] {
+				continue
+			}
+			result.recordTestResult(testResult)
 		}
 	}
 	return nil
@@ -80,6 +120,10 @@ func (p Playwright) getTestResultsFromSuite(suite PlaywrightReportSuite, suiteNa
 	var testResults []TestResult
 
 	for _, spec := range suite.Specs {
+		if len(spec.Tests) == 0 {
+			continue
+		}
+
 		projectName := spec.Tests[0].ProjectName
 		var status TestStatus
 		if spec.Ok {
@@ -88,6 +132,18 @@ func (p Playwright) getTestResultsFromSuite(suite PlaywrightReportSuite, suiteNa
 			status = TestStatusFailed
 		}
 
+		// Playwright may run a test several times when retries are configured,
+		// the duration and errors of its final run are reported.
+		var duration time.Duration
+		var failureMessages []string
+		if results := spec.Tests[0].Results; len(results) > 0 {
+			last := results[len(results)-1]
+			duration = time.Duration(last.Duration * float64(time.Millisecond))
+			for _, e := range last.Errors {
+				failureMessages = append(failureMessages, e.Message)
+			}
+		}
+
 		testResults = append(testResults, TestResult{
 			TestCase: plan.TestCase{
 				Name: spec.Title,
@@ -102,7 +158,11 @@ func (p Playwright) getTestResultsFromSuite(suite PlaywrightReportSuite, suiteNa
 				// [Playwright suite structure](https://playwright.dev/docs/api/class-suite)
 				Scope: fmt.Sprintf(" %s %s %s", projectName, suiteName, spec.Title),
 			},
-			Status: status,
+			Status:         status,
+			Duration:       duration,
+			FailureMessage: strings.Join(failureMessages, "\n"),
+			File:           spec.File,
+			Line:           spec.Line,
 		})
 	}
 
@@ -128,6 +188,26 @@ func (p Playwright) commandNameAndArgs(cmd string, testCases []string) (string,
 	return words[0], words[1:], nil
 }
 
+// retryCommandNameAndArgs replaces the "{{testNamePattern}}" placeholder in the retry command
+// with a grep pattern matching the given test titles.
+func (p Playwright) retryCommandNameAndArgs(cmd string, testNames []string) (string, []string, error) {
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return "", []string{}, err
+	}
+
+	idx := slices.Index(words, "{{testNamePattern}}")
+	if idx < 0 {
+		err := fmt.Errorf("couldn't find '{{testNamePattern}}' sentinel in retry command")
+		return "", []string{}, err
+	}
+
+	// The pattern is anchored at the end, so that retrying "group test" doesn't also run "group test 2".
+	words = slices.Replace(words, idx, idx+1, testNamePattern(testNames)+"$")
+
+	return words[0], words[1:], nil
+}
+
 func (p Playwright) parseReport(path string) (PlaywrightReport, error) {
 	var report PlaywrightReport
 	data, err := os.ReadFile(path)
@@ -158,12 +238,68 @@ func (p Playwright) GetFiles() ([]string, error) {
 	return files, nil
 }
 
+// GetExamples returns an array of test examples within the given files.
+// It lists the tests using Playwright's dry run, `playwright test --list`, with the JSON reporter.
+// The JSON report has the same suite structure as the report of a test run,
+// so the examples carry the same scope as the test results.
 func (p Playwright) GetExamples(files []string) ([]plan.TestCase, error) {
-	return nil, fmt.Errorf("not supported in Playwright")
+	// Create a temporary file to store the JSON output of the dry run.
+	// We cannot simply read the output from stdout because
+	// the test command may print other output.
+	f, err := os.CreateTemp("", "playwright-list-*.json")
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to create temporary file for playwright dry run: %v", err)
+	}
+
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	cmdName, cmdArgs, err := p.commandNameAndArgs(p.TestCommand, files)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdArgs = append(cmdArgs, "--list", "--reporter", "json")
+
+	debug.Printf("Running `%s %s` for dry run", cmdName, strings.Join(cmdArgs, " "))
+
+	cmd := exec.Command(cmdName, cmdArgs...)
+	cmd.Env = append(os.Environ(), "PLAYWRIGHT_JSON_OUTPUT_NAME="+f.Name())
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to run playwright dry run: %s", output)
+	}
+
+	report, err := p.parseReport(f.Name())
+	if err != nil {
+		return []plan.TestCase{}, err
+	}
+
+	var testCases []plan.TestCase
+	for _, suite := range report.Suites {
+		for _, testResult := range p.getTestResultsFromSuite(suite, suite.Title) {
+			testCases = append(testCases, testResult.TestCase)
+		}
+	}
+
+	return testCases, nil
 }
 
 type PlaywrightTest struct {
 	ProjectName string
+	Results     []PlaywrightTestResult
+}
+
+// PlaywrightTestResult is the result of a single run of a test.
+type PlaywrightTestResult struct {
+	// Duration is the duration of the run in milliseconds.
+	Duration float64
+	Errors   []struct {
+		Message string
+	}
 }
 
 type PlaywrightSpec struct {
@@ -1,15 +1,58 @@
 package runner
 
 import (
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/buildkite/test-engine-client/internal/plan"
 	"github.com/google/go-cmp/cmp"
 )
 
+func TestNewPlaywright(t *testing.T) {
+	cases := []struct {
+		input RunnerConfig
+		want  RunnerConfig
+	}{
+		//default
+		{
+			input: RunnerConfig{},
+			want: RunnerConfig{
+				TestCommand:            "npx playwright test",
+				TestFilePattern:        "**/{*.spec,*.test}.{ts,js}",
+				TestFileExcludePattern: "",
+				RetryTestCommand:       "npx playwright test --grep '{{testNamePattern}}'",
+			},
+		},
+		// custom
+		{
+			input: RunnerConfig{
+				TestCommand:            "yarn test",
+				TestFilePattern:        "tests/**/*.spec.ts",
+				TestFileExcludePattern: "tests/components/**/*.spec.ts",
+				RetryTestCommand:       "yarn test --grep '{{testNamePattern}}'",
+			},
+			want: RunnerConfig{
+				TestCommand:            "yarn test",
+				TestFilePattern:        "tests/**/*.spec.ts",
+				TestFileExcludePattern: "tests/components/**/*.spec.ts",
+				RetryTestCommand:       "yarn test --grep '{{testNamePattern}}'",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		got := NewPlaywright(c.input)
+		if diff := cmp.Diff(got.RunnerConfig, c.want); diff != "" {
+			t.Errorf("NewPlaywright(%v) diff (-got +want):\n%s", c.input, diff)
+		}
+	}
+}
+
 func TestPlaywrightRun(t *testing.T) {
 	changeCwd(t, "./testdata/playwright")
 
@@ -155,3 +198,207 @@ func TestPlaywrightGetFiles(t *testing.T) {
 		t.Errorf("Playwright.GetFiles() diff (-got +want):\n%s", diff)
 	}
 }
+
+func TestPlaywrightRetryCommandNameAndArgs(t *testing.T) {
+	testNames := []string{"chromium failed.spec.js test group failed", "firefox example.spec.js has title (1)"}
+	retryTestCommand := "npx playwright test --grep '{{testNamePattern}}'"
+
+	playwright := NewPlaywright(RunnerConfig{
+		RetryTestCommand: retryTestCommand,
+	})
+
+	gotName, gotArgs, err := playwright.retryCommandNameAndArgs(retryTestCommand, testNames)
+	if err != nil {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) error = %v", retryTestCommand, testNames, err)
+	}
+
+	wantName := "npx"
+	wantArgs := []string{"playwright", "test", "--grep", `(chromium failed\.spec\.js test group failed|firefox example\.spec\.js has title \(1\))$`}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) diff (-got +want):\n%s", retryTestCommand, testNames, diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) diff (-got +want):\n%s", retryTestCommand, testNames, diff)
+	}
+}
+
+func TestPlaywrightRetryCommandNameAndArgs_WithoutInterpolationPlaceholder(t *testing.T) {
+	testNames := []string{"chromium failed.spec.js test group failed"}
+	retryTestCommand := "npx playwright test"
+
+	playwright := NewPlaywright(RunnerConfig{
+		RetryTestCommand: retryTestCommand,
+	})
+
+	_, _, err := playwright.retryCommandNameAndArgs(retryTestCommand, testNames)
+
+	desiredString := "couldn't find '{{testNamePattern}}' sentinel in retry command"
+	if err == nil || err.Error() != desiredString {
+		t.Errorf("retryCommandNameAndArgs() error = %v, want %v", err, desiredString)
+	}
+}
+
+func TestPlaywrightRun_Retry(t *testing.T) {
+	changeCwd(t, "./testdata/playwright")
+
+	playwright := NewPlaywright(RunnerConfig{
+		TestCommand:      "yarn run playwright test --invalid-option",
+		RetryTestCommand: "yarn run playwright test --grep '{{testNamePattern}}'",
+		ResultPath:       "test-results/results.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(playwright.ResultPath)
+	})
+
+	testCases := []plan.TestCase{
+		{
+			Scope: " chromium failed.spec.js test group failed",
+			Path:  "failed.spec.js:5",
+			Name:  "failed",
+		},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := playwright.Run(result, testCases, true)
+
+	if err != nil {
+		t.Errorf("Playwright.Run(%q) error = %v", testCases, err)
+	}
+
+	if diff := cmp.Diff(result.FailedTests(), testCases); diff != "" {
+		t.Errorf("Playwright.Run(%q) RunResult.FailedTests() diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestPlaywrightRun_RetryRecordsOnlyRetriedTests(t *testing.T) {
+	report := `{"suites": [{"title": "a.spec.js", "suites": [{"title": "group", "specs": [
+		{"file": "a.spec.js", "line": 3, "title": "test", "ok": true, "tests": [{"projectName": "chromium"}]},
+		{"file": "a.spec.js", "line": 7, "title": "test 2", "ok": true, "tests": [{"projectName": "chromium"}]}
+	]}]}]}`
+	resultPath := filepath.Join(t.TempDir(), "results.json")
+	if err := os.WriteFile(resultPath, []byte(report), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	playwright := NewPlaywright(RunnerConfig{
+		RetryTestCommand: "true {{testNamePattern}}",
+		ResultPath:       resultPath,
+	})
+
+	testCases := []plan.TestCase{
+		{Scope: " chromium a.spec.js group test", Path: "a.spec.js:3", Name: "test"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := playwright.Run(result, testCases, true)
+
+	if err != nil {
+		t.Errorf("Playwright.Run(%q) error = %v", testCases, err)
+	}
+
+	// "test 2" is in the report too, but it wasn't retried.
+	wantStatistics := RunStatistics{
+		Total:            1,
+		PassedOnFirstRun: 1,
+	}
+
+	if diff := cmp.Diff(result.Statistics(), wantStatistics); diff != "" {
+		t.Errorf("Playwright.Run(%q) RunResult.Statistics() diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestPlaywrightGetExamples(t *testing.T) {
+	changeCwd(t, "./testdata/playwright")
+
+	playwright := NewPlaywright(RunnerConfig{
+		TestCommand: "yarn run playwright test",
+	})
+
+	files := []string{"tests/failed.spec.js"}
+	got, err := playwright.GetExamples(files)
+
+	want := []plan.TestCase{
+		{
+			Scope: " chromium failed.spec.js test group failed",
+			Path:  "failed.spec.js:5",
+			Name:  "failed",
+		},
+		{
+			Scope: " chromium failed.spec.js it passes",
+			Path:  "failed.spec.js:10",
+			Name:  "it passes",
+		},
+		{
+			Scope: " firefox failed.spec.js test group failed",
+			Path:  "failed.spec.js:5",
+			Name:  "failed",
+		},
+		{
+			Scope: " firefox failed.spec.js it passes",
+			Path:  "failed.spec.js:10",
+			Name:  "it passes",
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Playwright.GetExamples(%q) error = %v", files, err)
+	}
+
+	// Sort the examples by scope and name when comparing
+	sorter := cmp.Transformer("Sort", func(in []plan.TestCase) []plan.TestCase {
+		out := append([]plan.TestCase(nil), in...) // Copy input to avoid mutating it
+		slices.SortFunc(out, func(a, b plan.TestCase) int {
+			return strings.Compare(a.Scope+"/"+a.Name, b.Scope+"/"+b.Name)
+		})
+		return out
+	})
+
+	if diff := cmp.Diff(got, want, sorter); diff != "" {
+		t.Errorf("Playwright.GetExamples(%q) diff (-got +want):\n%s", files, diff)
+	}
+}
+
+func TestPlaywrightGetTestResultsFromSuite(t *testing.T) {
+	data := `{
+		"title": "apple.spec.js",
+		"specs": [{
+			"title": "is sweet",
+			"ok": false,
+			"file": "apple.spec.js",
+			"line": 5,
+			"column": 3,
+			"tests": [{
+				"projectName": "chromium",
+				"results": [
+					{"status": "failed", "duration": 120, "errors": [{"message": "Error: first run"}]},
+					{"status": "failed", "duration": 100, "errors": [{"message": "Error: expected sweet"}, {"message": "Error: got sour"}]}
+				]
+			}]
+		}]
+	}`
+
+	var suite PlaywrightReportSuite
+	if err := json.Unmarshal([]byte(data), &suite); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	got := NewPlaywright(RunnerConfig{}).getTestResultsFromSuite(suite, suite.Title)
+	want := []TestResult{
+		{
+			TestCase: plan.TestCase{
+				Scope: " chromium apple.spec.js is sweet",
+				Path:  "apple.spec.js:5",
+				Name:  "is sweet",
+			},
+			Status:         TestStatusFailed,
+			Duration:       100 * time.Millisecond,
+			FailureMessage: "Error: expected sweet\nError: got sour",
+			File:           "apple.spec.js",
+			Line:           5,
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Playwright.getTestResultsFromSuite() diff (-got +want):\n%s", diff)
+	}
+}
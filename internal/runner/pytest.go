@@ -0,0 +1,308 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/debug"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/kballard/go-shellquote"
+)
+
+type Pytest struct {
+	RunnerConfig
+}
+
+func NewPytest(p RunnerConfig) Pytest {
+	if p.TestCommand == "" {
+		p.TestCommand = "pytest {{testExamples}} --junit-xml {{resultPath}}"
+	}
+
+	if p.TestFilePattern == "" {
+		p.TestFilePattern = "**/{test_*,*_test}.py"
+	}
+
+	if p.RetryTestCommand == "" {
+		p.RetryTestCommand = p.TestCommand
+	}
+
+	return Pytest{
+		RunnerConfig: p,
+	}
+}
+
+func (p Pytest) Name() string {
+	return "pytest"
+}
+
+// GetFiles returns an array of file names using the discovery pattern.
+func (p Pytest) GetFiles() ([]string, error) {
+	debug.Println("Discovering test files with include pattern:", p.TestFilePattern, "exclude pattern:", p.TestFileExcludePattern)
+	files, err := discoverTestFiles(p.TestFilePattern, p.TestFileExcludePattern)
+	debug.Println("Discovered", len(files), "files")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found with pattern %q and exclude pattern %q", p.TestFilePattern, p.TestFileExcludePattern)
+	}
+
+	return files, nil
+}
+
+// Run executes the test command with the given test cases.
+// If retry is true, it will run the command using the retry test command,
+// otherwise it will use the test command.
+// The test cases are passed to pytest as node IDs, e.g. "tests/test_fruits.py::TestApple::test_red".
+//
+// Error is returned if the command fails to run, exits prematurely, or if the
+// output cannot be parsed.
+//
+// Test failure is not considered an error, and is instead returned as a RunResult.
+func (p Pytest) Run(result *RunResult, testCases []plan.TestCase, retry bool) error {
+	command := p.TestCommand
+
+	if retry {
+		command = p.RetryTestCommand
+	}
+
+	testPaths := make([]string, len(testCases))
+	for i, tc := range testCases {
+		testPaths[i] = tc.Path
+	}
+
+	commandName, commandArgs, err := p.commandNameAndArgs(command, testPaths)
+	if err != nil {
+		result.err = err
+		return fmt.Errorf("failed to build command: %w", err)
+	}
+
+	cmd := exec.Command(commandName, commandArgs...)
+
+	err = p.run(cmd)
+
+	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
+		result.err = err
+		return err
+	}
+
+	testResults, parseErr := p.ParseReport(p.ResultPath)
+	if parseErr != nil {
+		fmt.Println("Buildkite Test Engine Client: Failed to read pytest output, tests will not be retried.")
+		result.err = err
+		return err
+	}
+
+	for _, testResult := range testResults {
+		result.RecordTestResult(testResult.TestCase, testResult.Status)
+	}
+
+	return nil
+}
+
+// PytestJSONTest represents a single test in a pytest-json-report report.
+type PytestJSONTest struct {
+	NodeId  string `json:"nodeid"`
+	Outcome string `json:"outcome"`
+}
+
+// PytestJSONReport is the structure for pytest-json-report JSON report.
+type PytestJSONReport struct {
+	Tests []PytestJSONTest `json:"tests"`
+}
+
+// ParseReport reads the report at the given path and returns the test results.
+// The report can be either a JUnit XML report, generated with the `--junit-xml` option,
+// or a JSON report, generated by the pytest-json-report plugin.
+func (p Pytest) ParseReport(path string) ([]TestResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pytest output: %v", err)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return p.parseJSONReport(data)
+	}
+
+	report, err := parseJUnitReport(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var testResults []TestResult
+	for _, testCase := range report.AllTestCases() {
+		testResults = append(testResults, TestResult{
+			TestCase: mapNodeIdToTestCase(p.junitNodeId(testCase)),
+			Status:   testCase.Status(),
+		})
+	}
+
+	return testResults, nil
+}
+
+func (p Pytest) parseJSONReport(data []byte) ([]TestResult, error) {
+	var report PytestJSONReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse pytest output: %s", err)
+	}
+
+	var testResults []TestResult
+	for _, test := range report.Tests {
+		var status TestStatus
+		switch test.Outcome {
+		case "failed", "error":
+			status = TestStatusFailed
+		case "passed", "xfailed", "xpassed":
+			status = TestStatusPassed
+		case "skipped":
+			status = TestStatusPending
+		}
+
+		testResults = append(testResults, TestResult{
+			TestCase: mapNodeIdToTestCase(test.NodeId),
+			Status:   status,
+		})
+	}
+
+	return testResults, nil
+}
+
+// junitNodeId rebuilds the pytest node ID of a JUnit testcase.
+// pytest writes the classname as the dotted module path followed by the class names,
+// e.g. "tests.fruits.test_apple.TestApple", so the longest prefix of the classname
+// that is an existing python file is the module, and the rest are the classes.
+func (p Pytest) junitNodeId(testCase JUnitTestCase) string {
+	parts := strings.Split(testCase.Classname, ".")
+
+	for i := len(parts); i > 0; i-- {
+		path := filepath.ToSlash(filepath.Join(parts[:i]...)) + ".py"
+		if _, err := os.Stat(path); err == nil {
+			return strings.Join(append([]string{path}, append(parts[i:], testCase.Name)...), "::")
+		}
+	}
+
+	// If the module can't be found, e.g. when the report is read from a different directory,
+	// assume that the classname doesn't contain any class.
+	return strings.Join(parts, "/") + ".py::" + testCase.Name
+}
+
+// commandNameAndArgs replaces the "{{testExamples}}" placeholder in the test command with the test cases,
+// and the "{{resultPath}}" placeholder with the result path.
+// It returns the command name and arguments to run the tests.
+func (p Pytest) commandNameAndArgs(cmd string, testCases []string) (string, []string, error) {
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return "", []string{}, err
+	}
+
+	idx := slices.Index(words, "{{testExamples}}")
+	if idx < 0 {
+		words = append(words, testCases...)
+	} else {
+		words = slices.Replace(words, idx, idx+1, testCases...)
+	}
+
+	idx = slices.Index(words, "{{resultPath}}")
+	if idx >= 0 {
+		words = slices.Replace(words, idx, idx+1, p.ResultPath)
+	}
+
+	return words[0], words[1:], nil
+}
+
+// GetExamples returns an array of test examples within the given files.
+// The examples are collected with `pytest --collect-only --verbosity=-1`, which prints the node ID of each test.
+func (p Pytest) GetExamples(files []string) ([]plan.TestCase, error) {
+	// Create a temporary file for the report, so the collection doesn't overwrite the result of a previous run.
+	f, err := os.CreateTemp("", "pytest-collect-*.xml")
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to create temporary file for pytest collection: %v", err)
+	}
+
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	collector := p
+	collector.ResultPath = f.Name()
+
+	cmdName, cmdArgs, err := collector.commandNameAndArgs(p.TestCommand, files)
+	if err != nil {
+		return nil, err
+	}
+
+	// The verbosity is set explicitly rather than with -q, which would only decrease the verbosity
+	// set by -q or -v in the test command or addopts, and list the tests in a different format than node IDs.
+	cmdArgs = append(cmdArgs, "--collect-only", "--verbosity=-1")
+
+	debug.Printf("Running `%s %s` to collect examples", cmdName, strings.Join(cmdArgs, " "))
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(cmdName, cmdArgs...)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to run pytest collection: %s%s", output, stderr.Bytes())
+	}
+
+	return parsePytestCollection(output), nil
+}
+
+// parsePytestCollection returns the test cases of the node IDs listed by `pytest --collect-only --verbosity=-1`.
+// The node IDs are followed by a blank line, then the "warnings summary" and "short test summary" sections,
+// which mention node IDs too, therefore the parsing stops at the first blank line or "=" banner after the node IDs.
+func parsePytestCollection(output []byte) []plan.TestCase {
+	var testCases []plan.TestCase
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "=") {
+			if len(testCases) > 0 {
+				break
+			}
+			continue
+		}
+
+		if !strings.Contains(line, "::") || strings.HasPrefix(line, " ") {
+			continue
+		}
+		testCases = append(testCases, mapNodeIdToTestCase(line))
+	}
+
+	return testCases
+}
+
+func mapNodeIdToTestCase(nodeId string) plan.TestCase {
+	// The scope and name has to match with the scope generated by Buildkite test collector.
+	// In Buildkite test collector, the node ID is split by "::",
+	// the last part is the name, and the rest is the scope.
+	// The parameters of a parametrized test, e.g. "test_eat[a::b]", are part of the name.
+	base := nodeId
+	if idx := strings.Index(nodeId, "["); idx >= 0 {
+		base = nodeId[:idx]
+	}
+
+	scope, name := nodeId, nodeId
+	if idx := strings.LastIndex(base, "::"); idx >= 0 {
+		scope, name = nodeId[:idx], nodeId[idx+2:]
+	}
+
+	return plan.TestCase{
+		Identifier: nodeId,
+		Name:       name,
+		Path:       nodeId,
+		Scope:      scope,
+	}
+}
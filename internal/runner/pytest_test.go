@@ -0,0 +1,402 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kballard/go-shellquote"
+)
+
+func TestNewPytest(t *testing.T) {
+	cases := []struct {
+		input RunnerConfig
+		want  RunnerConfig
+	}{
+		//default
+		{
+			input: RunnerConfig{},
+			want: RunnerConfig{
+				TestCommand:            "pytest {{testExamples}} --junit-xml {{resultPath}}",
+				TestFilePattern:        "**/{test_*,*_test}.py",
+				TestFileExcludePattern: "",
+				RetryTestCommand:       "pytest {{testExamples}} --junit-xml {{resultPath}}",
+			},
+		},
+		// custom
+		{
+			input: RunnerConfig{
+				TestCommand:            "poetry run pytest {{testExamples}} --json-report --json-report-file {{resultPath}}",
+				TestFilePattern:        "tests/unit/**/test_*.py",
+				TestFileExcludePattern: "tests/integration",
+				RetryTestCommand:       "poetry run pytest -x {{testExamples}} --json-report --json-report-file {{resultPath}}",
+			},
+			want: RunnerConfig{
+				TestCommand:            "poetry run pytest {{testExamples}} --json-report --json-report-file {{resultPath}}",
+				TestFilePattern:        "tests/unit/**/test_*.py",
+				TestFileExcludePattern: "tests/integration",
+				RetryTestCommand:       "poetry run pytest -x {{testExamples}} --json-report --json-report-file {{resultPath}}",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		got := NewPytest(c.input)
+		if diff := cmp.Diff(got.RunnerConfig, c.want); diff != "" {
+			t.Errorf("NewPytest(%v) diff (-got +want):\n%s", c.input, diff)
+		}
+	}
+}
+
+func TestPytestRun(t *testing.T) {
+	changeCwd(t, "./testdata/pytest")
+
+	pytest := NewPytest(RunnerConfig{
+		ResultPath: "result.xml",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(pytest.ResultPath)
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "tests/fruits/test_apple.py"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := pytest.Run(result, testCases, false)
+
+	if err != nil {
+		t.Errorf("Pytest.Run(%q) error = %v", testCases, err)
+	}
+
+	wantFailedTests := []plan.TestCase{
+		{
+			Identifier: "tests/fruits/test_apple.py::TestApple::test_is_sweet",
+			Name:       "test_is_sweet",
+			Path:       "tests/fruits/test_apple.py::TestApple::test_is_sweet",
+			Scope:      "tests/fruits/test_apple.py::TestApple",
+		},
+	}
+
+	if diff := cmp.Diff(result.FailedTests(), wantFailedTests); diff != "" {
+		t.Errorf("Pytest.Run(%q) RunResult.FailedTests() diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestPytestRun_SignaledError(t *testing.T) {
+	pytest := NewPytest(RunnerConfig{
+		TestCommand: "./testdata/segv.sh",
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "./doesnt-matter_test.py"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := pytest.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("Pytest.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	signalError := new(ProcessSignaledError)
+	if !errors.As(err, &signalError) {
+		t.Errorf("Pytest.Run(%q) error type = %T (%v), want *ErrProcessSignaled", testCases, err, err)
+	}
+}
+
+func TestPytestRun_CommandFailed(t *testing.T) {
+	pytest := NewPytest(RunnerConfig{
+		TestCommand: "false",
+		ResultPath:  "doesnt-exist.xml",
+	})
+
+	testCases := []plan.TestCase{}
+	result := NewRunResult([]plan.TestCase{})
+	err := pytest.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("Pytest.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	exitError := new(exec.ExitError)
+	if !errors.As(err, &exitError) {
+		t.Errorf("Pytest.Run(%q) error type = %T (%v), want *exec.ExitError", testCases, err, err)
+	}
+}
+
+func TestPytestParseReport(t *testing.T) {
+	changeCwd(t, "./testdata/pytest")
+
+	want := []TestResult{
+		{
+			TestCase: plan.TestCase{
+				Identifier: "tests/fruits/banana_test.py::test_color[yellow]",
+				Name:       "test_color[yellow]",
+				Path:       "tests/fruits/banana_test.py::test_color[yellow]",
+				Scope:      "tests/fruits/banana_test.py",
+			},
+			Status: TestStatusPassed,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "tests/fruits/banana_test.py::test_color[green]",
+				Name:       "test_color[green]",
+				Path:       "tests/fruits/banana_test.py::test_color[green]",
+				Scope:      "tests/fruits/banana_test.py",
+			},
+			Status: TestStatusPassed,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "tests/fruits/test_apple.py::TestApple::test_is_red",
+				Name:       "test_is_red",
+				Path:       "tests/fruits/test_apple.py::TestApple::test_is_red",
+				Scope:      "tests/fruits/test_apple.py::TestApple",
+			},
+			Status: TestStatusPassed,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "tests/fruits/test_apple.py::TestApple::test_is_sweet",
+				Name:       "test_is_sweet",
+				Path:       "tests/fruits/test_apple.py::TestApple::test_is_sweet",
+				Scope:      "tests/fruits/test_apple.py::TestApple",
+			},
+			Status: TestStatusFailed,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "tests/test_vegetable.py::test_tomato_is_a_fruit",
+				Name:       "test_tomato_is_a_fruit",
+				Path:       "tests/test_vegetable.py::test_tomato_is_a_fruit",
+				Scope:      "tests/test_vegetable.py",
+			},
+			Status: TestStatusPassed,
+		},
+		{
+			TestCase: plan.TestCase{
+				Identifier: "tests/test_vegetable.py::test_pumpkin",
+				Name:       "test_pumpkin",
+				Path:       "tests/test_vegetable.py::test_pumpkin",
+				Scope:      "tests/test_vegetable.py",
+			},
+			Status: TestStatusPending,
+		},
+	}
+
+	pytest := NewPytest(RunnerConfig{})
+
+	for _, path := range []string{"junit.xml", "report.json"} {
+		t.Run(path, func(t *testing.T) {
+			got, err := pytest.ParseReport(path)
+			if err != nil {
+				t.Errorf("Pytest.ParseReport(%q) error = %v", path, err)
+			}
+
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("Pytest.ParseReport(%q) diff (-got +want):\n%s", path, diff)
+			}
+		})
+	}
+}
+
+func TestPytestCommandNameAndArgs_WithInterpolationPlaceholder(t *testing.T) {
+	testCases := []string{"tests/test_a.py::test_one", "tests/test_b.py::TestB::test_two"}
+	testCommand := "pytest {{testExamples}} --junit-xml {{resultPath}}"
+
+	pytest := NewPytest(RunnerConfig{
+		TestCommand: testCommand,
+		ResultPath:  "junit.xml",
+	})
+
+	gotName, gotArgs, err := pytest.commandNameAndArgs(testCommand, testCases)
+	if err != nil {
+		t.Errorf("commandNameAndArgs(%q, %q) error = %v", testCases, testCommand, err)
+	}
+
+	wantName := "pytest"
+	wantArgs := []string{"tests/test_a.py::test_one", "tests/test_b.py::TestB::test_two", "--junit-xml", "junit.xml"}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+}
+
+func TestPytestCommandNameAndArgs_WithoutTestExamplesPlaceholder(t *testing.T) {
+	testCases := []string{"tests/test_a.py::test_one", "tests/test_b.py::TestB::test_two"}
+	testCommand := "pytest --junit-xml {{resultPath}}"
+
+	pytest := NewPytest(RunnerConfig{
+		TestCommand: testCommand,
+		ResultPath:  "junit.xml",
+	})
+
+	gotName, gotArgs, err := pytest.commandNameAndArgs(testCommand, testCases)
+	if err != nil {
+		t.Errorf("commandNameAndArgs(%q, %q) error = %v", testCases, testCommand, err)
+	}
+
+	wantName := "pytest"
+	wantArgs := []string{"--junit-xml", "junit.xml", "tests/test_a.py::test_one", "tests/test_b.py::TestB::test_two"}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+}
+
+func TestPytestCommandNameAndArgs_InvalidTestCommand(t *testing.T) {
+	testCases := []string{"tests/test_a.py::test_one"}
+	testCommand := "pytest --options '{{testExamples}}"
+
+	pytest := NewPytest(RunnerConfig{
+		TestCommand: testCommand,
+	})
+
+	gotName, gotArgs, err := pytest.commandNameAndArgs(testCommand, testCases)
+
+	wantName := ""
+	wantArgs := []string{}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("commandNameAndArgs() diff (-got +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("commandNameAndArgs() diff (-got +want):\n%s", diff)
+	}
+	if !errors.Is(err, shellquote.UnterminatedSingleQuoteError) {
+		t.Errorf("commandNameAndArgs() error = %v, want %v", err, shellquote.UnterminatedSingleQuoteError)
+	}
+}
+
+func TestPytestGetFiles(t *testing.T) {
+	changeCwd(t, "./testdata/pytest")
+	pytest := NewPytest(RunnerConfig{})
+
+	got, err := pytest.GetFiles()
+	if err != nil {
+		t.Errorf("Pytest.GetFiles() error = %v", err)
+	}
+
+	want := []string{
+		"tests/fruits/banana_test.py",
+		"tests/fruits/test_apple.py",
+		"tests/test_vegetable.py",
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Pytest.GetFiles() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestPytestGetExamples(t *testing.T) {
+	changeCwd(t, "./testdata/pytest")
+	pytest := NewPytest(RunnerConfig{})
+
+	files := []string{"tests/fruits/test_apple.py"}
+	got, err := pytest.GetExamples(files)
+
+	want := []plan.TestCase{
+		{
+			Identifier: "tests/fruits/test_apple.py::TestApple::test_is_red",
+			Name:       "test_is_red",
+			Path:       "tests/fruits/test_apple.py::TestApple::test_is_red",
+			Scope:      "tests/fruits/test_apple.py::TestApple",
+		},
+		{
+			Identifier: "tests/fruits/test_apple.py::TestApple::test_is_sweet",
+			Name:       "test_is_sweet",
+			Path:       "tests/fruits/test_apple.py::TestApple::test_is_sweet",
+			Scope:      "tests/fruits/test_apple.py::TestApple",
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Pytest.GetExamples(%q) error = %v", files, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Pytest.GetExamples(%q) diff (-got +want):\n%s", files, diff)
+	}
+}
+
+func TestPytestGetExamples_QuietTestCommand(t *testing.T) {
+	changeCwd(t, "./testdata/pytest")
+	pytest := NewPytest(RunnerConfig{
+		TestCommand: "pytest -q {{testExamples}} --junit-xml {{resultPath}}",
+	})
+
+	files := []string{"tests/fruits/test_apple.py"}
+	got, err := pytest.GetExamples(files)
+
+	want := []plan.TestCase{
+		{
+			Identifier: "tests/fruits/test_apple.py::TestApple::test_is_red",
+			Name:       "test_is_red",
+			Path:       "tests/fruits/test_apple.py::TestApple::test_is_red",
+			Scope:      "tests/fruits/test_apple.py::TestApple",
+		},
+		{
+			Identifier: "tests/fruits/test_apple.py::TestApple::test_is_sweet",
+			Name:       "test_is_sweet",
+			Path:       "tests/fruits/test_apple.py::TestApple::test_is_sweet",
+			Scope:      "tests/fruits/test_apple.py::TestApple",
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Pytest.GetExamples(%q) error = %v", files, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Pytest.GetExamples(%q) diff (-got +want):\n%s", files, diff)
+	}
+}
+
+func TestParsePytestCollection_WithWarnings(t *testing.T) {
+	output := `tests/fruits/test_apple.py::TestApple::test_is_red
+tests/fruits/test_apple.py::TestApple::test_is_sweet
+
+=============================== warnings summary ===============================
+tests/fruits/test_apple.py::TestApple::test_is_red
+  /app/tests/fruits/test_apple.py:5: DeprecationWarning: red is deprecated
+    warnings.warn("red is deprecated", DeprecationWarning)
+
+tests/fruits/test_banana.py::test_is_yellow
+  /app/tests/fruits/test_banana.py:3: UserWarning: banana is not ripe
+
+-- Docs: https://docs.pytest.org/en/stable/how-to/capture-warnings.html
+=========================== short test summary info ============================
+ERROR tests/fruits/test_cherry.py::test_is_red - ImportError
+2 tests collected, 1 error in 0.01s
+`
+
+	got := parsePytestCollection([]byte(output))
+
+	want := []plan.TestCase{
+		{
+			Identifier: "tests/fruits/test_apple.py::TestApple::test_is_red",
+			Name:       "test_is_red",
+			Path:       "tests/fruits/test_apple.py::TestApple::test_is_red",
+			Scope:      "tests/fruits/test_apple.py::TestApple",
+		},
+		{
+			Identifier: "tests/fruits/test_apple.py::TestApple::test_is_sweet",
+			Name:       "test_is_sweet",
+			Path:       "tests/fruits/test_apple.py::TestApple::test_is_sweet",
+			Scope:      "tests/fruits/test_apple.py::TestApple",
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("parsePytestCollection() diff (-got +want):\n%s", diff)
+	}
+}
@@ -92,7 +92,7 @@ func (r Rspec) Run(result *RunResult, testCases []plan.TestCase, retry bool) err
 
 	cmd := exec.Command(commandName, commandArgs...)
 
-	err = runAndForwardSignal(cmd)
+	err = r.run(cmd)
 
 	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
 		result.err = err
@@ -117,7 +117,13 @@ func (r Rspec) Run(result *RunResult, testCases []plan.TestCase, retry bool) err
 			status = TestStatusPassed
 		}
 
-		result.RecordTestResult(mapExampleToTestCase(example), status)
+		testCase := mapExampleToTestCase(example)
+		result.RecordTestResult(testCase, status)
+		result.RecordTestDuration(testCase, secondsToDuration(example.RunTime))
+		result.RecordTestLocation(testCase, example.FilePath, example.LineNumber)
+		if example.Exception != nil {
+			result.RecordTestFailure(testCase, example.Exception.FailureMessage())
+		}
 	}
 
 	return nil
@@ -132,6 +138,24 @@ type RspecExample struct {
 	FilePath        string  `json:"file_path"`
 	LineNumber      int     `json:"line_number"`
 	RunTime         float64 `json:"run_time"`
+	// Exception is the exception raised by a failed example.
+	Exception *RspecException `json:"exception,omitempty"`
+}
+
+// RspecException represents the exception of a failed example in an Rspec report.
+type RspecException struct {
+	Class     string   `json:"class"`
+	Message   string   `json:"message"`
+	Backtrace []string `json:"backtrace"`
+}
+
+// FailureMessage returns the class and message of the exception, followed by the backtrace.
+func (e RspecException) FailureMessage() string {
+	message := strings.TrimSpace(e.Message)
+	if e.Class != "" {
+		message = e.Class + ": " + message
+	}
+	return strings.Join(append([]string{message}, e.Backtrace...), "\n")
 }
 
 // RspecReport is the structure for Rspec JSON report.
@@ -4,8 +4,10 @@ import (
 	"errors"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/buildkite/test-engine-client/internal/plan"
 	"github.com/google/go-cmp/cmp"
@@ -150,6 +152,44 @@ func TestRspecRun_TestFailedWithResultFile(t *testing.T) {
 	}
 }
 
+func TestRspecRun_TestDetails(t *testing.T) {
+	rspec := NewRspec(RunnerConfig{
+		TestCommand: `sh -c 'cp "$0" "$1"' ./testdata/rspec/report.json {{resultPath}}`,
+		ResultPath:  filepath.Join(t.TempDir(), "rspec.json"),
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "./spec/fruits/apple_spec.rb"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := rspec.Run(result, testCases, false)
+
+	if err != nil {
+		t.Errorf("Rspec.Run(%q) error = %v", testCases, err)
+	}
+
+	sweet := plan.TestCase{Scope: "Apple", Name: "is sweet"}
+	want := TestResult{
+		TestCase: plan.TestCase{
+			Identifier: "./spec/fruits/apple_spec.rb[1:2]",
+			Name:       "is sweet",
+			Path:       "./spec/fruits/apple_spec.rb[1:2]",
+			Scope:      "Apple",
+		},
+		Status:         TestStatusFailed,
+		ExecutionCount: 1,
+		Duration:       500 * time.Millisecond,
+		FailureMessage: "RSpec::Expectations::ExpectationNotMetError: expected: \"sweet\"\n     got: \"sour\"\n\n(compared using ==)\n./spec/fruits/apple_spec.rb:7:in `block (2 levels) in <top (required)>'",
+		File:           "./spec/fruits/apple_spec.rb",
+		Line:           6,
+	}
+	want.Attempts = []TestAttempt{{Status: want.Status, Duration: want.Duration, FailureMessage: want.FailureMessage}}
+
+	if diff := cmp.Diff(*result.tests[testIdentifier(sweet)], want); diff != "" {
+		t.Errorf("Rspec.Run(%q) result of %q diff (-got +want):\n%s", testCases, sweet.Name, diff)
+	}
+}
+
 func TestRspecRun_TestFailedWithoutResultFile(t *testing.T) {
 	rspec := NewRspec(RunnerConfig{
 		TestCommand: "rspec",
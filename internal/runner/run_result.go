@@ -1,6 +1,12 @@
 package runner
 
 import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/buildkite/test-engine-client/internal/plan"
 )
 
@@ -63,17 +69,99 @@ func (r *RunResult) getTest(testCase plan.TestCase) *TestResult {
 	return test
 }
 
-// RecordTestResult records the result of a test case.
+// RecordTestResult records the result of a new execution of a test case.
 // If the test case found in the mutedTestLookup, it will be marked as muted.
 func (r *RunResult) RecordTestResult(testCase plan.TestCase, status TestStatus) {
 	test := r.getTest(testCase)
 	test.Status = status
 	test.ExecutionCount++
+	test.Duration = 0
+	test.FailureMessage = ""
+	test.Attempts = append(test.Attempts, TestAttempt{Status: status})
 	if r.mutedTestLookup[testIdentifier(testCase)] {
 		test.Muted = true
 	}
 }
 
+// RecordTestDuration records the duration of the latest execution of a test case.
+func (r *RunResult) RecordTestDuration(testCase plan.TestCase, duration time.Duration) {
+	test := r.getTest(testCase)
+	test.Duration = duration
+	if len(test.Attempts) > 0 {
+		test.Attempts[len(test.Attempts)-1].Duration = duration
+	}
+}
+
+// RecordTestFailure records the failure message of the latest execution of a test case.
+func (r *RunResult) RecordTestFailure(testCase plan.TestCase, message string) {
+	test := r.getTest(testCase)
+	test.FailureMessage = message
+	if len(test.Attempts) > 0 {
+		test.Attempts[len(test.Attempts)-1].FailureMessage = message
+	}
+}
+
+// RecordTestLocation records the file and line of a test case.
+func (r *RunResult) RecordTestLocation(testCase plan.TestCase, file string, line int) {
+	test := r.getTest(testCase)
+	test.File = file
+	test.Line = line
+}
+
+// recordTestResult records the result of a new execution of a test case,
+// along with the details reported by the test runner, i.e. its duration, failure message and location.
+func (r *RunResult) recordTestResult(testResult TestResult) {
+	r.RecordTestResult(testResult.TestCase, testResult.Status)
+	r.RecordTestDuration(testResult.TestCase, testResult.Duration)
+	if testResult.FailureMessage != "" {
+		r.RecordTestFailure(testResult.TestCase, testResult.FailureMessage)
+	}
+	if testResult.File != "" {
+		r.RecordTestLocation(testResult.TestCase, testResult.File, testResult.Line)
+	}
+	if testResult.TestFile != "" {
+		r.getTest(testResult.TestCase).TestFile = testResult.TestFile
+	}
+}
+
+// merge records the results of other into r, e.g. the results of a concurrent process.
+// The results of the latest execution of a test are overwritten by the ones in other,
+// and the executions in other are added to the history of the test.
+func (r *RunResult) merge(other *RunResult) {
+	for _, otherTest := range other.tests {
+		test := r.getTest(otherTest.TestCase)
+		test.Status = otherTest.Status
+		test.ExecutionCount += otherTest.ExecutionCount
+		test.Duration = otherTest.Duration
+		test.FailureMessage = otherTest.FailureMessage
+		test.Attempts = append(test.Attempts, otherTest.Attempts...)
+		if otherTest.File != "" {
+			test.File = otherTest.File
+			test.Line = otherTest.Line
+		}
+		if otherTest.TestFile != "" {
+			test.TestFile = otherTest.TestFile
+		}
+		if r.mutedTestLookup[testIdentifier(otherTest.TestCase)] {
+			test.Muted = true
+		}
+	}
+
+	if other.err != nil {
+		r.err = errors.Join(r.err, other.err)
+	}
+}
+
+// TestResults returns the results of all test cases in the run.
+func (r *RunResult) TestResults() []TestResult {
+	var testResults []TestResult
+	for _, test := range r.tests {
+		testResults = append(testResults, *test)
+	}
+
+	return testResults
+}
+
 // FailedTests returns a list of test cases that failed.
 func (r *RunResult) FailedTests() []plan.TestCase {
 	var failedTests []plan.TestCase
@@ -87,6 +175,38 @@ func (r *RunResult) FailedTests() []plan.TestCase {
 	return failedTests
 }
 
+// SlowestTests returns up to n test results with a reported duration, from the slowest to the fastest.
+func (r *RunResult) SlowestTests(n int) []TestResult {
+	var testResults []TestResult
+	for _, test := range r.tests {
+		if test.Duration > 0 {
+			testResults = append(testResults, *test)
+		}
+	}
+
+	slices.SortFunc(testResults, func(a, b TestResult) int {
+		if a.Duration != b.Duration {
+			return cmp.Compare(b.Duration, a.Duration)
+		}
+		return strings.Compare(testIdentifier(a.TestCase), testIdentifier(b.TestCase))
+	})
+
+	return testResults[:min(n, len(testResults))]
+}
+
+// FlakyTests returns the results of the test cases that failed on an attempt and then passed on retry.
+// Muted tests are excluded, because they are already known to be flaky.
+func (r *RunResult) FlakyTests() []TestResult {
+	var flakyTests []TestResult
+	for _, test := range r.tests {
+		if !test.Muted && test.Flaky() {
+			flakyTests = append(flakyTests, *test)
+		}
+	}
+
+	return flakyTests
+}
+
 func (r *RunResult) MutedTests() []TestResult {
 	var mutedTests []TestResult
 	for _, test := range r.tests {
@@ -137,7 +257,7 @@ func (r *RunResult) Statistics() RunStatistics {
 			}
 
 		case testResult.Status == TestStatusPassed:
-			if testResult.ExecutionCount > 1 {
+			if testResult.Flaky() {
 				passedOnRetry++
 			} else {
 				passedOnFirstRun++
@@ -4,6 +4,7 @@ import (
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/buildkite/test-engine-client/internal/plan"
 	"github.com/google/go-cmp/cmp"
@@ -47,6 +48,123 @@ func TestRecordTestResult_MultipleExecution(t *testing.T) {
 	}
 }
 
+func TestRecordTestDuration(t *testing.T) {
+	r := NewRunResult([]plan.TestCase{})
+
+	testCase := plan.TestCase{Scope: "apple", Name: "is red"}
+	identifier := testIdentifier(testCase)
+	r.RecordTestResult(testCase, TestStatusFailed)
+	r.RecordTestDuration(testCase, 2*time.Second)
+	r.RecordTestResult(testCase, TestStatusPassed)
+	r.RecordTestDuration(testCase, 1*time.Second)
+
+	// It set the last execution duration
+	if r.tests[identifier].Duration != 1*time.Second {
+		t.Errorf("%q duration is %v, want %v", "apple/is red", r.tests[identifier].Duration, 1*time.Second)
+	}
+}
+
+func TestRecordTestFailure(t *testing.T) {
+	r := NewRunResult([]plan.TestCase{})
+
+	testCase := plan.TestCase{Scope: "apple", Name: "is red"}
+	identifier := testIdentifier(testCase)
+	r.RecordTestResult(testCase, TestStatusFailed)
+	r.RecordTestDuration(testCase, 2*time.Second)
+	r.RecordTestFailure(testCase, "expected red, got green")
+	r.RecordTestLocation(testCase, "spec/apple_spec.rb", 3)
+	r.RecordTestResult(testCase, TestStatusPassed)
+	r.RecordTestDuration(testCase, 1*time.Second)
+
+	want := TestResult{
+		TestCase:       testCase,
+		Status:         TestStatusPassed,
+		ExecutionCount: 2,
+		Duration:       1 * time.Second,
+		File:           "spec/apple_spec.rb",
+		Line:           3,
+		Attempts: []TestAttempt{
+			{Status: TestStatusFailed, Duration: 2 * time.Second, FailureMessage: "expected red, got green"},
+			{Status: TestStatusPassed, Duration: 1 * time.Second},
+		},
+	}
+
+	// It keeps the failure message in the history, but not as the latest result.
+	if diff := cmp.Diff(*r.tests[identifier], want); diff != "" {
+		t.Errorf("%q diff (-got +want):\n%s", "apple/is red", diff)
+	}
+
+	if got := r.tests[identifier].Location(); got != "spec/apple_spec.rb:3" {
+		t.Errorf("%q location is %q, want %q", "apple/is red", got, "spec/apple_spec.rb:3")
+	}
+}
+
+func TestTestResults(t *testing.T) {
+	r := NewRunResult([]plan.TestCase{})
+
+	apple := plan.TestCase{Scope: "apple", Name: "is red"}
+	banana := plan.TestCase{Scope: "banana", Name: "is yellow"}
+	r.RecordTestResult(apple, TestStatusPassed)
+	r.RecordTestDuration(apple, 1*time.Second)
+	r.RecordTestResult(banana, TestStatusFailed)
+
+	got := r.TestResults()
+	slices.SortFunc(got, func(a, b TestResult) int {
+		return strings.Compare(a.Scope, b.Scope)
+	})
+
+	want := []TestResult{
+		{
+			TestCase:       apple,
+			Status:         TestStatusPassed,
+			ExecutionCount: 1,
+			Duration:       1 * time.Second,
+			Attempts:       []TestAttempt{{Status: TestStatusPassed, Duration: 1 * time.Second}},
+		},
+		{
+			TestCase:       banana,
+			Status:         TestStatusFailed,
+			ExecutionCount: 1,
+			Attempts:       []TestAttempt{{Status: TestStatusFailed}},
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("TestResults() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestSlowestTests(t *testing.T) {
+	r := NewRunResult([]plan.TestCase{})
+
+	apple := plan.TestCase{Scope: "apple", Name: "is red"}
+	banana := plan.TestCase{Scope: "banana", Name: "is yellow"}
+	cherry := plan.TestCase{Scope: "cherry", Name: "is sour"}
+	durian := plan.TestCase{Scope: "durian", Name: "is smelly"}
+	r.RecordTestResult(apple, TestStatusPassed)
+	r.RecordTestDuration(apple, 1*time.Second)
+	r.RecordTestResult(banana, TestStatusFailed)
+	r.RecordTestDuration(banana, 3*time.Second)
+	r.RecordTestResult(cherry, TestStatusPassed)
+	r.RecordTestDuration(cherry, 2*time.Second)
+	// durian doesn't report a duration, so it is not listed
+	r.RecordTestResult(durian, TestStatusPassed)
+
+	var got []string
+	for _, test := range r.SlowestTests(2) {
+		got = append(got, testIdentifier(test.TestCase))
+	}
+
+	want := []string{"banana/is yellow", "cherry/is sour"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("SlowestTests(2) diff (-got +want):\n%s", diff)
+	}
+
+	if got := len(r.SlowestTests(10)); got != 3 {
+		t.Errorf("len(SlowestTests(10)) = %d, want %d", got, 3)
+	}
+}
+
 func TestFailedTests(t *testing.T) {
 	r := NewRunResult([]plan.TestCase{})
 
@@ -141,6 +259,39 @@ func TestFailedTests_ExcludeMutedTests(t *testing.T) {
 	}
 }
 
+func TestFlakyTests(t *testing.T) {
+	mutedApple := plan.TestCase{Scope: "apple", Name: "is sweet"}
+	r := NewRunResult([]plan.TestCase{mutedApple})
+
+	apple := plan.TestCase{Scope: "apple", Name: "is red"}
+	banana := plan.TestCase{Scope: "banana", Name: "is yellow"}
+	cherry := plan.TestCase{Scope: "cherry", Name: "is sour"}
+	// apple is flaky
+	r.RecordTestResult(apple, TestStatusFailed)
+	r.RecordTestResult(apple, TestStatusPassed)
+	// banana failed on every attempt
+	r.RecordTestResult(banana, TestStatusFailed)
+	r.RecordTestResult(banana, TestStatusFailed)
+	// cherry passed on the first attempt
+	r.RecordTestResult(cherry, TestStatusPassed)
+	// muted tests are not reported as flaky
+	r.RecordTestResult(mutedApple, TestStatusFailed)
+	r.RecordTestResult(mutedApple, TestStatusPassed)
+
+	want := []TestResult{
+		{
+			TestCase:       apple,
+			Status:         TestStatusPassed,
+			ExecutionCount: 2,
+			Attempts:       []TestAttempt{{Status: TestStatusFailed}, {Status: TestStatusPassed}},
+		},
+	}
+
+	if diff := cmp.Diff(r.FlakyTests(), want); diff != "" {
+		t.Errorf("FlakyTests() diff (-got +want):\n%s", diff)
+	}
+}
+
 func TestMutedTests(t *testing.T) {
 
 	apple := plan.TestCase{Scope: "apple", Name: "is red"}
@@ -160,7 +311,7 @@ func TestMutedTests(t *testing.T) {
 	}
 
 	wantMutedTest := []TestResult{
-		{TestCase: apple, Status: TestStatusFailed, ExecutionCount: 1, Muted: true},
+		{TestCase: apple, Status: TestStatusFailed, ExecutionCount: 1, Muted: true, Attempts: []TestAttempt{{Status: TestStatusFailed}}},
 	}
 
 	if diff := cmp.Diff(mutedTests, wantMutedTest); diff != "" {
@@ -176,9 +327,11 @@ func TestRunStatistics(t *testing.T) {
 		{Scope: "cat", Name: "is not a fruit"}, // unrecorded (not related to this run) test case should be ignored
 	})
 
-	// passed on first run: 2
+	// passed on first run: 3
 	r.RecordTestResult(plan.TestCase{Scope: "apple", Name: "is red"}, TestStatusPassed)
 	r.RecordTestResult(plan.TestCase{Scope: "mango", Name: "is red"}, TestStatusPassed)
+	r.RecordTestResult(plan.TestCase{Scope: "cherry", Name: "is red"}, TestStatusPassed)
+	r.RecordTestResult(plan.TestCase{Scope: "cherry", Name: "is red"}, TestStatusPassed) // test ran twice without failing
 
 	//passed on retry: 1
 	r.RecordTestResult(plan.TestCase{Scope: "apple", Name: "is green"}, TestStatusFailed)
@@ -195,8 +348,8 @@ func TestRunStatistics(t *testing.T) {
 	stats := r.Statistics()
 
 	if diff := cmp.Diff(stats, RunStatistics{
-		Total:            6,
-		PassedOnFirstRun: 2,
+		Total:            7,
+		PassedOnFirstRun: 3,
 		PassedOnRetry:    1,
 		MutedPassed:      1,
 		MutedFailed:      1,
@@ -205,3 +358,33 @@ func TestRunStatistics(t *testing.T) {
 		t.Errorf("Statistics() diff (-got +want):\n%s", diff)
 	}
 }
+
+func TestTestResultTestFilePath(t *testing.T) {
+	cases := []struct {
+		testResult TestResult
+		want       string
+	}{
+		{
+			testResult: TestResult{TestCase: plan.TestCase{Path: "./spec/apple_spec.rb[1:1]"}, File: "./spec/apple_spec.rb"},
+			want:       "./spec/apple_spec.rb[1:1]",
+		},
+		{
+			testResult: TestResult{File: "src/apple.test.js"},
+			want:       "src/apple.test.js",
+		},
+		{
+			testResult: TestResult{TestCase: plan.TestCase{Path: "example.com/app/fruits"}, File: "./fruits", TestFile: "./fruits"},
+			want:       "./fruits",
+		},
+		{
+			testResult: TestResult{TestCase: plan.TestCase{Path: "example.com/other/fruits"}},
+			want:       "example.com/other/fruits",
+		},
+	}
+
+	for _, tc := range cases {
+		if got := tc.testResult.TestFilePath(); got != tc.want {
+			t.Errorf("TestResult.TestFilePath() of %v = %q, want %q", tc.testResult, got, tc.want)
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package runner
 
-import "github.com/buildkite/test-engine-client/internal/plan"
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+)
 
 type TestStatus string
 
@@ -16,6 +22,63 @@ type TestResult struct {
 	Status         TestStatus
 	ExecutionCount int
 	Muted          bool
+	// Duration is the duration of the latest execution of the test case.
+	// It is zero if the test runner doesn't report the duration of the tests.
+	Duration time.Duration
+	// FailureMessage is the failure message of the latest execution of the test case, followed by the backtrace when available.
+	// It is empty if the latest execution didn't fail, or the test runner doesn't report failure messages.
+	FailureMessage string
+	// File and Line are the location of the test case, when reported by the test runner.
+	File string
+	Line int
+	// TestFile is the path of the test file in the same form as GetFiles, when it differs from the path of the test case,
+	// e.g. go test reports the import path of the package as the path, while GetFiles returns its directory.
+	TestFile string
+	// Attempts is the history of the executions of the test case, from the first to the latest.
+	Attempts []TestAttempt
+}
+
+// TestAttempt is the result of a single execution of a test case.
+type TestAttempt struct {
+	Status         TestStatus
+	Duration       time.Duration
+	FailureMessage string
+}
+
+// Flaky returns true if the test case passed after failing in an earlier attempt.
+func (t TestResult) Flaky() bool {
+	return t.Status == TestStatusPassed && slices.ContainsFunc(t.Attempts, func(a TestAttempt) bool { return a.Status == TestStatusFailed })
+}
+
+// Location returns the location of the test case as "file:line",
+// or an empty string if the test runner doesn't report it.
+func (t TestResult) Location() string {
+	switch {
+	case t.File == "":
+		return ""
+	case t.Line == 0:
+		return t.File
+	default:
+		return fmt.Sprintf("%s:%d", t.File, t.Line)
+	}
+}
+
+// TestFilePath returns the path identifying the test file of the test case, in the same form as GetFiles.
+// Most test runners report it as the path of the test case, except Jest which only reports it as the file.
+func (t TestResult) TestFilePath() string {
+	switch {
+	case t.TestFile != "":
+		return t.TestFile
+	case t.Path != "":
+		return t.Path
+	default:
+		return t.File
+	}
+}
+
+// secondsToDuration converts the duration in seconds, as reported by most test runners, to time.Duration.
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
 }
 
 func testIdentifier(testCase plan.TestCase) string {
@@ -0,0 +1,29 @@
+#!/usr/bin/env sh
+
+# This is an example of a custom runner adapter used to test the custom runner.
+# It reads the request from stdin and writes a canned response to stdout.
+# A real adapter would parse the request as JSON and run the tests.
+request=$(cat)
+
+case "$request" in
+  *'"method":"name"'*)
+    echo '{"name": "Fruit"}'
+    ;;
+  *'"method":"get_files"'*)
+    echo '{"files": ["fruits/apple.fruit", "fruits/banana.fruit"]}'
+    ;;
+  *'"method":"get_examples"'*)
+    echo '{"examples": [{"path": "fruits/apple.fruit:1", "scope": "apple", "name": "is red"}, {"path": "fruits/apple.fruit:5", "scope": "apple", "name": "is sweet"}]}'
+    ;;
+  *'"method":"run"'*'"retry":true'*)
+    echo "Retrying apple is sweet" >&2
+    echo '{"results": [{"path": "fruits/apple.fruit:5", "scope": "apple", "name": "is sweet", "status": "passed"}]}'
+    ;;
+  *'"method":"run"'*)
+    echo "Running apple is red, apple is sweet, banana is yellow" >&2
+    echo '{"results": [{"path": "fruits/apple.fruit:1", "scope": "apple", "name": "is red", "status": "passed", "duration": 0.5}, {"path": "fruits/apple.fruit:5", "scope": "apple", "name": "is sweet", "status": "failed", "failure_message": "expected sweet, got sour", "file": "fruits/apple.fruit", "line": 5}, {"path": "fruits/banana.fruit:1", "scope": "banana", "name": "is yellow", "status": "pending"}]}'
+    ;;
+  *)
+    echo '{"error": "unknown method"}'
+    ;;
+esac
@@ -0,0 +1,17 @@
+package fruits
+
+import "testing"
+
+func TestApple(t *testing.T) {
+	if color := "red"; color != "red" {
+		t.Error("apple is not red")
+	}
+}
+
+func TestBanana(t *testing.T) {
+	t.Run("yellow", func(t *testing.T) {})
+
+	t.Run("green", func(t *testing.T) {
+		t.Error("banana is not green")
+	})
+}
@@ -0,0 +1,9 @@
+package vegetables
+
+import "testing"
+
+func TestTomato(t *testing.T) {}
+
+func TestPumpkin(t *testing.T) {
+	t.Skip("pumpkin is not ready")
+}
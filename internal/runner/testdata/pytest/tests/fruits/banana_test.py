@@ -0,0 +1,6 @@
+import pytest
+
+
+@pytest.mark.parametrize("color", ["yellow", "green"])
+def test_color(color):
+    assert color in ["yellow", "green"]
@@ -0,0 +1,6 @@
+class TestApple:
+    def test_is_red(self):
+        assert True
+
+    def test_is_sweet(self):
+        assert False
@@ -0,0 +1,10 @@
+import pytest
+
+
+def test_tomato_is_a_fruit():
+    assert True
+
+
+@pytest.mark.skip(reason="not in season")
+def test_pumpkin():
+    assert True
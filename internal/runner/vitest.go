@@ -0,0 +1,221 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/debug"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/kballard/go-shellquote"
+)
+
+type Vitest struct {
+	RunnerConfig
+}
+
+func NewVitest(v RunnerConfig) Vitest {
+	if v.TestCommand == "" {
+		v.TestCommand = "npx vitest run {{testExamples}} --reporter=json --outputFile={{resultPath}}"
+	}
+
+	// Vitest finds the same test files as Jest by default.
+	if v.TestFilePattern == "" {
+		v.TestFilePattern = NewJest(RunnerConfig{}).TestFilePattern
+	}
+
+	if v.RetryTestCommand == "" {
+		v.RetryTestCommand = "npx vitest run {{testExamples}} -t '{{testNamePattern}}' --reporter=json --outputFile={{resultPath}}"
+	}
+
+	return Vitest{
+		RunnerConfig: v,
+	}
+}
+
+func (v Vitest) Name() string {
+	return "Vitest"
+}
+
+// GetFiles returns an array of file names using the discovery pattern.
+func (v Vitest) GetFiles() ([]string, error) {
+	debug.Println("Discovering test files with include pattern:", v.TestFilePattern, "exclude pattern:", v.TestFileExcludePattern)
+	files, err := discoverTestFiles(v.TestFilePattern, v.TestFileExcludePattern)
+	debug.Println("Discovered", len(files), "files")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found with pattern %q and exclude pattern %q", v.TestFilePattern, v.TestFileExcludePattern)
+	}
+
+	return files, nil
+}
+
+// Run executes the test command with the given test cases.
+// If retry is true, it will run the command using the retry test command,
+// with the files of the failed tests and a `-t` pattern matching their names.
+// Vitest reports the tests that don't match the pattern as skipped,
+// therefore only the results of the retried tests are recorded when retrying.
+//
+// Error is returned if the command fails to run, exits prematurely, or if the
+// output cannot be parsed.
+//
+// Test failure is not considered an error, and is instead returned as a RunResult.
+func (v Vitest) Run(result *RunResult, testCases []plan.TestCase, retry bool) error {
+	testPaths := []string{}
+	for _, tc := range testCases {
+		if !slices.Contains(testPaths, tc.Path) {
+			testPaths = append(testPaths, tc.Path)
+		}
+	}
+
+	var commandName string
+	var commandArgs []string
+	var err error
+
+	if retry {
+		testNames := make([]string, len(testCases))
+		for i, tc := range testCases {
+			testNames[i] = strings.TrimLeft(fmt.Sprintf("%s %s", tc.Scope, tc.Name), " ")
+		}
+		commandName, commandArgs, err = v.retryCommandNameAndArgs(v.RetryTestCommand, testPaths, testNames)
+	} else {
+		commandName, commandArgs, err = v.commandNameAndArgs(v.TestCommand, testPaths)
+	}
+
+	if err != nil {
+		result.err = err
+		return fmt.Errorf("failed to build command: %w", err)
+	}
+
+	cmd := exec.Command(commandName, commandArgs...)
+
+	err = v.run(cmd)
+
+	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
+		result.err = err
+		return err
+	}
+
+	report, parseErr := v.ParseReport(v.ResultPath)
+	if parseErr != nil {
+		fmt.Println("Buildkite Test Engine Client: Failed to read Vitest output, tests will not be retried.")
+		result.err = err
+		return err
+	}
+
+	retriedTests := map[string]bool{}
+	if retry {
+		for _, tc := range testCases {
+			retriedTests[testIdentifier(tc)] = true
+		}
+	}
+
+	for _, testResult := range report.TestResults {
+		path := relativePath(testResult.Name)
+
+		for _, example := range testResult.AssertionResults {
+			var status TestStatus
+			switch example.Status {
+			case "failed":
+				status = TestStatusFailed
+			case "passed":
+				status = TestStatusPassed
+			case "skipped", "pending", "todo":
+				status = TestStatusPending
+			}
+
+			testCase := mapJestExampleToTestCase(example)
+			testCase.Path = path
+
+			if retry && !retriedTests[testIdentifier(testCase)] {
+				continue
+			}
+			result.recordTestResult(example.testResult(testCase, status, path))
+		}
+	}
+
+	return nil
+}
+
+// ParseReport reads the Vitest JSON report at the given path.
+// The report generated by the Vitest json reporter has the same shape as the Jest report.
+func (v Vitest) ParseReport(path string) (JestReport, error) {
+	var report JestReport
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return JestReport{}, fmt.Errorf("failed to read Vitest output: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &report); err != nil {
+		return JestReport{}, fmt.Errorf("failed to parse Vitest output: %s", err)
+	}
+
+	return report, nil
+}
+
+// commandNameAndArgs replaces the "{{testExamples}}" placeholder in the test command with the test cases,
+// and the "{{resultPath}}" placeholder with the result path.
+// Unlike the other placeholders, "{{resultPath}}" can be part of a word, e.g. "--outputFile={{resultPath}}".
+func (v Vitest) commandNameAndArgs(cmd string, testCases []string) (string, []string, error) {
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return "", []string{}, err
+	}
+
+	idx := slices.Index(words, "{{testExamples}}")
+	if idx < 0 {
+		words = append(words, testCases...)
+	} else {
+		words = slices.Replace(words, idx, idx+1, testCases...)
+	}
+
+	for i, word := range words {
+		words[i] = strings.ReplaceAll(word, "{{resultPath}}", v.ResultPath)
+	}
+
+	return words[0], words[1:], nil
+}
+
+// retryCommandNameAndArgs replaces the "{{testNamePattern}}" placeholder in the retry command
+// with a pattern matching the given test names, in addition to the placeholders of the test command.
+func (v Vitest) retryCommandNameAndArgs(cmd string, testCases []string, testNames []string) (string, []string, error) {
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return "", []string{}, err
+	}
+
+	idx := slices.Index(words, "{{testNamePattern}}")
+	if idx < 0 {
+		err := fmt.Errorf("couldn't find '{{testNamePattern}}' sentinel in retry command")
+		return "", []string{}, err
+	}
+	words = slices.Replace(words, idx, idx+1, testNamePattern(testNames))
+
+	idx = slices.Index(words, "{{testExamples}}")
+	if idx >= 0 {
+		words = slices.Replace(words, idx, idx+1, testCases...)
+	}
+
+	if !slices.ContainsFunc(words, func(word string) bool { return strings.Contains(word, "{{resultPath}}") }) {
+		err := fmt.Errorf("couldn't find '{{resultPath}}' sentinel in retry command, exiting.")
+		return "", []string{}, err
+	}
+
+	for i, word := range words {
+		words[i] = strings.ReplaceAll(word, "{{resultPath}}", v.ResultPath)
+	}
+
+	return words[0], words[1:], nil
+}
+
+func (v Vitest) GetExamples(files []string) ([]plan.TestCase, error) {
+	return nil, fmt.Errorf("not supported in Vitest")
+}
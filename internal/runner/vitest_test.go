@@ -0,0 +1,310 @@
+package runner
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/kballard/go-shellquote"
+)
+
+// vitestReportCommand writes the Vitest report fixture to the result path,
+// with the test file paths relative to the current working directory.
+const vitestReportCommand = `sh -c 'sed "s|/ROOT|$PWD|g" "$0" > "$1"' ./testdata/vitest/report.json {{resultPath}}`
+
+func TestNewVitest(t *testing.T) {
+	cases := []struct {
+		input RunnerConfig
+		want  RunnerConfig
+	}{
+		//default
+		{
+			input: RunnerConfig{},
+			want: RunnerConfig{
+				TestCommand:            "npx vitest run {{testExamples}} --reporter=json --outputFile={{resultPath}}",
+				TestFilePattern:        "**/{__tests__/**/*,*.spec,*.test}.{ts,js,tsx,jsx}",
+				TestFileExcludePattern: "",
+				RetryTestCommand:       "npx vitest run {{testExamples}} -t '{{testNamePattern}}' --reporter=json --outputFile={{resultPath}}",
+			},
+		},
+		// custom
+		{
+			input: RunnerConfig{
+				TestCommand:            "yarn vitest run {{testExamples}} --reporter=json --outputFile={{resultPath}}",
+				TestFilePattern:        "src/**/*.test.ts",
+				TestFileExcludePattern: "src/e2e",
+				RetryTestCommand:       "yarn vitest run -t '{{testNamePattern}}' --reporter=json --outputFile={{resultPath}}",
+			},
+			want: RunnerConfig{
+				TestCommand:            "yarn vitest run {{testExamples}} --reporter=json --outputFile={{resultPath}}",
+				TestFilePattern:        "src/**/*.test.ts",
+				TestFileExcludePattern: "src/e2e",
+				RetryTestCommand:       "yarn vitest run -t '{{testNamePattern}}' --reporter=json --outputFile={{resultPath}}",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		got := NewVitest(c.input)
+		if diff := cmp.Diff(got.RunnerConfig, c.want); diff != "" {
+			t.Errorf("NewVitest(%v) diff (-got +want):\n%s", c.input, diff)
+		}
+	}
+}
+
+func TestVitestRun(t *testing.T) {
+	vitest := NewVitest(RunnerConfig{
+		TestCommand: vitestReportCommand,
+		ResultPath:  filepath.Join(t.TempDir(), "vitest.json"),
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "testdata/vitest/src/apple.test.ts"},
+		{Path: "testdata/vitest/src/banana.test.ts"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := vitest.Run(result, testCases, false)
+
+	if err != nil {
+		t.Errorf("Vitest.Run(%q) error = %v", testCases, err)
+	}
+
+	if result.Status() != RunStatusFailed {
+		t.Errorf("Vitest.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusFailed)
+	}
+
+	wantFailedTests := []plan.TestCase{
+		{Name: "is sweet", Path: "src/apple.test.ts", Scope: "Apple"},
+	}
+
+	if diff := cmp.Diff(result.FailedTests(), wantFailedTests); diff != "" {
+		t.Errorf("Vitest.Run(%q) RunResult.FailedTests() diff (-got +want):\n%s", testCases, diff)
+	}
+
+	if got := result.Statistics().Total; got != 4 {
+		t.Errorf("Vitest.Run(%q) RunResult.Statistics().Total = %d, want %d", testCases, got, 4)
+	}
+
+	sweet := result.tests[testIdentifier(wantFailedTests[0])]
+	if got, want := sweet.FailureMessage, "AssertionError: expected 'sour' to be 'sweet'"; got != want {
+		t.Errorf("Vitest.Run(%q) failure message of %q = %q, want %q", testCases, sweet.Name, got, want)
+	}
+
+	if got, want := sweet.Location(), "src/apple.test.ts:6"; got != want {
+		t.Errorf("Vitest.Run(%q) location of %q = %q, want %q", testCases, sweet.Name, got, want)
+	}
+
+	if got, want := sweet.Duration, 2*time.Millisecond; got != want {
+		t.Errorf("Vitest.Run(%q) duration of %q = %v, want %v", testCases, sweet.Name, got, want)
+	}
+}
+
+func TestVitestRun_Retry(t *testing.T) {
+	// The report contains tests that are not being retried.
+	vitest := NewVitest(RunnerConfig{
+		RetryTestCommand: vitestReportCommand + " -t {{testNamePattern}}",
+		ResultPath:       filepath.Join(t.TempDir(), "vitest.json"),
+	})
+
+	testCases := []plan.TestCase{
+		{Name: "is sweet", Path: "src/apple.test.ts", Scope: "Apple"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := vitest.Run(result, testCases, true)
+
+	if err != nil {
+		t.Errorf("Vitest.Run(%q) error = %v", testCases, err)
+	}
+
+	if len(result.tests) != 1 {
+		t.Errorf("Vitest.Run(%q) len(RunResult.tests) = %d, want 1", testCases, len(result.tests))
+	}
+}
+
+func TestVitestRun_CommandFailed(t *testing.T) {
+	vitest := NewVitest(RunnerConfig{
+		TestCommand: "false",
+		ResultPath:  filepath.Join(t.TempDir(), "doesnt-exist.json"),
+	})
+
+	testCases := []plan.TestCase{}
+	result := NewRunResult([]plan.TestCase{})
+	err := vitest.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("Vitest.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	exitError := new(exec.ExitError)
+	if !errors.As(err, &exitError) {
+		t.Errorf("Vitest.Run(%q) error type = %T (%v), want *exec.ExitError", testCases, err, err)
+	}
+}
+
+func TestVitestRun_SignaledError(t *testing.T) {
+	vitest := NewVitest(RunnerConfig{
+		TestCommand: "./testdata/segv.sh",
+	})
+
+	testCases := []plan.TestCase{
+		{Path: "./doesnt-matter.test.ts"},
+	}
+	result := NewRunResult([]plan.TestCase{})
+	err := vitest.Run(result, testCases, false)
+
+	if result.Status() != RunStatusError {
+		t.Errorf("Vitest.Run(%q) RunResult.Status = %v, want %v", testCases, result.Status(), RunStatusError)
+	}
+
+	signalError := new(ProcessSignaledError)
+	if !errors.As(err, &signalError) {
+		t.Errorf("Vitest.Run(%q) error type = %T (%v), want *ErrProcessSignaled", testCases, err, err)
+	}
+}
+
+func TestVitestParseReport(t *testing.T) {
+	vitest := NewVitest(RunnerConfig{})
+
+	report, err := vitest.ParseReport("./testdata/vitest/report.json")
+	if err != nil {
+		t.Errorf("Vitest.ParseReport() error = %v", err)
+	}
+
+	var got []JestExample
+	for _, testResult := range report.TestResults {
+		got = append(got, testResult.AssertionResults...)
+	}
+
+	want := []JestExample{
+		{Name: "Apple is red", Status: "passed", Title: "is red", AncestorTitles: []string{"Apple"}, Duration: 1, FailureMessages: []string{}},
+		{
+			Name:            "Apple is sweet",
+			Status:          "failed",
+			Title:           "is sweet",
+			AncestorTitles:  []string{"Apple"},
+			Duration:        2,
+			FailureMessages: []string{"AssertionError: expected 'sour' to be 'sweet'"},
+			Location:        &JestLocation{Line: 6, Column: 3},
+		},
+		{Name: "banana is yellow", Status: "passed", Title: "banana is yellow", AncestorTitles: []string{}, Duration: 1, FailureMessages: []string{}},
+		{Name: "banana is ripe", Status: "skipped", Title: "banana is ripe", AncestorTitles: []string{}, FailureMessages: []string{}},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Vitest.ParseReport() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestVitestCommandNameAndArgs(t *testing.T) {
+	testCases := []string{"src/apple.test.ts", "src/banana.test.ts"}
+	testCommand := "npx vitest run {{testExamples}} --reporter=json --outputFile={{resultPath}}"
+
+	vitest := NewVitest(RunnerConfig{
+		TestCommand: testCommand,
+		ResultPath:  "vitest.json",
+	})
+
+	gotName, gotArgs, err := vitest.commandNameAndArgs(testCommand, testCases)
+	if err != nil {
+		t.Errorf("commandNameAndArgs(%q, %q) error = %v", testCases, testCommand, err)
+	}
+
+	wantName := "npx"
+	wantArgs := []string{"vitest", "run", "src/apple.test.ts", "src/banana.test.ts", "--reporter=json", "--outputFile=vitest.json"}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+}
+
+func TestVitestCommandNameAndArgs_InvalidTestCommand(t *testing.T) {
+	testCases := []string{"src/apple.test.ts"}
+	testCommand := "npx vitest run --options '{{testExamples}}"
+
+	vitest := NewVitest(RunnerConfig{
+		TestCommand: testCommand,
+	})
+
+	gotName, gotArgs, err := vitest.commandNameAndArgs(testCommand, testCases)
+
+	wantName := ""
+	wantArgs := []string{}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("commandNameAndArgs() diff (-got +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("commandNameAndArgs() diff (-got +want):\n%s", diff)
+	}
+	if !errors.Is(err, shellquote.UnterminatedSingleQuoteError) {
+		t.Errorf("commandNameAndArgs() error = %v, want %v", err, shellquote.UnterminatedSingleQuoteError)
+	}
+}
+
+func TestVitestRetryCommandNameAndArgs(t *testing.T) {
+	testCases := []string{"src/apple.test.ts"}
+	testNames := []string{"Apple is sweet", "Apple (big) is red"}
+	retryCommand := "npx vitest run {{testExamples}} -t '{{testNamePattern}}' --reporter=json --outputFile={{resultPath}}"
+
+	vitest := NewVitest(RunnerConfig{
+		RetryTestCommand: retryCommand,
+		ResultPath:       "vitest.json",
+	})
+
+	gotName, gotArgs, err := vitest.retryCommandNameAndArgs(retryCommand, testCases, testNames)
+	if err != nil {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) error = %v", testCases, testNames, err)
+	}
+
+	wantName := "npx"
+	wantArgs := []string{"vitest", "run", "src/apple.test.ts", "-t", `(Apple is sweet|Apple \(big\) is red)`, "--reporter=json", "--outputFile=vitest.json"}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testNames, diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("retryCommandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testNames, diff)
+	}
+}
+
+func TestVitestRetryCommandNameAndArgs_WithoutPlaceholders(t *testing.T) {
+	cases := []struct {
+		retryCommand string
+		wantError    string
+	}{
+		{
+			retryCommand: "npx vitest run --reporter=json --outputFile={{resultPath}}",
+			wantError:    "couldn't find '{{testNamePattern}}' sentinel in retry command",
+		},
+		{
+			retryCommand: "npx vitest run -t '{{testNamePattern}}'",
+			wantError:    "couldn't find '{{resultPath}}' sentinel in retry command, exiting.",
+		},
+	}
+
+	for _, c := range cases {
+		vitest := NewVitest(RunnerConfig{
+			RetryTestCommand: c.retryCommand,
+		})
+
+		gotName, gotArgs, err := vitest.retryCommandNameAndArgs(c.retryCommand, []string{}, []string{"Apple is sweet"})
+
+		if diff := cmp.Diff(gotName, ""); diff != "" {
+			t.Errorf("retryCommandNameAndArgs(%q) diff (-got +want):\n%s", c.retryCommand, diff)
+		}
+		if diff := cmp.Diff(gotArgs, []string{}, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("retryCommandNameAndArgs(%q) diff (-got +want):\n%s", c.retryCommand, diff)
+		}
+		if err == nil || err.Error() != c.wantError {
+			t.Errorf("retryCommandNameAndArgs(%q) error = %v, want %q", c.retryCommand, err, c.wantError)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+// Package selection narrows down the test files to the ones affected by the changes of the current build.
+package selection
@@ -0,0 +1,152 @@
+package selection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"drjosh.dev/zzglob"
+)
+
+// Mapping is the mapping from source files to test files, read from a mapping file.
+type Mapping struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule selects the test files matching any of the Tests patterns
+// when a changed file matches the Source pattern.
+type Rule struct {
+	Source string   `json:"source"`
+	Tests  []string `json:"tests"`
+}
+
+// ReadMappingFile reads the mapping from the JSON file at the given path.
+func ReadMappingFile(path string) (Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Mapping{}, fmt.Errorf("failed to read mapping file: %w", err)
+	}
+
+	var mapping Mapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return Mapping{}, fmt.Errorf("failed to parse mapping file %s: %w", path, err)
+	}
+
+	for _, rule := range mapping.Rules {
+		if _, err := zzglob.Parse(rule.Source); err != nil {
+			return Mapping{}, fmt.Errorf("invalid source pattern %q in mapping file %s: %w", rule.Source, path, err)
+		}
+		for _, pattern := range rule.Tests {
+			if _, err := zzglob.Parse(pattern); err != nil {
+				return Mapping{}, fmt.Errorf("invalid tests pattern %q in mapping file %s: %w", pattern, path, err)
+			}
+		}
+	}
+
+	return mapping, nil
+}
+
+// ChangedFiles returns the files changed between the merge base of baseRef and HEAD,
+// relative to the current directory. Changes outside of the current directory are ignored.
+func ChangedFiles(baseRef string) ([]string, error) {
+	return changedFiles("", baseRef)
+}
+
+func changedFiles(dir string, baseRef string) ([]string, error) {
+	var stdout, stderr bytes.Buffer
+	// The paths are separated by NUL, as they can contain spaces, and git would quote the unusual ones otherwise.
+	cmd := exec.Command("git", "diff", "--name-only", "--relative", "-z", baseRef+"...HEAD")
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to diff against %s: %w\n%s", baseRef, err, stderr.Bytes())
+	}
+
+	output := strings.TrimSuffix(stdout.String(), "\x00")
+	if output == "" {
+		return nil, nil
+	}
+
+	return strings.Split(output, "\x00"), nil
+}
+
+// Select returns the test files affected by the changed files, in the order of files.
+// A test file is affected when:
+//   - it is changed itself,
+//   - a changed file has the same base name, once the test suffixes and prefixes are removed,
+//     e.g. app/models/apple.rb and spec/models/apple_spec.rb, or src/apple.ts and src/apple.test.ts,
+//   - a changed file matches the source pattern of a rule in the mapping, and the test file matches its tests patterns.
+func Select(files []string, changed []string, mapping Mapping) []string {
+	changedPaths := map[string]bool{}
+	changedStems := map[string]bool{}
+	for _, file := range changed {
+		file = path.Clean(file)
+		changedPaths[file] = true
+		changedStems[stem(file)] = true
+	}
+
+	var testPatterns []*zzglob.Pattern
+	for _, rule := range mapping.Rules {
+		source, err := zzglob.Parse(rule.Source)
+		if err != nil {
+			continue
+		}
+		for _, file := range changed {
+			if !source.Match(path.Clean(file)) {
+				continue
+			}
+			for _, pattern := range rule.Tests {
+				if p, err := zzglob.Parse(pattern); err == nil {
+					testPatterns = append(testPatterns, p)
+				}
+			}
+			break
+		}
+	}
+
+	selected := []string{}
+	for _, file := range files {
+		clean := path.Clean(file)
+		if changedPaths[clean] || changedStems[testStem(clean)] || matchAny(testPatterns, clean) {
+			selected = append(selected, file)
+		}
+	}
+
+	return selected
+}
+
+func matchAny(patterns []*zzglob.Pattern, file string) bool {
+	for _, p := range patterns {
+		if p.Match(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// stem returns the base name of the file without its extensions, e.g. "apple" for "src/apple.test.ts".
+func stem(file string) string {
+	name, _, _ := strings.Cut(path.Base(file), ".")
+	return name
+}
+
+// testStem returns the stem of the test file without the test suffixes and prefixes of the supported test runners,
+// e.g. "apple" for "spec/apple_spec.rb", "apple_test.go", "test_apple.py", and "Apple" for "tests/AppleTest.php".
+func testStem(file string) string {
+	name := stem(file)
+	for _, suffix := range []string{"_spec", "_test", "Tests", "Test", "Spec"} {
+		if trimmed, ok := strings.CutSuffix(name, suffix); ok && trimmed != "" {
+			return trimmed
+		}
+	}
+	if trimmed, ok := strings.CutPrefix(name, "test_"); ok && trimmed != "" {
+		return trimmed
+	}
+	return name
+}
@@ -0,0 +1,165 @@
+package selection
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSelect(t *testing.T) {
+	files := []string{
+		"spec/models/apple_spec.rb",
+		"spec/models/banana_spec.rb",
+		"src/cherry.test.ts",
+		"pkg/grape_test.go",
+		"tests/test_lemon.py",
+		"tests/Unit/MangoTest.php",
+		"spec/features/checkout_spec.rb",
+	}
+
+	changed := []string{
+		"app/models/apple.rb",
+		"src/cherry.ts",
+		"pkg/grape.go",
+		"lemon.py",
+		"src/Mango.php",
+		"spec/models/banana_spec.rb",
+		"README.md",
+	}
+
+	got := Select(files, changed, Mapping{})
+	want := []string{
+		"spec/models/apple_spec.rb",
+		"spec/models/banana_spec.rb",
+		"src/cherry.test.ts",
+		"pkg/grape_test.go",
+		"tests/test_lemon.py",
+		"tests/Unit/MangoTest.php",
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Select(%q, %q) diff (-got +want):\n%s", files, changed, diff)
+	}
+}
+
+func TestSelect_Mapping(t *testing.T) {
+	files := []string{
+		"spec/models/apple_spec.rb",
+		"spec/features/checkout_spec.rb",
+		"spec/features/login_spec.rb",
+	}
+
+	mapping := Mapping{
+		Rules: []Rule{
+			{Source: "app/views/**/*", Tests: []string{"spec/features/**/*_spec.rb"}},
+			{Source: "config/**/*", Tests: []string{"spec/models/*_spec.rb"}},
+		},
+	}
+
+	changed := []string{"./app/views/checkout/show.html.erb"}
+
+	got := Select(files, changed, mapping)
+	want := []string{
+		"spec/features/checkout_spec.rb",
+		"spec/features/login_spec.rb",
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Select(%q, %q) diff (-got +want):\n%s", files, changed, diff)
+	}
+}
+
+func TestSelect_NoChanges(t *testing.T) {
+	files := []string{"spec/models/apple_spec.rb"}
+
+	got := Select(files, nil, Mapping{})
+	if len(got) != 0 {
+		t.Errorf("Select(%q, nil) = %q, want empty", files, got)
+	}
+}
+
+func TestReadMappingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.json")
+	content := `{"rules": [{"source": "app/views/**/*", "tests": ["spec/features/**/*_spec.rb"]}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("os.WriteFile(%q) error = %v", path, err)
+	}
+
+	got, err := ReadMappingFile(path)
+	if err != nil {
+		t.Errorf("ReadMappingFile(%q) error = %v", path, err)
+	}
+
+	want := Mapping{
+		Rules: []Rule{
+			{Source: "app/views/**/*", Tests: []string{"spec/features/**/*_spec.rb"}},
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ReadMappingFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestReadMappingFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.json")
+	if err := os.WriteFile(path, []byte("rules:"), 0644); err != nil {
+		t.Fatalf("os.WriteFile(%q) error = %v", path, err)
+	}
+
+	_, err := ReadMappingFile(path)
+	if err == nil {
+		t.Errorf("ReadMappingFile(%q) error = nil, want error", path)
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %q error = %v\n%s", args, err, out)
+		}
+	}
+	write := func(name string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("os.MkdirAll(%q) error = %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("os.WriteFile(%q) error = %v", path, err)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	write("app/apple.rb")
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	git("checkout", "-q", "-b", "feature")
+	write("app/banana.rb")
+	write("spec/banana_spec.rb")
+	write("spec/cherry pie_spec.rb")
+	git("add", "-A")
+	git("commit", "-q", "-m", "feature")
+
+	got, err := changedFiles(dir, "main")
+	if err != nil {
+		t.Errorf("changedFiles(%q) error = %v", "main", err)
+	}
+
+	want := []string{"app/banana.rb", "spec/banana_spec.rb", "spec/cherry pie_spec.rb"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("changedFiles(%q) diff (-got +want):\n%s", "main", diff)
+	}
+}
+
+func TestChangedFiles_UnknownRef(t *testing.T) {
+	_, err := changedFiles(t.TempDir(), "origin/doesnt-exist")
+	if err == nil {
+		t.Errorf("changedFiles(%q) error = nil, want error", "origin/doesnt-exist")
+	}
+}
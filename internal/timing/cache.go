@@ -0,0 +1,156 @@
+package timing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// rollingWindow is the number of the most recent durations the rolling average is taken over.
+const rollingWindow = 10
+
+// Entry is the rolling average duration of a test file or a test example.
+type Entry struct {
+	// Duration is the rolling average duration in seconds.
+	Duration float64 `json:"duration"`
+	// Samples is the number of durations the average is taken over, up to rollingWindow.
+	Samples int `json:"samples"`
+}
+
+// add adds a duration to the rolling average.
+// Once the window is full, each new duration replaces an average sample,
+// so the older durations gradually lose their weight.
+func (e *Entry) add(duration time.Duration) {
+	if e.Samples < rollingWindow {
+		e.Samples++
+	}
+	e.Duration += (duration.Seconds() - e.Duration) / float64(e.Samples)
+}
+
+// Timings is the durations of the test files and test examples of a suite, run with a test runner.
+type Timings struct {
+	// Files maps the path of each test file to its duration.
+	Files map[string]*Entry `json:"files"`
+	// Examples maps the scope and name of each test example to its duration.
+	Examples map[string]*Entry `json:"examples"`
+}
+
+// Cache is the local timing cache, persisted as a JSON file.
+// The timings are keyed by the suite slug and the test runner,
+// so the same cache file can be shared by multiple suites.
+type Cache struct {
+	Suites map[string]*Timings `json:"suites"`
+}
+
+// key returns the key of the timings of the given suite and test runner.
+func key(suiteSlug string, runner string) string {
+	return suiteSlug + "/" + runner
+}
+
+// ReadFile reads the timing cache from the JSON file at the given path.
+// If the file doesn't exist, e.g. in the first build, an empty cache is returned.
+func ReadFile(path string) (Cache, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Cache{}, nil
+	}
+	if err != nil {
+		return Cache{}, fmt.Errorf("failed to read timing cache: %w", err)
+	}
+
+	var cache Cache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return Cache{}, fmt.Errorf("failed to parse timing cache %q: %w", path, err)
+	}
+
+	return cache, nil
+}
+
+// WriteFile writes the timing cache as JSON to the file at the given path.
+// The parent directories of the file are created if they don't exist.
+func WriteFile(path string, cache Cache) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode timing cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for timing cache: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write timing cache: %w", err)
+	}
+
+	return nil
+}
+
+// Record adds the durations of the given test files and test examples
+// to the rolling averages of the given suite and test runner.
+func (c *Cache) Record(suiteSlug string, runner string, files map[string]time.Duration, examples map[string]time.Duration) {
+	if c.Suites == nil {
+		c.Suites = map[string]*Timings{}
+	}
+
+	timings, ok := c.Suites[key(suiteSlug, runner)]
+	if !ok {
+		timings = &Timings{}
+		c.Suites[key(suiteSlug, runner)] = timings
+	}
+
+	if timings.Files == nil {
+		timings.Files = map[string]*Entry{}
+	}
+	if timings.Examples == nil {
+		timings.Examples = map[string]*Entry{}
+	}
+
+	addAll(timings.Files, files)
+	addAll(timings.Examples, examples)
+}
+
+func addAll(entries map[string]*Entry, durations map[string]time.Duration) {
+	for name, duration := range durations {
+		entry, ok := entries[name]
+		if !ok {
+			entry = &Entry{}
+			entries[name] = entry
+		}
+		entry.add(duration)
+	}
+}
+
+// FileTimings returns the average duration of each test file of the given suite and test runner.
+func (c Cache) FileTimings(suiteSlug string, runner string) map[string]time.Duration {
+	timings, ok := c.Suites[key(suiteSlug, runner)]
+	if !ok {
+		return nil
+	}
+
+	result := map[string]time.Duration{}
+	for path, entry := range timings.Files {
+		result[path] = time.Duration(entry.Duration * float64(time.Second))
+	}
+
+	return result
+}
+
+// lineSuffix matches the line number of a test location, e.g. ":12" in "spec/apple_spec.rb:12".
+var lineSuffix = regexp.MustCompile(`:\d+$`)
+
+// FilePath returns the path of the test file from the path of a test example.
+// The test runners identify an example with a location inside the test file, e.g.
+// "./spec/apple_spec.rb[1:2]" in RSpec, "tests/test_apple.py::test_is_red" in pytest,
+// or "src/apple.spec.ts:12" in Playwright.
+func FilePath(path string) string {
+	path, _, _ = strings.Cut(path, "::")
+	path, _, _ = strings.Cut(path, "[")
+	path = lineSuffix.ReplaceAllString(path, "")
+	return strings.TrimPrefix(path, "./")
+}
@@ -0,0 +1,157 @@
+package timing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCacheRecord(t *testing.T) {
+	cache := Cache{}
+
+	cache.Record("my-suite", "rspec", map[string]time.Duration{
+		"spec/apple_spec.rb": 2 * time.Second,
+	}, map[string]time.Duration{
+		"Apple/is red": 1 * time.Second,
+	})
+	cache.Record("my-suite", "rspec", map[string]time.Duration{
+		"spec/apple_spec.rb":  4 * time.Second,
+		"spec/banana_spec.rb": 1 * time.Second,
+	}, nil)
+	cache.Record("other-suite", "rspec", map[string]time.Duration{
+		"spec/apple_spec.rb": 10 * time.Second,
+	}, nil)
+
+	want := Cache{
+		Suites: map[string]*Timings{
+			"my-suite/rspec": {
+				Files: map[string]*Entry{
+					"spec/apple_spec.rb":  {Duration: 3, Samples: 2},
+					"spec/banana_spec.rb": {Duration: 1, Samples: 1},
+				},
+				Examples: map[string]*Entry{
+					"Apple/is red": {Duration: 1, Samples: 1},
+				},
+			},
+			"other-suite/rspec": {
+				Files: map[string]*Entry{
+					"spec/apple_spec.rb": {Duration: 10, Samples: 1},
+				},
+				Examples: map[string]*Entry{},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(cache, want); diff != "" {
+		t.Errorf("Cache.Record() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestCacheRecord_RollingAverage(t *testing.T) {
+	cache := Cache{}
+
+	for i := 0; i < rollingWindow; i++ {
+		cache.Record("my-suite", "rspec", map[string]time.Duration{"spec/apple_spec.rb": 1 * time.Second}, nil)
+	}
+
+	// Once the window is full, a new duration replaces an average sample.
+	cache.Record("my-suite", "rspec", map[string]time.Duration{"spec/apple_spec.rb": 11 * time.Second}, nil)
+
+	got := *cache.Suites["my-suite/rspec"].Files["spec/apple_spec.rb"]
+	want := Entry{Duration: 2, Samples: rollingWindow}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Cache.Record() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestCacheFileTimings(t *testing.T) {
+	cache := Cache{}
+	cache.Record("my-suite", "jest", map[string]time.Duration{
+		"src/apple.test.js": 1500 * time.Millisecond,
+	}, nil)
+
+	got := cache.FileTimings("my-suite", "jest")
+	want := map[string]time.Duration{
+		"src/apple.test.js": 1500 * time.Millisecond,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Cache.FileTimings() diff (-got +want):\n%s", diff)
+	}
+
+	if got := cache.FileTimings("my-suite", "rspec"); got != nil {
+		t.Errorf("Cache.FileTimings() = %v, want nil", got)
+	}
+}
+
+func TestWriteFileAndReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "timing.json")
+
+	want := Cache{}
+	want.Record("my-suite", "rspec", map[string]time.Duration{
+		"spec/apple_spec.rb": 2 * time.Second,
+	}, map[string]time.Duration{
+		"Apple/is red": 1 * time.Second,
+	})
+
+	if err := WriteFile(path, want); err != nil {
+		t.Fatalf("WriteFile(%q) error = %v", path, err)
+	}
+
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q) error = %v", path, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ReadFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestReadFile_NotExist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timing.json")
+
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Errorf("ReadFile(%q) error = %v", path, err)
+	}
+
+	if diff := cmp.Diff(got, Cache{}); diff != "" {
+		t.Errorf("ReadFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestReadFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timing.json")
+	if err := os.WriteFile(path, []byte("suites"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadFile(path); err == nil {
+		t.Errorf("ReadFile(%q) error = nil, want error", path)
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	cases := map[string]string{
+		"./spec/apple_spec.rb[1:2]":         "spec/apple_spec.rb",
+		"spec/apple_spec.rb:12":             "spec/apple_spec.rb",
+		"tests/test_apple.py::test_is_red":  "tests/test_apple.py",
+		"tests/test_apple.py::TestApple::x": "tests/test_apple.py",
+		"tests/test_color.py::test[a:1]":    "tests/test_color.py",
+		"src/apple.spec.ts":                 "src/apple.spec.ts",
+		"com.example.TomatoTest":            "com.example.TomatoTest",
+		"fruits/apple.fruit:5":              "fruits/apple.fruit",
+		"example.com/gotest/fruits":         "example.com/gotest/fruits",
+	}
+
+	for path, want := range cases {
+		if got := FilePath(path); got != want {
+			t.Errorf("FilePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+// Package timing provides a local cache of test durations that is persisted between builds.
+package timing
@@ -5,25 +5,34 @@ package main
 import (
 	"context"
 	"errors"
-	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/buildkite/test-engine-client/internal/api"
 	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/coordinator"
 	"github.com/buildkite/test-engine-client/internal/debug"
 	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/buildkite/test-engine-client/internal/report"
 	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/buildkite/test-engine-client/internal/selection"
+	"github.com/buildkite/test-engine-client/internal/timing"
 	"github.com/olekukonko/tablewriter"
 	"golang.org/x/sys/unix"
 )
 
 var Version = ""
 
+// filesTimingTimeout is the maximum time spent fetching the timings of the files for a fallback plan.
+var filesTimingTimeout = 10 * time.Second
+
 const Logo = `
 ______ ______ _____
 ___  /____  /___  /____________
@@ -46,58 +55,121 @@ type TestRunner interface {
 	Name() string
 }
 
+// TestQueue is the shared queue of test cases that the nodes claim batches from in dynamic mode.
+type TestQueue interface {
+	// Claim returns the next batch of test cases, or an empty batch when the queue is drained.
+	Claim(ctx context.Context) ([]plan.TestCase, error)
+}
+
+// apiTestQueue is the queue of the test plan served by the Test Engine API, or a local coordinator.
+type apiTestQueue struct {
+	client    *api.Client
+	suiteSlug string
+	params    api.ClaimTestCasesParams
+}
+
+// Claim claims the next batch of test cases, numbering the claims of the node from 1.
+func (q *apiTestQueue) Claim(ctx context.Context) ([]plan.TestCase, error) {
+	q.params.Sequence++
+	return q.client.ClaimTestCases(ctx, q.suiteSlug, q.params)
+}
+
 func main() {
 	debug.SetDebug(os.Getenv("BUILDKITE_TEST_ENGINE_DEBUG_ENABLED") == "true")
 
-	versionFlag := flag.Bool("version", false, "print version information")
+	name, args := parseCommand(os.Args[1:])
+	switch name {
+	case "run":
+		runCommand(args)
+	case "files":
+		filesCommand(args)
+	case "examples":
+		examplesCommand(args)
+	case "plan":
+		planCommand(args)
+	case "report":
+		reportCommand(args)
+	case "doctor":
+		doctorCommand(args)
+	case "help":
+		printCommands(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		printCommands(os.Stderr)
+		os.Exit(2)
+	}
+}
+
+// runCommand fetches or creates the test plan, runs the tests assigned to this node and reports the result.
+func runCommand(args []string) {
+	fs := newFlagSet("run")
+	versionFlag := fs.Bool("version", false, "print version information")
+	coordinatorFlag := fs.String("coordinator", "", "serve the queue of the test plan in BUILDKITE_TEST_ENGINE_PLAN_FILE to the nodes in dynamic mode, at the given address (e.g. localhost:8080)")
+	configFlags := config.RegisterFlags(fs)
 
-	flag.Parse()
+	_ = fs.Parse(args)
 
 	if *versionFlag {
 		fmt.Println(Version)
 		os.Exit(0)
 	}
 
-	printStartUpMessage()
-
-	// get config
-	cfg, err := config.New()
-	if err != nil {
-		logErrorAndExit(16, "Invalid configuration...\n%v", err)
+	if *coordinatorFlag != "" {
+		serveCoordinator(*coordinatorFlag, configFlags.Getenv("BUILDKITE_TEST_ENGINE_PLAN_FILE"))
 	}
 
-	testRunner, err := runner.DetectRunner(cfg)
-	if err != nil {
-		logErrorAndExit(16, "Unsupported value for BUILDKITE_TEST_ENGINE_TEST_RUNNER %q: %v", cfg.TestRunner, err)
-	}
+	printStartUpMessage()
 
-	files, err := testRunner.GetFiles()
-	if err != nil {
-		logErrorAndExit(16, "Couldn't get files: %v", err)
-	}
+	// get config
+	cfg := newCommandConfig(configFlags)
+	testRunner := detectRunner(cfg)
+	files := getFiles(cfg, testRunner)
 
 	// get plan
 	ctx := context.Background()
-	apiClient := api.NewClient(api.ClientConfig{
-		ServerBaseUrl:    cfg.ServerBaseUrl,
-		AccessToken:      cfg.AccessToken,
-		OrganizationSlug: cfg.OrganizationSlug,
-		Version:          Version,
-	})
+	apiClient := newAPIClient(cfg)
+	testPlan := getTestPlan(ctx, apiClient, cfg, files, testRunner)
 
-	testPlan, err := fetchOrCreateTestPlan(ctx, apiClient, cfg, files, testRunner)
-	if err != nil {
-		logErrorAndExit(16, "Couldn't fetch or create test plan: %v", err)
+	if cfg.PlanOutputFile != "" {
+		// Error is suppressed because the tests can still run without the plan being saved.
+		if err := plan.WriteFile(cfg.PlanOutputFile, testPlan); err != nil {
+			fmt.Printf("Failed to write test plan to %s: %v\n", cfg.PlanOutputFile, err)
+		}
 	}
 
 	debug.Printf("My favourite ice cream is %s", testPlan.Experiment)
 
-	// get plan for this node
-	thisNodeTask := testPlan.Tasks[strconv.Itoa(cfg.NodeIndex)]
+	// Metadata is only sent for the test plans that come from the API.
+	shouldSendMetadata := !testPlan.Fallback && !cfg.HasPlanFile()
 
 	// execute tests
 	var timeline []api.Timeline
-	runResult, err := runTestsWithRetry(testRunner, &thisNodeTask.Tests, cfg.MaxRetries, testPlan.MutedTests, &timeline)
+	var runResult runner.RunResult
+	var err error
+	if cfg.Mode == config.ModeDynamic && !testPlan.Fallback {
+		queue := &apiTestQueue{
+			client:    apiClient,
+			suiteSlug: cfg.SuiteSlug,
+			params: api.ClaimTestCasesParams{
+				Identifier: cfg.Identifier,
+				NodeIndex:  cfg.NodeIndex,
+				BatchSize:  cfg.BatchSize,
+			},
+		}
+		runResult, err = runDynamicTestsWithRetry(ctx, testRunner, queue, cfg.MaxRetries, testPlan.MutedTests, &timeline)
+	} else {
+		if cfg.Mode == config.ModeDynamic {
+			fmt.Println("⚠️ Dynamic mode is not available with a fallback plan, running the tests assigned to this node.")
+		}
+
+		// get plan for this node
+		thisNodeTask, ok := testPlan.Tasks[strconv.Itoa(cfg.NodeIndex)]
+		if !ok {
+			logErrorAndExit(16, "Couldn't find a task for node %d in the test plan", cfg.NodeIndex)
+		}
+
+		runResult, err = runTestsWithRetry(testRunner, &thisNodeTask.Tests, cfg.MaxRetries, testPlan.MutedTests, &timeline)
+	}
 
 	if err != nil {
 		if ProcessSignaledError := new(runner.ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
@@ -105,7 +177,7 @@ func main() {
 		}
 
 		if exitError := new(exec.ExitError); errors.As(err, &exitError) {
-			if !testPlan.Fallback {
+			if shouldSendMetadata {
 				sendMetadata(ctx, apiClient, cfg, timeline)
 			}
 			logErrorAndExit(exitError.ExitCode(), "%s exited with error: %v", testRunner.Name(), err)
@@ -116,24 +188,194 @@ func main() {
 
 	printReport(runResult)
 
+	if cfg.JUnitReportPath != "" {
+		// Error is suppressed because we don't want to fail the build if we can't write the report.
+		if err := report.WriteJUnitFile(cfg.JUnitReportPath, runResult); err != nil {
+			fmt.Printf("Failed to write JUnit report to %s: %v\n", cfg.JUnitReportPath, err)
+		}
+	}
+
+	if cfg.SummaryPath != "" {
+		summary := report.NewSummary(runResult, cfg.NodeIndex, cfg.Identifier, testPlan.Fallback, timeline)
+		// Error is suppressed because we don't want to fail the build if we can't write the summary.
+		if err := report.WriteSummaryFile(cfg.SummaryPath, summary); err != nil {
+			fmt.Printf("Failed to write summary to %s: %v\n", cfg.SummaryPath, err)
+		}
+	}
+
+	if cfg.AnnotationPath != "" || cfg.Annotate {
+		annotate(cfg, runResult)
+	}
+
+	if cfg.TimingCachePath != "" {
+		updateTimingCache(cfg, runResult)
+	}
+
+	if shouldSendMetadata {
+		sendFlakyTests(ctx, apiClient, cfg, runResult)
+	}
+
 	if runResult.Status() == runner.RunStatusFailed {
-		if !testPlan.Fallback {
+		if shouldSendMetadata {
 			sendMetadata(ctx, apiClient, cfg, timeline)
 		}
 
 		os.Exit(1)
 	}
 
-	if !testPlan.Fallback {
+	if shouldSendMetadata {
 		sendMetadata(ctx, apiClient, cfg, timeline)
 	}
 }
 
+// newCommandConfig reads the configuration of the command, ignoring the validation errors
+// of the given environment variables that the command doesn't use.
+// It exits when the configuration is invalid.
+func newCommandConfig(configFlags config.Flags, except ...string) config.Config {
+	cfg, err := config.NewWithFlagsExcept(configFlags, except...)
+	if err != nil {
+		logErrorAndExit(16, "Invalid configuration...\n%v", err)
+	}
+
+	if cfg.ConfigFile != "" {
+		debug.Printf("Read configuration from %s, profile %q", cfg.ConfigFile, cfg.Profile)
+	}
+
+	return cfg
+}
+
+// detectRunner returns the test runner of the configuration, or exits if it's not supported.
+func detectRunner(cfg config.Config) TestRunner {
+	testRunner, err := runner.DetectRunner(cfg)
+	if err != nil {
+		logErrorAndExit(16, "Unsupported value for BUILDKITE_TEST_ENGINE_TEST_RUNNER %q: %v", cfg.TestRunner, err)
+	}
+	return testRunner
+}
+
+// getFiles returns the test files discovered by the test runner,
+// narrowed down to the ones affected by the changes when changed-files test selection is enabled.
+func getFiles(cfg config.Config, testRunner TestRunner) []string {
+	files, err := testRunner.GetFiles()
+	if err != nil {
+		logErrorAndExit(16, "Couldn't get files: %v", err)
+	}
+
+	if cfg.SelectChangedTests {
+		files, err = selectChangedTests(cfg, files)
+		if err != nil {
+			logErrorAndExit(16, "Couldn't select changed tests: %v", err)
+		}
+	}
+
+	return files
+}
+
+func newAPIClient(cfg config.Config) *api.Client {
+	return api.NewClient(api.ClientConfig{
+		ServerBaseUrl:    cfg.ServerBaseUrl,
+		AccessToken:      cfg.AccessToken,
+		OrganizationSlug: cfg.OrganizationSlug,
+		Version:          Version,
+	})
+}
+
+// getTestPlan reads the test plan from the plan file if it exists,
+// otherwise fetches or creates it with Test Engine.
+func getTestPlan(ctx context.Context, apiClient *api.Client, cfg config.Config, files []string, testRunner TestRunner) plan.TestPlan {
+	if cfg.HasPlanFile() {
+		fmt.Printf("+++ Buildkite Test Engine Client: Reading test plan from %s\n", cfg.PlanFile)
+		testPlan, err := plan.ReadFile(cfg.PlanFile)
+		if err != nil {
+			logErrorAndExit(16, "Couldn't read test plan file: %v", err)
+		}
+		return testPlan
+	}
+
+	testPlan, err := fetchOrCreateTestPlan(ctx, apiClient, cfg, files, testRunner)
+	if err != nil {
+		logErrorAndExit(16, "Couldn't fetch or create test plan: %v", err)
+	}
+	return testPlan
+}
+
+// serveCoordinator serves the queue of the test plan in the given file at the given address,
+// so the nodes in dynamic mode can claim test cases from it without Test Engine.
+func serveCoordinator(addr string, planFile string) {
+	testPlan, err := plan.ReadFile(planFile)
+	if err != nil {
+		logErrorAndExit(16, "Couldn't read test plan file: %v", err)
+	}
+
+	fmt.Printf("+++ Buildkite Test Engine Client: Serving test plan from %s at %s\n", planFile, addr)
+	if err := http.ListenAndServe(addr, coordinator.New(testPlan)); err != nil {
+		logErrorAndExit(16, "Couldn't serve test plan: %v", err)
+	}
+	os.Exit(0)
+}
+
 func printReport(runResult runner.RunResult) {
 	fmt.Println("+++ ========== Buildkite Test Engine Report  ==========")
 
 	// Print statistics
-	statistics := runResult.Statistics()
+	printStatistics(runResult.Statistics())
+
+	// Print muted and failed tests
+	mutedTests := runResult.MutedTests()
+	if len(mutedTests) > 0 {
+		fmt.Println("")
+		fmt.Println("+++ Muted Tests:")
+		for _, mutedTest := range runResult.MutedTests() {
+			fmt.Printf("- %s %s (%s)\n", mutedTest.Scope, mutedTest.Name, mutedTest.Status)
+		}
+	}
+
+	flakyTests := runResult.FlakyTests()
+	if len(flakyTests) > 0 {
+		fmt.Println("")
+		fmt.Println("+++ Flaky Tests:")
+		slices.SortFunc(flakyTests, func(a, b runner.TestResult) int {
+			return strings.Compare(a.Scope+"/"+a.Name, b.Scope+"/"+b.Name)
+		})
+		for _, flakyTest := range flakyTests {
+			fmt.Printf("- %s %s (passed after %d attempts)\n", flakyTest.Scope, flakyTest.Name, flakyTest.ExecutionCount)
+		}
+	}
+
+	failedTests := failedTestResults(runResult)
+	if len(failedTests) > 0 {
+		fmt.Println("")
+		fmt.Println("+++ Failed Tests:")
+		for _, failedTest := range failedTests {
+			fmt.Printf("- %s %s", failedTest.Scope, failedTest.Name)
+			if location := failedTest.Location(); location != "" {
+				fmt.Printf(" (%s)", location)
+			}
+			fmt.Println("")
+			for _, line := range report.FailureExcerpt(failedTest.FailureMessage, report.FailureExcerptLines) {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+
+	slowestTests := runResult.SlowestTests(slowestTestsCount)
+	if len(slowestTests) > 0 {
+		fmt.Println("")
+		fmt.Println("+++ Slowest Tests:")
+		for _, slowTest := range slowestTests {
+			fmt.Printf("- %s %s %s", slowTest.Duration.Round(time.Millisecond), slowTest.Scope, slowTest.Name)
+			if location := slowTest.Location(); location != "" {
+				fmt.Printf(" (%s)", location)
+			}
+			fmt.Println("")
+		}
+	}
+
+	fmt.Println("===================================================")
+}
+
+// printStatistics prints the table of the statistics of the run.
+func printStatistics(statistics runner.RunStatistics) {
 	data := [][]string{
 		{"Passed", "first run", strconv.Itoa(statistics.PassedOnFirstRun)},
 		{"Passed", "on retry", strconv.Itoa(statistics.PassedOnRetry)},
@@ -148,27 +390,74 @@ func printReport(runResult runner.RunResult) {
 	table.SetAutoMergeCellsByColumnIndex([]int{0, 1})
 	table.SetRowLine(true)
 	table.Render()
+}
 
-	// Print muted and failed tests
-	mutedTests := runResult.MutedTests()
-	if len(mutedTests) > 0 {
-		fmt.Println("")
-		fmt.Println("+++ Muted Tests:")
-		for _, mutedTest := range runResult.MutedTests() {
-			fmt.Printf("- %s %s (%s)\n", mutedTest.Scope, mutedTest.Name, mutedTest.Status)
+// annotate writes the annotation of the run to the annotation file,
+// and pipes it to buildkite-agent when there are failed, passed on retry or muted tests to report.
+// Errors are suppressed because we don't want to fail the build if we can't annotate it.
+func annotate(cfg config.Config, runResult runner.RunResult) {
+	annotation := report.NewAnnotation(runResult, cfg.NodeIndex)
+
+	if cfg.AnnotationPath != "" {
+		if err := report.WriteAnnotationFile(cfg.AnnotationPath, annotation); err != nil {
+			fmt.Printf("Failed to write annotation to %s: %v\n", cfg.AnnotationPath, err)
 		}
 	}
 
-	failedTests := runResult.FailedTests()
-	if len(failedTests) > 0 {
-		fmt.Println("")
-		fmt.Println("+++ Failed Tests:")
-		for _, failedTests := range runResult.FailedTests() {
-			fmt.Printf("- %s %s\n", failedTests.Scope, failedTests.Name)
+	if !cfg.Annotate || annotation.Empty() {
+		return
+	}
+
+	err := report.Annotate(cfg.AgentPath, annotation)
+	switch {
+	case errors.Is(err, report.ErrAgentNotFound):
+		fmt.Printf("%s is not available, skipping the annotation of the build.\n", cfg.AgentPath)
+	case err != nil:
+		fmt.Printf("Failed to annotate the build: %v\n", err)
+	}
+}
+
+// slowestTestsCount is the number of slowest tests listed in the report.
+const slowestTestsCount = 10
+
+// failedTestResults returns the results of the failed tests that are not muted, sorted by scope and name.
+func failedTestResults(runResult runner.RunResult) []runner.TestResult {
+	var failedTests []runner.TestResult
+	for _, test := range runResult.TestResults() {
+		if test.Status == runner.TestStatusFailed && !test.Muted {
+			failedTests = append(failedTests, test)
 		}
 	}
 
-	fmt.Println("===================================================")
+	slices.SortFunc(failedTests, func(a, b runner.TestResult) int {
+		return strings.Compare(a.Scope+"/"+a.Name, b.Scope+"/"+b.Name)
+	})
+
+	return failedTests
+}
+
+// sendFlakyTests reports the tests that passed on retry to Test Engine, so they can be muted in the next test plans.
+func sendFlakyTests(ctx context.Context, apiClient *api.Client, cfg config.Config, runResult runner.RunResult) {
+	flakyTests := runResult.FlakyTests()
+	if len(flakyTests) == 0 {
+		return
+	}
+
+	params := api.FlakyTestsParams{Identifier: cfg.Identifier}
+	for _, flakyTest := range flakyTests {
+		params.FlakyTests = append(params.FlakyTests, api.FlakyTest{
+			Scope:          flakyTest.Scope,
+			Name:           flakyTest.Name,
+			Path:           flakyTest.Path,
+			Identifier:     flakyTest.Identifier,
+			ExecutionCount: flakyTest.ExecutionCount,
+		})
+	}
+
+	// Error is suppressed because we don't want to fail the build if we can't report the flaky tests.
+	if err := apiClient.PostFlakyTests(ctx, cfg.SuiteSlug, params); err != nil {
+		fmt.Printf("Failed to send flaky tests to Test Engine: %v\n", err)
+	}
 }
 
 func createTimestamp() string {
@@ -188,23 +477,63 @@ func sendMetadata(ctx context.Context, apiClient *api.Client, cfg config.Config,
 	}
 }
 
+// runTestsWithRetry runs the test cases assigned to this node, and retries the failed tests up to maxRetries times.
 func runTestsWithRetry(testRunner TestRunner, testsCases *[]plan.TestCase, maxRetries int, mutedTests []plan.TestCase, timeline *[]api.Timeline) (runner.RunResult, error) {
-	attemptCount := 0
+	// Create a new run result with muted tests to keep track of the results.
+	runResult := runner.NewRunResult(mutedTests)
+
+	err := runBatchWithRetry(testRunner, runResult, testsCases, maxRetries, timeline, "")
+	return *runResult, err
+}
 
+// runDynamicTestsWithRetry repeatedly claims a batch of test cases from the queue and runs it with retries,
+// until the queue is drained. The results of all the batches are recorded into a single run result.
+func runDynamicTestsWithRetry(ctx context.Context, testRunner TestRunner, queue TestQueue, maxRetries int, mutedTests []plan.TestCase, timeline *[]api.Timeline) (runner.RunResult, error) {
 	// Create a new run result with muted tests to keep track of the results.
 	runResult := runner.NewRunResult(mutedTests)
 
+	for batch := 1; ; batch++ {
+		testCases, err := queue.Claim(ctx)
+		if err != nil {
+			return *runResult, fmt.Errorf("failed to claim test cases: %w", err)
+		}
+
+		if len(testCases) == 0 {
+			debug.Printf("Test queue is drained after %d batches", batch-1)
+			return *runResult, nil
+		}
+
+		fmt.Printf("+++ Buildkite Test Engine Client: Claimed batch %d of %d test cases\n", batch, len(testCases))
+		err = runBatchWithRetry(testRunner, runResult, &testCases, maxRetries, timeline, fmt.Sprintf("batch_%d_", batch))
+		if err != nil {
+			return *runResult, err
+		}
+	}
+}
+
+// runBatchWithRetry runs the test cases, and retries the failed tests up to maxRetries times.
+// The results are recorded into runResult, which may contain the results of the previous batches in dynamic mode.
+// Only the tests that failed in this batch are retried.
+// The timeline events are prefixed with eventPrefix to tell the batches apart.
+func runBatchWithRetry(testRunner TestRunner, runResult *runner.RunResult, testsCases *[]plan.TestCase, maxRetries int, timeline *[]api.Timeline, eventPrefix string) error {
+	attemptCount := 0
+
+	previouslyFailed := map[plan.TestCase]bool{}
+	for _, testCase := range runResult.FailedTests() {
+		previouslyFailed[testCase] = true
+	}
+
 	for attemptCount <= maxRetries {
 		if attemptCount == 0 {
 			fmt.Printf("+++ Buildkite Test Engine Client: Running tests\n")
 			*timeline = append(*timeline, api.Timeline{
-				Event:     "test_start",
+				Event:     eventPrefix + "test_start",
 				Timestamp: createTimestamp(),
 			})
 		} else {
 			fmt.Printf("+++ Buildkite Test Engine Client: ♻️ Attempt %d of %d to retry failing tests\n", attemptCount, maxRetries)
 			*timeline = append(*timeline, api.Timeline{
-				Event:     fmt.Sprintf("retry_%d_start", attemptCount),
+				Event:     fmt.Sprintf("%sretry_%d_start", eventPrefix, attemptCount),
 				Timestamp: createTimestamp(),
 			})
 		}
@@ -213,37 +542,44 @@ func runTestsWithRetry(testRunner TestRunner, testsCases *[]plan.TestCase, maxRe
 
 		if attemptCount == 0 {
 			*timeline = append(*timeline, api.Timeline{
-				Event:     "test_end",
+				Event:     eventPrefix + "test_end",
 				Timestamp: createTimestamp(),
 			})
 		} else {
 			*timeline = append(*timeline, api.Timeline{
-				Event:     fmt.Sprintf("retry_%d_end", attemptCount),
+				Event:     fmt.Sprintf("%sretry_%d_end", eventPrefix, attemptCount),
 				Timestamp: createTimestamp(),
 			})
 		}
 
 		// Don't retry if there is an error that is not a test failure.
 		if err != nil {
-			return *runResult, err
+			return err
 		}
 
 		// Don't retry if we've reached max retries.
 		if attemptCount == maxRetries {
-			return *runResult, nil
+			return nil
+		}
+
+		var failedTests []plan.TestCase
+		for _, testCase := range runResult.FailedTests() {
+			if !previouslyFailed[testCase] {
+				failedTests = append(failedTests, testCase)
+			}
 		}
 
 		// Don't retry if tests are passed.
-		if runResult.Status() == runner.RunStatusPassed {
-			return *runResult, nil
+		if len(failedTests) == 0 {
+			return nil
 		}
 
 		// Retry only the failed tests.
-		*testsCases = runResult.FailedTests()
+		*testsCases = failedTests
 		attemptCount++
 	}
 
-	return *runResult, nil
+	return nil
 }
 
 func logSignalAndExit(name string, signal syscall.Signal) {
@@ -270,14 +606,15 @@ func fetchOrCreateTestPlan(ctx context.Context, apiClient *api.Client, cfg confi
 	handleError := func(err error) (plan.TestPlan, error) {
 		if errors.Is(err, api.ErrRetryTimeout) {
 			fmt.Println("⚠️ Could not fetch or create plan from server, falling back to non-intelligent splitting. Your build may take longer than usual.")
-			p := plan.CreateFallbackPlan(files, cfg.Parallelism)
+			// The server is unreachable, therefore only the local timings are used.
+			p := plan.CreateTimedFallbackPlan(files, readTimingCache(cfg), cfg.Parallelism)
 			return p, nil
 		}
 
 		if billingError := new(api.BillingError); errors.As(err, &billingError) {
 			fmt.Println(billingError.Message)
 			fmt.Println("⚠️ Falling back to non-intelligent splitting. Your build may take longer than usual.")
-			p := plan.CreateFallbackPlan(files, cfg.Parallelism)
+			p := createFallbackPlan(ctx, apiClient, cfg, files)
 			return p, nil
 		}
 
@@ -293,7 +630,7 @@ func fetchOrCreateTestPlan(ctx context.Context, apiClient *api.Client, cfg confi
 		// In this case, we should create a fallback plan.
 		if len(cachedPlan.Tasks) == 0 {
 			fmt.Println("⚠️ Error plan received, falling back to non-intelligent splitting. Your build may take longer than usual.")
-			testPlan := plan.CreateFallbackPlan(files, cfg.Parallelism)
+			testPlan := createFallbackPlan(ctx, apiClient, cfg, files)
 			return testPlan, nil
 		}
 
@@ -319,7 +656,7 @@ func fetchOrCreateTestPlan(ctx context.Context, apiClient *api.Client, cfg confi
 	// In this case, we should create a fallback plan.
 	if len(testPlan.Tasks) == 0 {
 		fmt.Println("⚠️ Error plan received, falling back to non-intelligent splitting. Your build may take longer than usual.")
-		testPlan = plan.CreateFallbackPlan(files, cfg.Parallelism)
+		testPlan = createFallbackPlan(ctx, apiClient, cfg, files)
 		return testPlan, nil
 	}
 
@@ -327,6 +664,117 @@ func fetchOrCreateTestPlan(ctx context.Context, apiClient *api.Client, cfg confi
 	return testPlan, nil
 }
 
+// createFallbackPlan creates a fallback plan balanced by the durations of the files.
+// The timings of the files are fetched from the server when available,
+// then read from the local timing cache, otherwise the durations of the files are estimated locally.
+func createFallbackPlan(ctx context.Context, apiClient *api.Client, cfg config.Config, files []string) plan.TestPlan {
+	// The server is already misbehaving, so we don't wait too long for the timings.
+	timingCtx, cancel := context.WithTimeout(ctx, filesTimingTimeout)
+	defer cancel()
+
+	debug.Printf("Fetching timings of %d files", len(files))
+	timings, err := apiClient.FetchFilesTiming(timingCtx, cfg.SuiteSlug, files)
+	if err != nil {
+		debug.Printf("Couldn't fetch timings of files: %v", err)
+	}
+
+	if timings == nil {
+		timings = map[string]time.Duration{}
+	}
+	for path, duration := range readTimingCache(cfg) {
+		if _, ok := timings[path]; !ok {
+			timings[path] = duration
+		}
+	}
+
+	return plan.CreateTimedFallbackPlan(files, timings, cfg.Parallelism)
+}
+
+// readTimingCache returns the timings of the files from the local timing cache, if configured.
+func readTimingCache(cfg config.Config) map[string]time.Duration {
+	if cfg.TimingCachePath == "" {
+		return nil
+	}
+
+	cache, err := timing.ReadFile(cfg.TimingCachePath)
+	if err != nil {
+		fmt.Printf("Failed to read timing cache: %v\n", err)
+		return nil
+	}
+
+	return cache.FileTimings(cfg.SuiteSlug, cfg.TestRunner)
+}
+
+// updateTimingCache records the durations of the tests in the run result into the local timing cache.
+// The duration of a file is the total duration of its tests.
+// Tests without a duration, e.g. when the test runner doesn't report it, are not recorded.
+func updateTimingCache(cfg config.Config, runResult runner.RunResult) {
+	files := map[string]time.Duration{}
+	examples := map[string]time.Duration{}
+	for _, testResult := range runResult.TestResults() {
+		if testResult.Duration <= 0 {
+			continue
+		}
+		files[timing.FilePath(testResult.TestFilePath())] += testResult.Duration
+		examples[testResult.Scope+"/"+testResult.Name] = testResult.Duration
+	}
+
+	if len(files) == 0 {
+		debug.Println("No test durations to record in the timing cache")
+		return
+	}
+
+	// Error is suppressed because we don't want to fail the build if we can't update the cache.
+	cache, err := timing.ReadFile(cfg.TimingCachePath)
+	if err != nil {
+		fmt.Printf("Failed to read timing cache, it will be overwritten: %v\n", err)
+		cache = timing.Cache{}
+	}
+
+	cache.Record(cfg.SuiteSlug, cfg.TestRunner, files, examples)
+
+	if err := timing.WriteFile(cfg.TimingCachePath, cache); err != nil {
+		fmt.Printf("Failed to write timing cache: %v\n", err)
+	}
+}
+
+// selectChangedTests narrows down the test files to the ones affected by the changes against the base ref.
+// All the test files are returned on the default branch, when the changed files can't be determined,
+// or when no test file is affected, so that a change is never left untested by mistake.
+// Error is returned if the mapping file can't be read.
+func selectChangedTests(cfg config.Config, files []string) ([]string, error) {
+	if cfg.DefaultBranch != "" && cfg.Branch == cfg.DefaultBranch {
+		fmt.Printf("+++ Buildkite Test Engine Client: Running all tests on the default branch %s\n", cfg.DefaultBranch)
+		return files, nil
+	}
+
+	var mapping selection.Mapping
+	if cfg.ChangedMappingFile != "" {
+		var err error
+		mapping, err = selection.ReadMappingFile(cfg.ChangedMappingFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changed, err := selection.ChangedFiles(cfg.ChangedBaseRef)
+	if err != nil {
+		fmt.Printf("⚠️ Couldn't find the changed files, running all tests: %v\n", err)
+		return files, nil
+	}
+
+	debug.Printf("Found %d changed files against %s", len(changed), cfg.ChangedBaseRef)
+
+	selected := selection.Select(files, changed, mapping)
+	if len(selected) == 0 {
+		fmt.Printf("+++ Buildkite Test Engine Client: No tests are affected by the changes against %s, running all tests\n", cfg.ChangedBaseRef)
+		return files, nil
+	}
+
+	fmt.Printf("+++ Buildkite Test Engine Client: Selected %d of %d test files affected by the changes against %s\n", len(selected), len(files), cfg.ChangedBaseRef)
+	return selected, nil
+}
+
 // createRequestParam creates the request parameters for the test plan with the given configuration and files.
 // The files should have been filtered by include/exclude patterns before passing to this function.
 // If SplitByExample is disabled (default), it will return the default params that contain all the files.
@@ -10,13 +10,17 @@ import (
 	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/buildkite/test-engine-client/internal/api"
 	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/coordinator"
 	"github.com/buildkite/test-engine-client/internal/plan"
 	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/buildkite/test-engine-client/internal/timing"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -275,6 +279,117 @@ func TestRunTestsWithRetry_Error(t *testing.T) {
 	}
 }
 
+// fakeTestQueue is a test queue that returns the given batches in order, and an empty batch after them.
+type fakeTestQueue struct {
+	batches [][]plan.TestCase
+}
+
+func (q *fakeTestQueue) Claim(ctx context.Context) ([]plan.TestCase, error) {
+	if len(q.batches) == 0 {
+		return nil, nil
+	}
+	batch := q.batches[0]
+	q.batches = q.batches[1:]
+	return batch, nil
+}
+
+func TestRunDynamicTestsWithRetry(t *testing.T) {
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand: "./internal/runner/testdata/custom/adapter.sh",
+	})
+	queue := &fakeTestQueue{
+		batches: [][]plan.TestCase{
+			{{Path: "fruits/apple.fruit"}},
+			{{Path: "fruits/banana.fruit"}},
+		},
+	}
+	timeline := []api.Timeline{}
+	testResult, err := runDynamicTestsWithRetry(context.Background(), testRunner, queue, 1, []plan.TestCase{}, &timeline)
+
+	if err != nil {
+		t.Errorf("runDynamicTestsWithRetry(...) error = %v", err)
+	}
+
+	if testResult.Status() != runner.RunStatusPassed {
+		t.Errorf("runDynamicTestsWithRetry(...) testResult.Status = %v, want %v", testResult.Status(), runner.RunStatusPassed)
+	}
+
+	// The failed test of each batch is retried within the batch.
+	events := []string{}
+	for _, event := range timeline {
+		events = append(events, event.Event)
+	}
+	want := []string{
+		"batch_1_test_start", "batch_1_test_end", "batch_1_retry_1_start", "batch_1_retry_1_end",
+		"batch_2_test_start", "batch_2_test_end", "batch_2_retry_1_start", "batch_2_retry_1_end",
+	}
+	if diff := cmp.Diff(events, want); diff != "" {
+		t.Errorf("timeline events diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRunDynamicTestsWithRetry_Coordinator(t *testing.T) {
+	testPlan := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"0": {NodeNumber: 0, Tests: []plan.TestCase{{Path: "fruits/apple.fruit"}}},
+			"1": {NodeNumber: 1, Tests: []plan.TestCase{{Path: "fruits/banana.fruit"}}},
+		},
+	}
+	svr := httptest.NewServer(coordinator.New(testPlan))
+	defer svr.Close()
+
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand: "./internal/runner/testdata/custom/adapter.sh",
+	})
+	queue := &apiTestQueue{
+		client:    api.NewClient(api.ClientConfig{ServerBaseUrl: svr.URL, OrganizationSlug: "my-org"}),
+		suiteSlug: "my-suite",
+		params:    api.ClaimTestCasesParams{Identifier: "identifier", NodeIndex: 0, BatchSize: 1},
+	}
+	timeline := []api.Timeline{}
+	_, err := runDynamicTestsWithRetry(context.Background(), testRunner, queue, 0, []plan.TestCase{}, &timeline)
+
+	if err != nil {
+		t.Errorf("runDynamicTestsWithRetry(...) error = %v", err)
+	}
+
+	// Both test cases of the plan are claimed by this node, one per batch.
+	events := []string{}
+	for _, event := range timeline {
+		events = append(events, event.Event)
+	}
+	want := []string{"batch_1_test_start", "batch_1_test_end", "batch_2_test_start", "batch_2_test_end"}
+	if diff := cmp.Diff(events, want); diff != "" {
+		t.Errorf("timeline events diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRunDynamicTestsWithRetry_ClaimError(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "bad request"}`, http.StatusBadRequest)
+	}))
+	defer svr.Close()
+
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand: "./internal/runner/testdata/custom/adapter.sh",
+	})
+	queue := &apiTestQueue{
+		client:    api.NewClient(api.ClientConfig{ServerBaseUrl: svr.URL}),
+		suiteSlug: "my-suite",
+		params:    api.ClaimTestCasesParams{BatchSize: 1},
+	}
+	timeline := []api.Timeline{}
+	_, err := runDynamicTestsWithRetry(context.Background(), testRunner, queue, 0, []plan.TestCase{}, &timeline)
+
+	if err == nil || !strings.Contains(err.Error(), "failed to claim test cases") {
+		t.Errorf("runDynamicTestsWithRetry(...) error = %v, want claim error", err)
+	}
+
+	if len(timeline) != 0 {
+		t.Errorf("timeline length = %v, want %d", len(timeline), 0)
+	}
+}
+
 func TestFetchOrCreateTestPlan(t *testing.T) {
 	files := []string{"apple"}
 	testRunner := runner.Rspec{}
@@ -850,3 +965,319 @@ func TestSendMetadata_Unauthorized(t *testing.T) {
 
 	sendMetadata(context.Background(), client, cfg, timeline)
 }
+
+func TestFetchOrCreateTestPlan_PlanErrorWithFilesTiming(t *testing.T) {
+	files := []string{"apple", "banana", "cherry", "mango"}
+	testRunner := runner.Rspec{}
+
+	// mock server to return an error plan, and the timings of the files
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/test_files") {
+			fmt.Fprint(w, `{"apple": 1000, "banana": 5000, "cherry": 2000, "mango": 2000}`)
+			return
+		}
+		fmt.Fprint(w, `{"tasks": {}}`)
+	}))
+	defer svr.Close()
+
+	ctx := context.Background()
+	cfg := config.Config{
+		NodeIndex:     0,
+		Parallelism:   2,
+		Identifier:    "identifier",
+		ServerBaseUrl: svr.URL,
+		SuiteSlug:     "my-suite",
+	}
+	apiClient := api.NewClient(api.ClientConfig{
+		ServerBaseUrl: cfg.ServerBaseUrl,
+	})
+
+	// we want the function to return a fallback plan balanced by the timings of the files
+	want := plan.TestPlan{
+		Fallback: true,
+		Tasks: map[string]*plan.Task{
+			"0": {
+				NodeNumber: 0,
+				Tests:      []plan.TestCase{{Path: "banana", EstimatedDuration: 5000}},
+			},
+			"1": {
+				NodeNumber: 1,
+				Tests: []plan.TestCase{
+					{Path: "cherry", EstimatedDuration: 2000},
+					{Path: "mango", EstimatedDuration: 2000},
+					{Path: "apple", EstimatedDuration: 1000},
+				},
+			},
+		},
+	}
+
+	got, err := fetchOrCreateTestPlan(ctx, apiClient, cfg, files, testRunner)
+	if err != nil {
+		t.Errorf("fetchOrCreateTestPlan(ctx, %v, %v) error = %v", cfg, files, err)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("fetchOrCreateTestPlan(ctx, %v, %v) diff (-got +want):\n%s", cfg, files, diff)
+	}
+}
+
+func TestUpdateTimingCache(t *testing.T) {
+	cfg := config.Config{
+		SuiteSlug:       "my-suite",
+		TestRunner:      "rspec",
+		TimingCachePath: filepath.Join(t.TempDir(), "timing.json"),
+	}
+
+	runResult := runner.NewRunResult([]plan.TestCase{})
+	redApple := plan.TestCase{Path: "./spec/apple_spec.rb[1:1]", Scope: "Apple", Name: "is red"}
+	sweetApple := plan.TestCase{Path: "./spec/apple_spec.rb[1:2]", Scope: "Apple", Name: "is sweet"}
+	cherry := plan.TestCase{Path: "./spec/cherry_spec.rb[1:1]", Scope: "Cherry", Name: "is small"}
+	runResult.RecordTestResult(redApple, runner.TestStatusPassed)
+	runResult.RecordTestDuration(redApple, 1*time.Second)
+	runResult.RecordTestResult(sweetApple, runner.TestStatusFailed)
+	runResult.RecordTestDuration(sweetApple, 2*time.Second)
+	// The duration of cherry is not reported.
+	runResult.RecordTestResult(cherry, runner.TestStatusPassed)
+
+	updateTimingCache(cfg, *runResult)
+
+	got := readTimingCache(cfg)
+	want := map[string]time.Duration{
+		"spec/apple_spec.rb": 3 * time.Second,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("readTimingCache() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestFetchOrCreateTestPlan_InternalServerErrorWithTimingCache(t *testing.T) {
+	files := []string{"apple", "banana", "cherry"}
+	testRunner := runner.Rspec{}
+
+	// mock server to return a 500 Internal Server Error
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}))
+	defer svr.Close()
+
+	// set the fetch timeout to 1 millisecond so we don't wait too long
+	ctx := context.Background()
+	fetchCtx, cancel := context.WithTimeout(ctx, 1*time.Millisecond)
+	defer cancel()
+
+	cfg := config.Config{
+		NodeIndex:       0,
+		Parallelism:     2,
+		Identifier:      "identifier",
+		ServerBaseUrl:   svr.URL,
+		SuiteSlug:       "my-suite",
+		TestRunner:      "rspec",
+		TimingCachePath: filepath.Join(t.TempDir(), "timing.json"),
+	}
+	apiClient := api.NewClient(api.ClientConfig{
+		ServerBaseUrl: cfg.ServerBaseUrl,
+	})
+
+	cache := timing.Cache{}
+	cache.Record(cfg.SuiteSlug, cfg.TestRunner, map[string]time.Duration{
+		"apple":  1 * time.Second,
+		"banana": 1 * time.Second,
+		"cherry": 2 * time.Second,
+	}, nil)
+	if err := timing.WriteFile(cfg.TimingCachePath, cache); err != nil {
+		t.Fatal(err)
+	}
+
+	// we want the function to return a fallback plan balanced by the local timings
+	want := plan.TestPlan{
+		Fallback: true,
+		Tasks: map[string]*plan.Task{
+			"0": {
+				NodeNumber: 0,
+				Tests:      []plan.TestCase{{Path: "cherry", EstimatedDuration: 2000}},
+			},
+			"1": {
+				NodeNumber: 1,
+				Tests: []plan.TestCase{
+					{Path: "apple", EstimatedDuration: 1000},
+					{Path: "banana", EstimatedDuration: 1000},
+				},
+			},
+		},
+	}
+
+	got, err := fetchOrCreateTestPlan(fetchCtx, apiClient, cfg, files, testRunner)
+	if err != nil {
+		t.Errorf("fetchOrCreateTestPlan(ctx, %v, %v) error = %v", cfg, files, err)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("fetchOrCreateTestPlan(ctx, %v, %v) diff (-got +want):\n%s", cfg, files, diff)
+	}
+}
+
+func TestSelectChangedTests_DefaultBranch(t *testing.T) {
+	cfg := config.Config{
+		Branch:         "main",
+		DefaultBranch:  "main",
+		ChangedBaseRef: "origin/doesnt-exist",
+	}
+	files := []string{"spec/apple_spec.rb", "spec/banana_spec.rb"}
+
+	got, err := selectChangedTests(cfg, files)
+	if err != nil {
+		t.Errorf("selectChangedTests(...) error = %v", err)
+	}
+
+	if diff := cmp.Diff(got, files); diff != "" {
+		t.Errorf("selectChangedTests(...) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestSelectChangedTests_UnknownBaseRef(t *testing.T) {
+	cfg := config.Config{
+		Branch:         "feature",
+		DefaultBranch:  "main",
+		ChangedBaseRef: "origin/doesnt-exist",
+	}
+	files := []string{"spec/apple_spec.rb", "spec/banana_spec.rb"}
+
+	got, err := selectChangedTests(cfg, files)
+	if err != nil {
+		t.Errorf("selectChangedTests(...) error = %v", err)
+	}
+
+	if diff := cmp.Diff(got, files); diff != "" {
+		t.Errorf("selectChangedTests(...) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestSelectChangedTests_InvalidMappingFile(t *testing.T) {
+	cfg := config.Config{
+		Branch:             "feature",
+		ChangedBaseRef:     "HEAD",
+		ChangedMappingFile: filepath.Join(t.TempDir(), "doesnt-exist.json"),
+	}
+	files := []string{"spec/apple_spec.rb"}
+
+	_, err := selectChangedTests(cfg, files)
+	if err == nil || !strings.Contains(err.Error(), "failed to read mapping file") {
+		t.Errorf("selectChangedTests(...) error = %v, want mapping file error", err)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	dir := t.TempDir()
+	agentPath := filepath.Join(dir, "buildkite-agent")
+	// The stub agent records its arguments, so we can check it was called.
+	if err := os.WriteFile(agentPath, []byte("#!/bin/sh\necho \"$@\" > \"$0.args\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Config{
+		NodeIndex:      2,
+		AnnotationPath: filepath.Join(dir, "annotation.md"),
+		Annotate:       true,
+		AgentPath:      agentPath,
+	}
+
+	runResult := runner.NewRunResult([]plan.TestCase{})
+	runResult.RecordTestResult(plan.TestCase{Scope: "Apple", Name: "is red"}, runner.TestStatusFailed)
+
+	annotate(cfg, *runResult)
+
+	if _, err := os.Stat(cfg.AnnotationPath); err != nil {
+		t.Errorf("annotation file %q is not written: %v", cfg.AnnotationPath, err)
+	}
+
+	args, err := os.ReadFile(agentPath + ".args")
+	if err != nil {
+		t.Fatalf("buildkite-agent is not called: %v", err)
+	}
+
+	want := "annotate --context bktec-node-2 --style error\n"
+	if diff := cmp.Diff(string(args), want); diff != "" {
+		t.Errorf("buildkite-agent args diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestAnnotate_NothingToReport(t *testing.T) {
+	dir := t.TempDir()
+	agentPath := filepath.Join(dir, "buildkite-agent")
+	if err := os.WriteFile(agentPath, []byte("#!/bin/sh\necho \"$@\" > \"$0.args\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Config{Annotate: true, AgentPath: agentPath}
+
+	runResult := runner.NewRunResult([]plan.TestCase{})
+	runResult.RecordTestResult(plan.TestCase{Scope: "Apple", Name: "is red"}, runner.TestStatusPassed)
+
+	annotate(cfg, *runResult)
+
+	if _, err := os.Stat(agentPath + ".args"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("buildkite-agent is called, want no annotation when all tests passed")
+	}
+}
+
+func TestSendFlakyTests(t *testing.T) {
+	var got api.FlakyTestsParams
+	requests := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("json.Decode() error = %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer svr.Close()
+
+	cfg := config.Config{
+		OrganizationSlug: "my-org",
+		SuiteSlug:        "my-suite",
+		Identifier:       "123/456",
+		ServerBaseUrl:    svr.URL,
+	}
+	client := api.NewClient(api.ClientConfig{
+		ServerBaseUrl:    cfg.ServerBaseUrl,
+		OrganizationSlug: cfg.OrganizationSlug,
+	})
+
+	runResult := runner.NewRunResult([]plan.TestCase{})
+	apple := plan.TestCase{Path: "./spec/apple_spec.rb[1:1]", Scope: "Apple", Name: "is red"}
+	banana := plan.TestCase{Path: "./spec/banana_spec.rb[1:1]", Scope: "Banana", Name: "is yellow"}
+	runResult.RecordTestResult(apple, runner.TestStatusFailed)
+	runResult.RecordTestResult(apple, runner.TestStatusPassed)
+	runResult.RecordTestResult(banana, runner.TestStatusPassed)
+
+	sendFlakyTests(context.Background(), client, cfg, *runResult)
+
+	want := api.FlakyTestsParams{
+		Identifier: "123/456",
+		FlakyTests: []api.FlakyTest{
+			{Scope: "Apple", Name: "is red", Path: "./spec/apple_spec.rb[1:1]", ExecutionCount: 2},
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("sendFlakyTests() request params diff (-got +want):\n%s", diff)
+	}
+
+	if requests != 1 {
+		t.Errorf("sendFlakyTests() sent %d requests, want 1", requests)
+	}
+}
+
+func TestSendFlakyTests_NoFlakyTests(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("sendFlakyTests() sent a request to %s, want no request", r.URL.Path)
+	}))
+	defer svr.Close()
+
+	cfg := config.Config{SuiteSlug: "my-suite", ServerBaseUrl: svr.URL}
+	client := api.NewClient(api.ClientConfig{ServerBaseUrl: cfg.ServerBaseUrl})
+
+	runResult := runner.NewRunResult([]plan.TestCase{})
+	runResult.RecordTestResult(plan.TestCase{Scope: "Apple", Name: "is red"}, runner.TestStatusPassed)
+
+	sendFlakyTests(context.Background(), client, cfg, *runResult)
+}